@@ -23,11 +23,42 @@ func main() {
 	defer databaseConnection.CloseDatabase()
 
 	// statistical machine
-	statisticalMachine := model.NewStatisticalData(databaseConnection)
+	statisticalMachine := model.NewStatisticalData(databaseConnection,
+		configData.CleaningConfiguration.ListResolutions())
+	statisticalMachine.TablesInit()
 
-	// data collector
-	framesParser := machine.NewFramesParser(&configData.NetworkConfiguration, statisticalMachine)
-	framesParser.StartCapturing()
+	// data collectors
+	captureSources := configData.NetworkConfiguration.ListCaptureSources()
+	captureStatistics := machine.NewCaptureStatistics()
+	flowTable := machine.NewFlowTable(&configData.NetworkConfiguration)
+	switch configData.NetworkConfiguration.DataSource {
+	case machine.DATA_SOURCE_NETFLOW, machine.DATA_SOURCE_SFLOW:
+		// records of all exporters are accounted to one observed link
+		if len(captureSources) > 1 {
+			configuration.Error.Panicf("Data source %s is supported only with single capture source",
+				configData.NetworkConfiguration.DataSource)
+		}
+		flowCollector := machine.NewFlowCollector(&configData.NetworkConfiguration, captureSources[0],
+			statisticalMachine)
+		flowCollector.StartCollecting()
+	case machine.DATA_SOURCE_CAPTURE, "":
+		if configData.NetworkConfiguration.ReplayFile != "" && len(captureSources) > 1 {
+			configuration.Error.Panic("Replay of capture file is supported only with single capture source")
+		}
+		framesPipeline, err := machine.NewFramesPipeline(&configData.NetworkConfiguration, statisticalMachine,
+			captureStatistics)
+		if err != nil {
+			configuration.Error.Panicf("Error building of frames pipeline: %v", err)
+		}
+		framesPipeline.StartPipeline()
+		for _, captureSource := range captureSources {
+			framesParser := machine.NewFramesParser(&configData.NetworkConfiguration, captureSource,
+				framesPipeline, captureStatistics, flowTable)
+			framesParser.StartCapturing()
+		}
+	default:
+		configuration.Error.Panicf("Unknown data source: %s", configData.NetworkConfiguration.DataSource)
+	}
 
 	// data cleaner
 	dataCleaner := machine.NewDataCleaner(&configData.CleaningConfiguration, statisticalMachine)
@@ -36,7 +67,7 @@ func main() {
 	// device manager
 	deviceManager := machine.NewDeviceManager(&configData.PHYConfiguration,
 		configData.LoadAnalyserConfiguration.SmoothingRange, configData.PredictionAnalyserConfiguration.Designator,
-			configData.NetworkConfiguration.LinkBandwidth)
+			captureSources)
 	deviceManager.StartDeviceManager()
 	defer deviceManager.CloseDeviceManager()
 
@@ -48,7 +79,7 @@ func main() {
 
 	// real-time load analyser
 	realTimeLoader := machine.NewLoadAnalyser(&configData.LoadAnalyserConfiguration, deviceManager,
-		statisticalMachine, smoothingCreator1)
+		statisticalMachine, smoothingCreator1, captureSources, configData.NetworkConfiguration.ListDirections())
 	realTimeLoader.StartMachine()
 
 	// R server connection
@@ -60,11 +91,13 @@ func main() {
 
 	// predictive load analyser
 	predictionAnalyser := machine.NewPredictionAnalyser(&configData.PredictionAnalyserConfiguration, deviceManager,
-		statisticalMachine, smoothingCreator2, rServer, configData.NetworkConfiguration.LinkBandwidth)
+		statisticalMachine, smoothingCreator2, rServer, captureSources,
+		configData.NetworkConfiguration.ListDirections())
 	predictionAnalyser.StartMachine()
 
 	// rest server
-	restServer := controller.NewRestController(&configData.RestConfiguration, statisticalMachine, deviceManager)
+	restServer := controller.NewRestController(&configData.RestConfiguration, statisticalMachine, deviceManager,
+		captureStatistics, flowTable)
 	restServer.StartRestController()
 
 	// web server
@@ -12,26 +12,35 @@ import (
 )
 
 // Attribute conf *model.RestConfiguration - REST settings - routing paths. See model.RestConfiguration.
-// Attribute databaseController *model.StatisticalData - accessing of database operations. See model.StatisticalData.
+// Attribute databaseController model.Storage - accessing of database operations. See model.Storage.
 // Attribute deviceManager *machine.DeviceManager - I/O controller (led strip, buttons, and lcd)
+// Attribute captureStatistics *machine.CaptureStatistics - health counters of capturing. See machine.CaptureStatistics.
+// Attribute flowTable *machine.FlowTable - table of captured flows. See machine.FlowTable.
 type RestController struct {
 	restConfiguration	*model.RestConfiguration
-	databaseController	*model.StatisticalData
+	databaseController	model.Storage
 	deviceManager		*machine.DeviceManager
+	captureStatistics	*machine.CaptureStatistics
+	flowTable			*machine.FlowTable
 }
 
 // Creating instance of the RestController.
 // Parameter conf *model.RestConfiguration - REST settings - routing paths. See model.RestConfiguration.
-// Parameter databaseController *model.StatisticalData - accessing of database operations. See model.StatisticalData.
+// Parameter databaseController model.Storage - accessing of database operations. See model.Storage.
 // Parameter dataRouter *model.DataRouter - data router for setting final (forecasted or smoothed) data entries.
 // Parameter deviceManager *machine.DeviceManager - I/O controller (led strip, buttons, and lcd)
+// Parameter captureStatistics *machine.CaptureStatistics - health counters of capturing. See machine.CaptureStatistics.
+// Parameter flowTable *machine.FlowTable - table of captured flows. See machine.FlowTable.
 // Returning *RestController - RestController object.
-func NewRestController(conf *model.RestConfiguration, databaseController *model.StatisticalData,
-	deviceManager *machine.DeviceManager) *RestController {
+func NewRestController(conf *model.RestConfiguration, databaseController model.Storage,
+	deviceManager *machine.DeviceManager, captureStatistics *machine.CaptureStatistics,
+	flowTable *machine.FlowTable) *RestController {
 	restController := RestController {
 		restConfiguration: conf,
 		databaseController: databaseController,
 		deviceManager: deviceManager,
+		captureStatistics: captureStatistics,
+		flowTable: flowTable,
 	}
 	return &restController
 }
@@ -40,13 +49,7 @@ func NewRestController(conf *model.RestConfiguration, databaseController *model.
 func (RestController *RestController) StartRestController() {
 	configuration.Info.Println("Initialisation of REST services.")
 	fireUpServices := func() {
-		// Services declaration
-		r := httprouter.New()
-		r.GET(RestController.restConfiguration.PathGetDataTypes, RestController.GetDataTypes)
-		r.GET(RestController.restConfiguration.PathGetDataType, RestController.GetDataType)
-		r.DELETE(RestController.restConfiguration.PathRemoveDataType, RestController.RemoveDataType)
-		r.POST(RestController.restConfiguration.PathWriteNewDataType, RestController.WriteNewDataType)
-		r.POST(RestController.restConfiguration.PathModifyDataType, RestController.ModifyDataType)
+		r := RestController.buildRouter()
 		// Starting of routing
 		startingPath := fmt.Sprintf(":%d", RestController.restConfiguration.LocalhostPort)
 		err := http.ListenAndServe(startingPath, r)
@@ -58,6 +61,21 @@ func (RestController *RestController) StartRestController() {
 	configuration.Info.Println("REST services have been initialised successfully.")
 }
 
+// Declaration of REST services on configured routes.
+// Returning *httprouter.Router - router with all REST services. See httprouter.Router.
+func (RestController *RestController) buildRouter() *httprouter.Router {
+	r := httprouter.New()
+	r.GET(RestController.restConfiguration.PathGetDataTypes, RestController.GetDataTypes)
+	r.GET(RestController.restConfiguration.PathGetDataType, RestController.GetDataType)
+	r.DELETE(RestController.restConfiguration.PathRemoveDataType, RestController.RemoveDataType)
+	r.POST(RestController.restConfiguration.PathWriteNewDataType, RestController.WriteNewDataType)
+	r.POST(RestController.restConfiguration.PathModifyDataType, RestController.ModifyDataType)
+	r.GET(RestController.restConfiguration.PathGetLoads, RestController.GetLoads)
+	r.GET(RestController.restConfiguration.PathGetCaptureStatistics, RestController.GetCaptureStatistics)
+	r.GET(RestController.restConfiguration.PathGetTopTalkers, RestController.GetTopTalkers)
+	return r
+}
+
 // Fetching of all data types from database (REST API).
 // Parameter w http.ResponseWriter - HTTP response channel. See http.ResponseWriter.
 // Parameter r *http.Request - HTTP request header. See http.Request.
@@ -198,3 +216,94 @@ func (RestController *RestController) ModifyDataType(w http.ResponseWriter, r *h
 		fmt.Fprintf(w, "%v", err03)
 	}
 }
+
+// Fetching of actual loads (bytes and frames per second) of all data types (REST API).
+// Parameter w http.ResponseWriter - HTTP response channel. See http.ResponseWriter.
+// Parameter r *http.Request - HTTP request header. See http.Request.
+func (RestController *RestController) GetLoads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	loads := RestController.deviceManager.ListLoads()
+	jsonBytes, err := json.Marshal(loads)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		fmt.Fprintf(w, "%s", jsonBytes)
+	} else {
+		msg := fmt.Sprintf("An error occurred during marshaling of list of loads: %s\n", err)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "%s", msg)
+		configuration.Error.Print(msg)
+	}
+}
+
+// Fetching of capture health counters (drops, early bucket flushes, and frame errors) of all links (REST API).
+// Parameter w http.ResponseWriter - HTTP response channel. See http.ResponseWriter.
+// Parameter r *http.Request - HTTP request header. See http.Request.
+func (RestController *RestController) GetCaptureStatistics(w http.ResponseWriter, r *http.Request,
+	_ httprouter.Params) {
+	counters := RestController.captureStatistics.ListCounters()
+	jsonBytes, err := json.Marshal(counters)
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		fmt.Fprintf(w, "%s", jsonBytes)
+	} else {
+		msg := fmt.Sprintf("An error occurred during marshaling of capture statistics: %s\n", err)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "%s", msg)
+		configuration.Error.Print(msg)
+	}
+}
+
+// Fetching of top flows and top hosts by bytes over selected window (REST API).
+// Parameter w http.ResponseWriter - HTTP response channel. See http.ResponseWriter.
+// Parameter r *http.Request - HTTP request header. See http.Request.
+// Query parameter count - maximum number of listed flows and hosts (default 10).
+// Query parameter window - window of the listing [s] (default 60 seconds).
+func (RestController *RestController) GetTopTalkers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	errorsBucket := configuration.NewCompositeError()
+	count := readQueryParameter(r, "count", machine.TOP_TALKERS_COUNT_DEFAULT, errorsBucket)
+	window := readQueryParameter(r, "window", machine.TOP_TALKERS_WINDOW_DEFAULT, errorsBucket)
+	err01 := errorsBucket.Evaluate()
+	if err01 != nil {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(400)
+		fmt.Fprintf(w, "%v", err01)
+		return
+	}
+	topTalkers := RestController.flowTable.ListTopTalkers(count, window)
+	jsonBytes, err02 := json.Marshal(topTalkers)
+	if err02 == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		fmt.Fprintf(w, "%s", jsonBytes)
+	} else {
+		msg := fmt.Sprintf("An error occurred during marshaling of top talkers: %s\n", err02)
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(500)
+		fmt.Fprintf(w, "%s", msg)
+		configuration.Error.Print(msg)
+	}
+}
+
+// Reading of positive integer query parameter.
+// Parameter r *http.Request - HTTP request header. See http.Request.
+// Parameter name string - name of the query parameter.
+// Parameter defaultValue uint - value of missing parameter.
+// Parameter errorsBucket *configuration.CompositeError - collector of invalid parameters.
+// See configuration.CompositeError.
+// Returning uint - value of the parameter.
+func readQueryParameter(r *http.Request, name string, defaultValue uint,
+	errorsBucket *configuration.CompositeError) uint {
+	text := r.URL.Query().Get(name)
+	if text == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseUint(text, 10, 32)
+	if err != nil || value == 0 {
+		errorsBucket.AddError(1, fmt.Sprintf("Query parameter %s must be a positive integer: %s", name, text))
+		return defaultValue
+	}
+	return uint(value)
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/julienschmidt/httprouter"
+	"machine"
+	"model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Routing paths of tested REST services (the same paths as in the default configuration file).
+var testRestConfiguration = model.RestConfiguration{
+	PathGetDataTypes: "/datatype/list",
+	PathGetDataType: "/datatype/detail/:id",
+	PathRemoveDataType: "/datatype/delete/:id",
+	PathWriteNewDataType: "/datatype/create",
+	PathModifyDataType: "/datatype/modify/:id",
+	PathGetLoads: "/load/list",
+	PathGetCaptureStatistics: "/capture/statistics",
+	PathGetTopTalkers: "/flows/top",
+}
+
+// Building of router with REST services over in-memory storage.
+// Parameter captureStatistics *machine.CaptureStatistics - health counters of capturing.
+// See machine.CaptureStatistics.
+// Returning *httprouter.Router - router with all REST services. See httprouter.Router.
+func buildTestRouter(captureStatistics *machine.CaptureStatistics) *httprouter.Router {
+	captureSources := []model.CaptureSource{{Name: "eth1", LinkBandwidth: 1000000}}
+	deviceManager := machine.NewDeviceManager(&model.PHYConfiguration{}, 1000, 0.1, captureSources)
+	restController := NewRestController(&testRestConfiguration, model.NewMemoryStorage(nil), deviceManager,
+		captureStatistics, machine.NewFlowTable(&model.NetworkConfiguration{}))
+	return restController.buildRouter()
+}
+
+// Sending of HTTP request to the router.
+// Parameter router *httprouter.Router - router with REST services. See httprouter.Router.
+// Parameter method string - HTTP method.
+// Parameter path string - requested path.
+// Parameter body string - HTTP body (empty string without body).
+// Returning *httptest.ResponseRecorder - recorded response. See httptest.ResponseRecorder.
+func sendRequest(router *httprouter.Router, method string, path string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+// Unit test - creating, listing, fetching, modifying, and removing of data types over REST API.
+// Parameter t *testing.T - testing engine.
+func TestDataTypeServices(t *testing.T) {
+	router := buildTestRouter(machine.NewCaptureStatistics())
+
+	t.Log("Creating of data types ...")
+	response := sendRequest(router, http.MethodPost, "/datatype/create",
+		`{"Name": "HTTPS", "NetworkProtocol": 2048, "TransportProtocol": 6, "Port": 443}`)
+	createdDataType := model.DataType{}
+	if response.Code != 200 || json.Unmarshal(response.Body.Bytes(), &createdDataType) != nil ||
+		createdDataType.ID == 0 || createdDataType.Name != "HTTPS" {
+		t.Fatalf("Expected created data type; given response: %d %s", response.Code, response.Body)
+	}
+	response = sendRequest(router, http.MethodPost, "/datatype/create",
+		`{"Name": "HTTPS", "NetworkProtocol": 2048, "TransportProtocol": 17, "Port": 443}`)
+	if response.Code != 400 {
+		t.Errorf("Expected rejected duplicate name; given response: %d %s", response.Code, response.Body)
+	}
+	if response = sendRequest(router, http.MethodPost, "/datatype/create", "{"); response.Code != 400 {
+		t.Errorf("Expected rejected malformed JSON; given response: %d %s", response.Code, response.Body)
+	}
+
+	t.Log("Listing and fetching of data types ...")
+	var dataTypes []model.DataType
+	response = sendRequest(router, http.MethodGet, "/datatype/list", "")
+	if response.Code != 200 || json.Unmarshal(response.Body.Bytes(), &dataTypes) != nil || len(dataTypes) != 1 {
+		t.Errorf("Expected one listed data type; given response: %d %s", response.Code, response.Body)
+	}
+	tests := []struct {
+		path			string
+		expectedCode	int
+	}{
+		{"/datatype/detail/1", 200},
+		{"/datatype/detail/9", 404},
+		{"/datatype/detail/x", 400},
+	}
+	for _, test := range tests {
+		if response = sendRequest(router, http.MethodGet, test.path, ""); response.Code != test.expectedCode {
+			t.Errorf("%s: expected status: %d; given response: %d %s", test.path, test.expectedCode,
+				response.Code, response.Body)
+		}
+	}
+
+	t.Log("Modifying of data type ...")
+	response = sendRequest(router, http.MethodPost, "/datatype/modify/1",
+		`{"Name": "TLS", "Forecasting": false, "NetworkProtocol": 2048, "TransportProtocol": 6, "Port": 443}`)
+	if response.Code != 200 {
+		t.Errorf("Expected modified data type; given response: %d %s", response.Code, response.Body)
+	}
+	fetchedDataType := model.DataType{}
+	response = sendRequest(router, http.MethodGet, "/datatype/detail/1", "")
+	if json.Unmarshal(response.Body.Bytes(), &fetchedDataType) != nil || fetchedDataType.Name != "TLS" {
+		t.Errorf("Expected modified name TLS; given response: %d %s", response.Code, response.Body)
+	}
+	response = sendRequest(router, http.MethodPost, "/datatype/modify/x", `{"Name": "TLS"}`)
+	if response.Code != 400 {
+		t.Errorf("Expected rejected invalid ID; given response: %d %s", response.Code, response.Body)
+	}
+
+	t.Log("Removing of data type ...")
+	if response = sendRequest(router, http.MethodDelete, "/datatype/delete/1", ""); response.Code != 200 {
+		t.Errorf("Expected removed data type; given response: %d %s", response.Code, response.Body)
+	}
+	if response = sendRequest(router, http.MethodDelete, "/datatype/delete/1", ""); response.Code != 404 {
+		t.Errorf("Expected missing removed data type; given response: %d %s", response.Code, response.Body)
+	}
+	response = sendRequest(router, http.MethodGet, "/datatype/list", "")
+	if response.Code != 200 || json.Unmarshal(response.Body.Bytes(), &dataTypes) != nil || len(dataTypes) != 0 {
+		t.Errorf("Expected no listed data types; given response: %d %s", response.Code, response.Body)
+	}
+}
+
+// Unit test - listing of loads, capture health counters, and top talkers over REST API.
+// Parameter t *testing.T - testing engine.
+func TestMonitoringServices(t *testing.T) {
+	captureStatistics := machine.NewCaptureStatistics()
+	captureStatistics.RegisterSource("eth1")
+	captureStatistics.AddFrameErrors("eth1", 3, 2, 1)
+	router := buildTestRouter(captureStatistics)
+
+	t.Log("Listing of loads before the first computation ...")
+	response := sendRequest(router, http.MethodGet, "/load/list", "")
+	if response.Code != 200 || response.Header().Get("Content-Type") != "application/json" ||
+		strings.TrimSpace(response.Body.String()) != "[]" {
+		t.Errorf("Expected empty JSON list of loads; given response: %d %s", response.Code, response.Body)
+	}
+
+	t.Log("Listing of capture health counters ...")
+	var counters []machine.CaptureCounters
+	response = sendRequest(router, http.MethodGet, "/capture/statistics", "")
+	if response.Code != 200 || json.Unmarshal(response.Body.Bytes(), &counters) != nil || len(counters) != 1 ||
+		counters[0].InterfaceName != "eth1" || counters[0].NonEncapsulatedFrames != 3 ||
+		counters[0].MalformedFrames != 2 || counters[0].ParseErrors != 1 {
+		t.Errorf("Unexpected capture health counters; given response: %d %s", response.Code, response.Body)
+	}
+
+	t.Log("Rejecting of invalid query parameter of top talkers ...")
+	if response = sendRequest(router, http.MethodGet, "/flows/top?count=0", ""); response.Code != 400 {
+		t.Errorf("Expected rejected count of top talkers; given response: %d %s", response.Code, response.Body)
+	}
+}
@@ -0,0 +1,340 @@
+package machine
+
+import (
+	"model"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identified flows that haven't been seen for this time are forgotten.
+const APPLICATION_FLOW_TIMEOUT = 2 * time.Minute
+// Interval of removing of expired flows.
+const APPLICATION_FLOW_SWEEP_INTERVAL = 30 * time.Second
+// Maximum number of remembered flows (protection against memory exhaustion; new flows are then identified only
+// by their first packet).
+const APPLICATION_FLOWS_MAX = 65536
+// DNS port.
+const PORT_DNS = uint(53)
+// Length of DNS header.
+const DNS_HEADER_LENGTH = uint(12)
+// Label length bits that mark compressed DNS name (pointer).
+const DNS_COMPRESSION_MASK = uint8(0xc0)
+// TLS record type and handshake type of ClientHello.
+const TLS_RECORD_HANDSHAKE = uint8(0x16)
+const TLS_HANDSHAKE_CLIENT_HELLO = uint8(0x01)
+// Length of TLS record header and handshake header.
+const TLS_RECORD_HEADER_LENGTH = uint(5)
+const TLS_HANDSHAKE_HEADER_LENGTH = uint(4)
+// TLS server name extension and its host name type.
+const TLS_EXTENSION_SERVER_NAME = uint16(0x0000)
+const TLS_SERVER_NAME_HOST = uint8(0x00)
+// Methods that start HTTP requests.
+var HTTP_METHODS = [][]byte{[]byte("GET "), []byte("POST "), []byte("HEAD "), []byte("PUT "), []byte("DELETE "),
+	[]byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "), []byte("TRACE ")}
+
+// Bidirectional identification of TCP / UDP flow (both directions share the same key).
+// Attribute transportProtocol uint - TCP or UDP.
+// Attribute vlanId uint - VLAN identifier of the outer tag.
+// Attribute lowerAddress model.IPAddress - lower endpoint address. See model.IPAddress.
+// Attribute upperAddress model.IPAddress - upper endpoint address. See model.IPAddress.
+// Attribute lowerPort uint - port of the lower endpoint.
+// Attribute upperPort uint - port of the upper endpoint.
+type applicationFlowKey struct {
+	transportProtocol	uint
+	vlanId				uint
+	lowerAddress		model.IPAddress
+	upperAddress		model.IPAddress
+	lowerPort			uint
+	upperPort			uint
+}
+
+// Server name of identified flow.
+// Attribute serverName string - server name in lower case.
+// Attribute source uint - source of the server name (model.SERVER_NAME_TLS, model.SERVER_NAME_HTTP, or
+// model.SERVER_NAME_DNS).
+// Attribute lastSeen time.Time - arrival time of the last frame of the flow. See time.Time.
+type applicationFlow struct {
+	serverName		string
+	source			uint
+	lastSeen		time.Time
+}
+
+// Lightweight application-layer classification - server names are read from TLS ClientHello (SNI), HTTP requests
+// (Host header), and DNS queries (query name). The classification is flow-sticky: once the first identifying packet
+// is seen, all following frames of the flow (both directions) carry its server name. Names split across several
+// TCP segments are not reassembled.
+// Attribute mutex *sync.Mutex - synchronisation of parse workers. See sync.Mutex.
+// Attribute flows map[applicationFlowKey](*applicationFlow) - identified flows. See applicationFlow.
+// Attribute lastSweep time.Time - time of the last removing of expired flows. See time.Time.
+type ApplicationClassifier struct {
+	mutex		*sync.Mutex
+	flows		map[applicationFlowKey](*applicationFlow)
+	lastSweep	time.Time
+}
+
+// Creating instance of the ApplicationClassifier.
+// Returning *ApplicationClassifier - ApplicationClassifier object without identified flows.
+func NewApplicationClassifier() *ApplicationClassifier {
+	applicationClassifier := ApplicationClassifier{
+		mutex: &sync.Mutex{},
+		flows: make(map[applicationFlowKey](*applicationFlow)),
+	}
+	return &applicationClassifier
+}
+
+// Classification of the frame - server name of the flow is written to raw data type if the flow has already been
+// identified or if the payload identifies it.
+// Parameter rawDataType *model.RawDataType - decoded raw data type. See model.RawDataType.
+// Parameter payload []byte - TCP / UDP payload of the frame.
+// Parameter timestamp time.Time - arrival time of the frame. See time.Time.
+func (ApplicationClassifier *ApplicationClassifier) Classify(rawDataType *model.RawDataType, payload []byte,
+	timestamp time.Time) {
+	if rawDataType.TransportProtocol != uint(PROTOCOL_TCP) && rawDataType.TransportProtocol != uint(PROTOCOL_UDP) {
+		return
+	}
+	key := newApplicationFlowKey(rawDataType)
+	if ApplicationClassifier.lookupFlow(key, rawDataType, timestamp) || len(payload) == 0 {
+		return
+	}
+	serverName, source := readServerName(rawDataType, payload)
+	if serverName == "" {
+		return
+	}
+	rawDataType.ServerName = serverName
+	rawDataType.ServerNameSource = source
+	ApplicationClassifier.mutex.Lock()
+	defer ApplicationClassifier.mutex.Unlock()
+	if len(ApplicationClassifier.flows) < APPLICATION_FLOWS_MAX {
+		ApplicationClassifier.flows[key] = &applicationFlow{serverName: serverName, source: source,
+			lastSeen: timestamp}
+	}
+}
+
+// Searching for identified flow; expired flows are removed periodically.
+// Parameter key applicationFlowKey - identification of the flow. See applicationFlowKey.
+// Parameter rawDataType *model.RawDataType - raw data type to which server name is written. See model.RawDataType.
+// Parameter timestamp time.Time - arrival time of the frame. See time.Time.
+// Returning bool - true if the flow has been identified.
+func (ApplicationClassifier *ApplicationClassifier) lookupFlow(key applicationFlowKey,
+	rawDataType *model.RawDataType, timestamp time.Time) bool {
+	ApplicationClassifier.mutex.Lock()
+	defer ApplicationClassifier.mutex.Unlock()
+	if timestamp.Sub(ApplicationClassifier.lastSweep) >= APPLICATION_FLOW_SWEEP_INTERVAL {
+		for flowKey, flow := range ApplicationClassifier.flows {
+			if timestamp.Sub(flow.lastSeen) > APPLICATION_FLOW_TIMEOUT {
+				delete(ApplicationClassifier.flows, flowKey)
+			}
+		}
+		ApplicationClassifier.lastSweep = timestamp
+	}
+	flow, present := ApplicationClassifier.flows[key]
+	if !present {
+		return false
+	}
+	if timestamp.After(flow.lastSeen) {
+		flow.lastSeen = timestamp
+	}
+	rawDataType.ServerName = flow.serverName
+	rawDataType.ServerNameSource = flow.source
+	return true
+}
+
+// Building of bidirectional flow key - endpoints are ordered by address and port.
+// Parameter rawDataType *model.RawDataType - decoded raw data type. See model.RawDataType.
+// Returning applicationFlowKey - key of the flow. See applicationFlowKey.
+func newApplicationFlowKey(rawDataType *model.RawDataType) applicationFlowKey {
+	key := applicationFlowKey{
+		transportProtocol: rawDataType.TransportProtocol,
+		vlanId: rawDataType.VlanId,
+		lowerAddress: rawDataType.SrcAddress,
+		upperAddress: rawDataType.DstAddress,
+		lowerPort: rawDataType.SrcPort,
+		upperPort: rawDataType.DstPort,
+	}
+	comparison := bytes.Compare(key.lowerAddress[:], key.upperAddress[:])
+	if comparison > 0 || (comparison == 0 && key.lowerPort > key.upperPort) {
+		key.lowerAddress, key.upperAddress = key.upperAddress, key.lowerAddress
+		key.lowerPort, key.upperPort = key.upperPort, key.lowerPort
+	}
+	return key
+}
+
+// Reading of server name from the payload (DNS query name for UDP port 53, TLS SNI or HTTP Host for TCP).
+// Parameter rawDataType *model.RawDataType - decoded raw data type. See model.RawDataType.
+// Parameter payload []byte - TCP / UDP payload.
+// Returning string - server name in lower case (empty - the payload doesn't identify the flow).
+// Returning uint - source of the server name.
+func readServerName(rawDataType *model.RawDataType, payload []byte) (string, uint) {
+	if rawDataType.TransportProtocol == uint(PROTOCOL_UDP) {
+		if rawDataType.SrcPort == PORT_DNS || rawDataType.DstPort == PORT_DNS {
+			return readDnsQueryName(payload), model.SERVER_NAME_DNS
+		}
+		return "", model.SERVER_NAME_ANY
+	}
+	if serverName := readTlsServerName(payload); serverName != "" {
+		return serverName, model.SERVER_NAME_TLS
+	}
+	if serverName := readHttpHost(payload); serverName != "" {
+		return serverName, model.SERVER_NAME_HTTP
+	}
+	return "", model.SERVER_NAME_ANY
+}
+
+// Reading of server name indication from TLS ClientHello.
+// Parameter payload []byte - TCP payload.
+// Returning string - server name in lower case (empty - the payload is not ClientHello with SNI).
+func readTlsServerName(payload []byte) string {
+	if uint(len(payload)) < TLS_RECORD_HEADER_LENGTH + TLS_HANDSHAKE_HEADER_LENGTH ||
+		payload[0] != TLS_RECORD_HANDSHAKE || payload[1] != 0x03 || payload[5] != TLS_HANDSHAKE_CLIENT_HELLO {
+		return ""
+	}
+	reader := payloadReader{data: payload, index: TLS_RECORD_HEADER_LENGTH + TLS_HANDSHAKE_HEADER_LENGTH}
+	// client version and random
+	reader.skip(34)
+	// session ID, cipher suites, and compression methods
+	reader.skip(uint(reader.readUint8()))
+	reader.skip(uint(reader.readUint16()))
+	reader.skip(uint(reader.readUint8()))
+	extensionsEnd := uint(reader.readUint16()) + reader.index
+	for !reader.failed && reader.index + 4 <= extensionsEnd {
+		extensionType := reader.readUint16()
+		extensionLength := uint(reader.readUint16())
+		if extensionType == TLS_EXTENSION_SERVER_NAME {
+			// length of server name list
+			reader.skip(2)
+			nameType := reader.readUint8()
+			name := reader.read(uint(reader.readUint16()))
+			if reader.failed || nameType != TLS_SERVER_NAME_HOST {
+				return ""
+			}
+			return normaliseServerName(string(name))
+		}
+		reader.skip(extensionLength)
+	}
+	return ""
+}
+
+// Reading of Host header from HTTP request.
+// Parameter payload []byte - TCP payload.
+// Returning string - host name in lower case without port (empty - the payload is not HTTP request with Host).
+func readHttpHost(payload []byte) string {
+	isRequest := false
+	for _, method := range HTTP_METHODS {
+		isRequest = isRequest || bytes.HasPrefix(payload, method)
+	}
+	if !isRequest {
+		return ""
+	}
+	headerEnd := bytes.Index(payload, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		headerEnd = len(payload)
+	}
+	lines := bytes.Split(payload[:headerEnd], []byte("\r\n"))
+	for _, line := range lines[1:] {
+		colon := bytes.IndexByte(line, ':')
+		if colon > 0 && strings.EqualFold(string(bytes.TrimSpace(line[:colon])), "host") {
+			host := string(bytes.TrimSpace(line[colon + 1:]))
+			if hostWithoutPort, _, err := net.SplitHostPort(host); err == nil {
+				host = hostWithoutPort
+			}
+			return normaliseServerName(host)
+		}
+	}
+	return ""
+}
+
+// Reading of the first query name from DNS message (query or response).
+// Parameter payload []byte - UDP payload.
+// Returning string - query name in lower case (empty - the payload is not DNS message with question).
+func readDnsQueryName(payload []byte) string {
+	reader := payloadReader{data: payload, index: 4}
+	questions := reader.readUint16()
+	reader.skip(DNS_HEADER_LENGTH - 6)
+	if reader.failed || questions == 0 {
+		return ""
+	}
+	var labels []string
+	for {
+		labelLength := reader.readUint8()
+		// the question name is not expected to be compressed
+		if reader.failed || labelLength & DNS_COMPRESSION_MASK != 0 {
+			return ""
+		}
+		if labelLength == 0 {
+			break
+		}
+		label := reader.read(uint(labelLength))
+		if reader.failed {
+			return ""
+		}
+		labels = append(labels, string(label))
+	}
+	return normaliseServerName(strings.Join(labels, "."))
+}
+
+// Normalisation of server name - conversion to lower case and removal of trailing dot.
+// Parameter serverName string - read server name.
+// Returning string - normalised server name (empty if the name is too long).
+func normaliseServerName(serverName string) string {
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if len(serverName) > model.SERVER_NAME_MAX_LENGTH {
+		return ""
+	}
+	return serverName
+}
+
+// Sequential reader of big-endian fields of application-layer payload.
+// Attribute data []byte - read data.
+// Attribute index uint - index of the next field.
+// Attribute failed bool - some field exceeded the data (subsequent reads return zero values).
+type payloadReader struct {
+	data			[]byte
+	index			uint
+	failed			bool
+}
+
+// Reading of 8-bit unsigned integer.
+// Returning uint8 - read value (0 if the data is truncated).
+func (payloadReader *payloadReader) readUint8() uint8 {
+	value := payloadReader.read(1)
+	if value == nil {
+		return 0
+	}
+	return value[0]
+}
+
+// Reading of 16-bit unsigned integer.
+// Returning uint16 - read value (0 if the data is truncated).
+func (payloadReader *payloadReader) readUint16() uint16 {
+	value := payloadReader.read(2)
+	if value == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(value)
+}
+
+// Reading of fixed number of bytes.
+// Parameter length uint - number of read bytes.
+// Returning []byte - read bytes (nil if the data is truncated).
+func (payloadReader *payloadReader) read(length uint) []byte {
+	start := payloadReader.index
+	payloadReader.skip(length)
+	if payloadReader.failed {
+		return nil
+	}
+	return payloadReader.data[start : start + length]
+}
+
+// Skipping of fixed number of bytes.
+// Parameter length uint - number of skipped bytes.
+func (payloadReader *payloadReader) skip(length uint) {
+	if payloadReader.failed || payloadReader.index + length > uint(len(payloadReader.data)) {
+		payloadReader.failed = true
+		return
+	}
+	payloadReader.index += length
+}
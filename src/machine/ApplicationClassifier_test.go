@@ -0,0 +1,124 @@
+package machine
+
+import (
+	"model"
+	"net"
+	"testing"
+	"time"
+)
+
+// Building of TLS ClientHello record with server name indication.
+// Parameter serverName string - server name (empty - the extension is omitted).
+// Returning []byte - TLS record.
+func buildClientHello(serverName string) []byte {
+	var extensions []byte
+	// supported versions extension precedes SNI
+	extensions = append(extensions, 0x00, 0x2b, 0x00, 0x03, 0x02, 0x03, 0x04)
+	if serverName != "" {
+		nameLength := len(serverName)
+		extensions = append(extensions, 0x00, 0x00, byte((nameLength + 5) >> 8), byte(nameLength + 5),
+			byte((nameLength + 3) >> 8), byte(nameLength + 3), TLS_SERVER_NAME_HOST, byte(nameLength >> 8),
+			byte(nameLength))
+		extensions = append(extensions, serverName...)
+	}
+	body := []byte{0x03, 0x03}
+	body = append(body, make([]byte, 32)...)
+	// session ID, cipher suites, and compression methods
+	body = append(body, 0x01, 0xaa, 0x00, 0x02, 0x13, 0x01, 0x01, 0x00)
+	body = append(body, byte(len(extensions) >> 8), byte(len(extensions)))
+	body = append(body, extensions...)
+	handshake := append([]byte{TLS_HANDSHAKE_CLIENT_HELLO, 0x00, byte(len(body) >> 8), byte(len(body))}, body...)
+	return append([]byte{TLS_RECORD_HANDSHAKE, 0x03, 0x01, byte(len(handshake) >> 8), byte(len(handshake))},
+		handshake...)
+}
+
+// Building of DNS query with one question.
+// Parameter labels ...string - labels of the query name.
+// Returning []byte - DNS message.
+func buildDnsQuery(labels ...string) []byte {
+	message := []byte{0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	for _, label := range labels {
+		message = append(message, byte(len(label)))
+		message = append(message, label...)
+	}
+	return append(message, 0x00, 0x00, 0x01, 0x00, 0x01)
+}
+
+// Unit test - reading of server names from TLS, HTTP, and DNS payloads.
+// Parameter t *testing.T - testing engine.
+func TestReadServerName(t *testing.T) {
+	tcp := model.RawDataType{TransportProtocol: uint(PROTOCOL_TCP), SrcPort: 50000, DstPort: 443}
+	udp := model.RawDataType{TransportProtocol: uint(PROTOCOL_UDP), SrcPort: 50000, DstPort: 53}
+	otherUdp := model.RawDataType{TransportProtocol: uint(PROTOCOL_UDP), SrcPort: 50000, DstPort: 443}
+	truncatedHello := buildClientHello("www.youtube.com")
+	tests := []struct {
+		name			string
+		rawDataType		model.RawDataType
+		payload			[]byte
+		serverName		string
+		source			uint
+	}{
+		{"TLS SNI", tcp, buildClientHello("WWW.YouTube.com"), "www.youtube.com", model.SERVER_NAME_TLS},
+		{"TLS without SNI", tcp, buildClientHello(""), "", model.SERVER_NAME_ANY},
+		{"truncated ClientHello", tcp, truncatedHello[:len(truncatedHello) - 4], "", model.SERVER_NAME_ANY},
+		{"TLS application data", tcp, []byte{0x17, 0x03, 0x03, 0x00, 0x10, 0x00, 0x00, 0x00, 0x00}, "",
+			model.SERVER_NAME_ANY},
+		{"HTTP Host", tcp, []byte("GET /index.html HTTP/1.1\r\nUser-Agent: test\r\nhost: Example.com:8080\r\n\r\n"),
+			"example.com", model.SERVER_NAME_HTTP},
+		{"HTTP without Host", tcp, []byte("GET / HTTP/1.0\r\n\r\nHost: example.com\r\n"), "",
+			model.SERVER_NAME_ANY},
+		{"HTTP response", tcp, []byte("HTTP/1.1 200 OK\r\nHost: example.com\r\n\r\n"), "", model.SERVER_NAME_ANY},
+		{"DNS query", udp, buildDnsQuery("www", "YouTube", "com"), "www.youtube.com", model.SERVER_NAME_DNS},
+		{"DNS without question", udp, []byte{0x12, 0x34, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x00}, "", model.SERVER_NAME_DNS},
+		{"compressed DNS name", udp, append(buildDnsQuery()[:12], 0xc0, 0x0c), "", model.SERVER_NAME_DNS},
+		{"truncated DNS name", udp, buildDnsQuery("www", "youtube", "com")[:20], "", model.SERVER_NAME_DNS},
+		{"UDP without DNS", otherUdp, buildDnsQuery("www", "youtube", "com"), "", model.SERVER_NAME_ANY},
+	}
+	for _, test := range tests {
+		serverName, source := readServerName(&test.rawDataType, test.payload)
+		if serverName != test.serverName || (serverName != "" && source != test.source) {
+			t.Errorf("%s: expected server name: %s (%d); given server name: %s (%d)", test.name, test.serverName,
+				test.source, serverName, source)
+		}
+	}
+}
+
+// Unit test - flow-sticky classification of both directions and expiration of idle flows.
+// Parameter t *testing.T - testing engine.
+func TestApplicationClassifierFlows(t *testing.T) {
+	classifier := NewApplicationClassifier()
+	client := model.NewIPAddress(net.ParseIP("192.168.1.10"))
+	server := model.NewIPAddress(net.ParseIP("142.250.1.1"))
+	request := model.RawDataType{TransportProtocol: uint(PROTOCOL_TCP), SrcAddress: client, DstAddress: server,
+		SrcPort: 50000, DstPort: 443}
+	response := model.RawDataType{TransportProtocol: uint(PROTOCOL_TCP), SrcAddress: server, DstAddress: client,
+		SrcPort: 443, DstPort: 50000}
+	otherFlow := request
+	otherFlow.SrcPort = 50001
+	startTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name			string
+		rawDataType		model.RawDataType
+		payload			[]byte
+		timestamp		time.Time
+		serverName		string
+	}{
+		{"handshake before identification", request, nil, startTime, ""},
+		{"ClientHello", request, buildClientHello("www.youtube.com"), startTime, "www.youtube.com"},
+		{"encrypted request", request, []byte{0x17, 0x03, 0x03}, startTime.Add(time.Second), "www.youtube.com"},
+		{"response", response, []byte{0x17, 0x03, 0x03}, startTime.Add(time.Minute), "www.youtube.com"},
+		{"other flow", otherFlow, []byte{0x17, 0x03, 0x03}, startTime.Add(time.Minute), ""},
+		{"active flow", response, nil, startTime.Add(2 * time.Minute), "www.youtube.com"},
+		{"expired flow", request, nil, startTime.Add(5 * time.Minute), ""},
+	}
+	for _, test := range tests {
+		rawDataType := test.rawDataType
+		classifier.Classify(&rawDataType, test.payload, test.timestamp)
+		if rawDataType.ServerName != test.serverName {
+			t.Errorf("%s: expected server name: %s; given server name: %s", test.name, test.serverName,
+				rawDataType.ServerName)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package machine
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default interval of sampling of capture statistics [ms].
+const STATISTICS_INTERVAL_DEFAULT = uint(10000)
+
+// Health counters of one capture source.
+// Attribute InterfaceName string - identifier of the observed link. See model.CaptureSource.
+// Attribute ReceivedFrames uint64 - frames received by pcap (sampled from pcap.Handle.Stats).
+// Attribute KernelDrops uint64 - frames dropped by kernel because of full buffer (sampled from pcap.Handle.Stats).
+// Attribute InterfaceDrops uint64 - frames dropped by network interface or its driver (sampled from
+// pcap.Handle.Stats).
+// Attribute EarlyBucketFlushes uint64 - how many times the frames bucket has been filled and submitted before the next
+// tick (no frames are dropped; sustained growth signals rate close to capacity of parse workers).
+// Attribute PipelineDrops uint64 - frames dropped by the frames pipeline according to its drop policy.
+// Attribute NonEncapsulatedFrames uint64 - frames without the configured encapsulation (non-TZSP frames by default).
+// Attribute MalformedFrames uint64 - unwrapped frames that cannot be decoded to raw data type.
+// Attribute ParseErrors uint64 - frames whose parsing has failed on runtime error (truncated or corrupted headers).
+// Attribute SampleTime time.Time - time of the last sampling of pcap statistics. See time.Time.
+type CaptureCounters struct {
+	InterfaceName			string
+	ReceivedFrames			uint64
+	KernelDrops				uint64
+	InterfaceDrops			uint64
+	EarlyBucketFlushes		uint64
+	PipelineDrops			uint64
+	NonEncapsulatedFrames	uint64
+	MalformedFrames			uint64
+	ParseErrors				uint64
+	SampleTime				time.Time
+}
+
+// Attribute mutex *sync.Mutex - synchronisation of capturing goroutines and readers. See sync.Mutex.
+// Attribute counters map[string](*CaptureCounters) - health counters by interface identifiers. See CaptureCounters.
+type CaptureStatistics struct {
+	mutex		*sync.Mutex
+	counters	map[string](*CaptureCounters)
+}
+
+// Creating instance of the CaptureStatistics.
+// Returning *CaptureStatistics - CaptureStatistics object without counters.
+func NewCaptureStatistics() *CaptureStatistics {
+	captureStatistics := CaptureStatistics{
+		mutex: &sync.Mutex{},
+		counters: make(map[string](*CaptureCounters)),
+	}
+	return &captureStatistics
+}
+
+// Fetching of counters of the capture source; the counters are created if they don't exist (mutex must be locked).
+// Parameter interfaceName string - identifier of the observed link.
+// Returning *CaptureCounters - counters of the link. See CaptureCounters.
+func (CaptureStatistics *CaptureStatistics) getCounters(interfaceName string) *CaptureCounters {
+	counters, present := CaptureStatistics.counters[interfaceName]
+	if !present {
+		counters = &CaptureCounters{InterfaceName: interfaceName}
+		CaptureStatistics.counters[interfaceName] = counters
+	}
+	return counters
+}
+
+// Registering of the capture source, so its counters are listed even before the first frame arrives.
+// Parameter interfaceName string - identifier of the observed link.
+func (CaptureStatistics *CaptureStatistics) RegisterSource(interfaceName string) {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	CaptureStatistics.getCounters(interfaceName)
+}
+
+// Setting of counters sampled from pcap handle (the values are cumulative since opening of the handle).
+// Parameter interfaceName string - identifier of the observed link.
+// Parameter received uint64 - frames received by pcap.
+// Parameter kernelDrops uint64 - frames dropped by kernel.
+// Parameter interfaceDrops uint64 - frames dropped by network interface.
+// Parameter sampleTime time.Time - time of the sampling. See time.Time.
+// Returning uint64 - count of newly dropped frames since the previous sampling.
+func (CaptureStatistics *CaptureStatistics) SetHandleStatistics(interfaceName string, received uint64,
+	kernelDrops uint64, interfaceDrops uint64, sampleTime time.Time) uint64 {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	counters := CaptureStatistics.getCounters(interfaceName)
+	var newDrops uint64
+	if kernelDrops + interfaceDrops > counters.KernelDrops + counters.InterfaceDrops {
+		newDrops = kernelDrops + interfaceDrops - counters.KernelDrops - counters.InterfaceDrops
+	}
+	counters.ReceivedFrames = received
+	counters.KernelDrops = kernelDrops
+	counters.InterfaceDrops = interfaceDrops
+	counters.SampleTime = sampleTime
+	return newDrops
+}
+
+// Increasing of counter of full frames buckets submitted before the next tick.
+// Parameter interfaceName string - identifier of the observed link.
+func (CaptureStatistics *CaptureStatistics) AddEarlyBucketFlush(interfaceName string) {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	CaptureStatistics.getCounters(interfaceName).EarlyBucketFlushes++
+}
+
+// Adding of frames that have been dropped by the frames pipeline.
+// Parameter interfaceName string - identifier of the observed link.
+// Parameter frames uint64 - number of dropped frames.
+func (CaptureStatistics *CaptureStatistics) AddPipelineDrops(interfaceName string, frames uint64) {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	CaptureStatistics.getCounters(interfaceName).PipelineDrops += frames
+}
+
+// Adding of frame counters of one processed bucket.
+// Parameter interfaceName string - identifier of the observed link.
+// Parameter nonEncapsulated uint64 - frames without the configured encapsulation.
+// Parameter malformed uint64 - frames that cannot be decoded.
+// Parameter parseErrors uint64 - frames whose parsing has failed on runtime error.
+func (CaptureStatistics *CaptureStatistics) AddFrameErrors(interfaceName string, nonEncapsulated uint64,
+	malformed uint64, parseErrors uint64) {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	counters := CaptureStatistics.getCounters(interfaceName)
+	counters.NonEncapsulatedFrames += nonEncapsulated
+	counters.MalformedFrames += malformed
+	counters.ParseErrors += parseErrors
+}
+
+// Listing of counters of all capture sources.
+// Returning []CaptureCounters - copies of counters sorted by interface identifiers. See CaptureCounters.
+func (CaptureStatistics *CaptureStatistics) ListCounters() []CaptureCounters {
+	CaptureStatistics.mutex.Lock()
+	defer CaptureStatistics.mutex.Unlock()
+	list := make([]CaptureCounters, 0, len(CaptureStatistics.counters))
+	for _, counters := range CaptureStatistics.counters {
+		list = append(list, *counters)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].InterfaceName < list[j].InterfaceName
+	})
+	return list
+}
@@ -0,0 +1,37 @@
+package machine
+
+import (
+	"testing"
+	"time"
+)
+
+// Unit test - accumulation of capture health counters and detection of new drops.
+// Parameter t *testing.T - testing engine.
+func TestCaptureStatistics(t *testing.T) {
+	captureStatistics := NewCaptureStatistics()
+	captureStatistics.RegisterSource("eth2")
+
+	t.Log("Sampling of pcap statistics ...")
+	if newDrops := captureStatistics.SetHandleStatistics("eth1", 1000, 5, 1, time.Now()); newDrops != 6 {
+		t.Errorf("Expected new drops: 6; given new drops: %d", newDrops)
+	}
+	if newDrops := captureStatistics.SetHandleStatistics("eth1", 2000, 5, 1, time.Now()); newDrops != 0 {
+		t.Errorf("Expected new drops: 0; given new drops: %d", newDrops)
+	}
+
+	t.Log("Adding of early bucket flushes and frame errors ...")
+	captureStatistics.AddEarlyBucketFlush("eth1")
+	captureStatistics.AddFrameErrors("eth1", 3, 2, 1)
+	captureStatistics.AddFrameErrors("eth1", 1, 0, 0)
+
+	counters := captureStatistics.ListCounters()
+	if len(counters) != 2 || counters[0].InterfaceName != "eth1" || counters[1].InterfaceName != "eth2" {
+		t.Fatalf("Unexpected listed counters: %+v", counters)
+	}
+	eth1 := counters[0]
+	if eth1.ReceivedFrames != 2000 || eth1.KernelDrops != 5 || eth1.InterfaceDrops != 1 ||
+		eth1.EarlyBucketFlushes != 1 || eth1.NonEncapsulatedFrames != 4 || eth1.MalformedFrames != 2 ||
+		eth1.ParseErrors != 1 {
+		t.Errorf("Unexpected counters of eth1: %+v", eth1)
+	}
+}
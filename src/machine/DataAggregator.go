@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"model"
+	"time"
+)
+
+// Identification of aggregated entry - raw data type within one second (the finest resolution of data counters, so
+// buckets that cover a long time, for example during replay of capture file, are not counted at one time).
+// Attribute rawDataType model.RawDataType - raw data type. See model.RawDataType.
+// Attribute bucket int64 - start of the second [Unix seconds].
+type aggregationKey struct {
+	rawDataType		model.RawDataType
+	bucket			int64
+}
+
+// Attribute repository map[aggregationKey](*model.RawData) - aggregated bytes and frames by raw data types and
+// seconds. See aggregationKey.
+type DataAggregator struct {
+	repository		map[aggregationKey](*model.RawData)
+}
+
+// Creating instance of the DataAggregator.
+// Returning *DataAggregator - DataAggregator object with empty repository.
+func NewDataAggregator() *DataAggregator {
+	dataAggregator := DataAggregator{
+		repository: make(map[aggregationKey](*model.RawData), STARTING_MAP_SIZE),
+	}
+	return &dataAggregator
+}
+
+// Adding of bytes and frames to the counters of raw data type within the second of arrival.
+// Parameter rawDataType model.RawDataType - raw data type that identifies the counters. See model.RawDataType.
+// Parameter bytes uint64 - number of added bytes.
+// Parameter packets uint64 - number of added frames.
+// Parameter time time.Time - arrival time of the added bytes; the latest arrival time of the raw data type within the
+// second is kept. See time.Time.
+func (DataAggregator *DataAggregator) AddEntry(rawDataType model.RawDataType, bytes uint64, packets uint64,
+	time time.Time) {
+	key := aggregationKey{rawDataType: rawDataType, bucket: time.Truncate(model.RESOLUTION_SECOND).Unix()}
+	data, present := DataAggregator.repository[key]
+	if present {
+		data.Bytes += bytes
+		data.Packets += packets
+		if time.After(data.Time) {
+			data.Time = time
+		}
+	} else {
+		DataAggregator.repository[key] = &model.RawData{
+			Bytes: bytes,
+			Packets: packets,
+			Time: time,
+			RawDataType: &rawDataType,
+		}
+	}
+}
+
+// Building of slice from aggregated entries.
+// Returning *[](*model.RawData) - aggregated entries. See model.RawData.
+func (DataAggregator *DataAggregator) BuildSlice() *[](*model.RawData) {
+	slice := make([](*model.RawData), len(DataAggregator.repository))
+	i := uint(0)
+	for _, value := range DataAggregator.repository {
+		slice[i] = value
+		i++
+	}
+	return &slice
+}
+
+// Checking if there are some aggregated entries.
+// Returning bool - true if the repository is empty.
+func (DataAggregator *DataAggregator) IsEmpty() bool {
+	return len(DataAggregator.repository) == 0
+}
@@ -8,20 +8,20 @@ import (
 
 // Attribute cleaningConfiguration *model.CleaningConfiguration - cleaning depth and interval. See
 // model.CleaningConfiguration.
-// Attribute statisticalData *model.StatisticalData - instance that control access to SQL database.
-// See model.StatisticalData.
+// Attribute statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
 type DataCleaner struct {
 	cleaningConfiguration 	*model.CleaningConfiguration
-	statisticalData 		*model.StatisticalData
+	statisticalData 		model.Storage
 }
 
 // Creating instance of the DataCleaner.
 // Parameter cleaningConfiguration *model.CleaningConfiguration - cleaning depth and interval. See
 // model.CleaningConfiguration.
-// Parameter statisticalData *model.StatisticalData - instance that control access to SQL database.
-// See model.StatisticalData.
+// Parameter statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
 // Returning *DataCleaner - DataCleaner object.
-func NewDataCleaner(cleaningConf *model.CleaningConfiguration, statisticalData *model.StatisticalData) *DataCleaner {
+func NewDataCleaner(cleaningConf *model.CleaningConfiguration, statisticalData model.Storage) *DataCleaner {
 	dataCleaner := DataCleaner{
 		cleaningConfiguration: cleaningConf,
 		statisticalData: statisticalData,
@@ -35,17 +35,17 @@ func (DataCleaner *DataCleaner) StartCleaning() {
 	go periodicTask(DataCleaner.cleaningConfiguration, DataCleaner.statisticalData)
 }
 
-// Function executes infinite loop under which old data entries are periodically removed to the configured depth.
+// Function executes infinite loop under which old data entries are periodically removed to the configured depths of
+// their resolutions (see model.CleaningConfiguration.ListResolutions) or retentions of their data types (see
+// model.DataType).
 // Parameter cleaningConfiguration *model.CleaningConfiguration - cleaning depth and interval. See
 // model.CleaningConfiguration.
-func periodicTask(cleaningConfiguration *model.CleaningConfiguration, statisticalData *model.StatisticalData) {
+func periodicTask(cleaningConfiguration *model.CleaningConfiguration, statisticalData model.Storage) {
 	ticker := time.NewTicker(time.Duration(cleaningConfiguration.CleaningInterval) * time.Millisecond)
 	for {
 		select {
 		case <- ticker.C:
-			now := time.Now()
-			limit := now.Add(- time.Duration(cleaningConfiguration.CleaningDepth) * time.Millisecond)
-			statisticalData.RemoveOldDataEntries(limit)
+			statisticalData.RemoveOldDataEntries(time.Now())
 		}
 	}
 	configuration.Info.Println("Cleaning of data entries finished.")
@@ -23,6 +23,8 @@ const LINE_LENGTH uint = 16
 const DIRECTION_RX = "RX"
 // String representation of upstream traffic direction.
 const DIRECTION_TX = "TX"
+// String representation of traffic between local networks.
+const DIRECTION_INTERNAL = "INT"
 // Conversion ration between bytes and kilo-bytes.
 const CONVERSION_RATIO_KB = 1000
 // Conversion ration between bytes and mega-bytes.
@@ -39,19 +41,28 @@ const B_UNIT = "B"
 const KB_UNIT = "kB"
 // String representation of mega-bytes.
 const MB_UNIT = "MB"
+// When this frames treshold is reached or exceeded, kilo-frames format instead of frames format must be used.
+const CONVERSION_TRESHOLD_P_KP = 1000
+// String representation of frames.
+const P_UNIT = "p"
+// String representation of kilo-frames.
+const KP_UNIT = "kp"
 
 // Attribute configData *model.GPIOConfiguration - GPIO bits layout. See model.GPIOConfiguration.
 // Attribute lcdMutex *sync.Mutex - semaphore that controls access to LCD device. See sync.Mutex.
 // Attribute displayMutex *sync.Mutex - semaphore that controls access to LCD displayed information. See sync.Mutex.
 // Attribute allDisplays *map[DisplayTemplate]*OutputData - actual list of displays - information that can be shown
 // on LCD. See DisplayTemplate.
+// Attribute packetRates *map[DisplayTemplate]float64 - mean frames counts that belong to load displays (prediction
+// displays have no frames count). See DisplayTemplate.
 // Attribute actualDisplay *DisplayTemplate - identification of information that are actually presented on LCD. See
 // DisplayTemplate.
 // Attribute smoothingRange uint - smoothing range in milliseconds.
 // Attribute ledMutex *sync.Mutex - controlling of access to LED Neopixel strip.
 // Attribute designator	float64 - it describes criterion for changing prediction state - fraction of bandwidth that
 // must exceeded from actual load (positivw or negative fraction domain).
-// Attribute linkBandwidth uint64 - observed link bandwidth (maximum load) [bytes/s].
+// Attribute linkBandwidths map[string]uint64 - bandwidths of observed links (maximum load) [bytes/s] by interface
+// identifiers. See model.CaptureSource.
 // Attribute robot *gobot.Robot - buttons listeners.
 type DeviceManager struct {
 	configData		*model.PHYConfiguration
@@ -59,10 +70,11 @@ type DeviceManager struct {
 	displayMutex	*sync.Mutex
 	ledMutex		*sync.Mutex
 	allDisplays		*map[DisplayTemplate]float64
+	packetRates		*map[DisplayTemplate]float64
 	actualDisplay	*DisplayTemplate
 	smoothingRange	uint
 	designator		float64
-	linkBandwidth	uint64
+	linkBandwidths	map[string]uint64
 	robot			*gobot.Robot
 }
 
@@ -71,23 +83,30 @@ type DeviceManager struct {
 // Parameter smoothingRange uint - smoothing range in milliseconds.
 // Parameter designator	float64 - it describes criterion for changing prediction state - fraction of bandwidth that
 // must exceeded from actual load (positivw or negative fraction domain).
+// Parameter captureSources []model.CaptureSource - observed links with their bandwidths. See model.CaptureSource.
 // Returning *DeviceManager - built instance of DeviceManager structure (its reference). See DeviceManager.
 func NewDeviceManager(conf *model.PHYConfiguration, smoothingRange uint, designator	float64,
-	linkBandwidth	uint64) *DeviceManager {
+	captureSources	[]model.CaptureSource) *DeviceManager {
 	var lcdMutex = &sync.Mutex{}
 	var displayMutex = &sync.Mutex{}
 	var ledMutex = &sync.Mutex{}
 	allDisplays := make(map[DisplayTemplate]float64)
+	packetRates := make(map[DisplayTemplate]float64)
+	linkBandwidths := make(map[string]uint64)
+	for _, captureSource := range captureSources {
+		linkBandwidths[captureSource.Name] = captureSource.LinkBandwidth
+	}
 	ioDeviceManager := DeviceManager {
 		configData:			conf,
 		lcdMutex:			lcdMutex,
 		actualDisplay:		nil,
 		allDisplays:		&allDisplays,
+		packetRates:		&packetRates,
 		smoothingRange:		smoothingRange,
 		displayMutex:		displayMutex,
 		ledMutex:			ledMutex,
 		designator:			designator,
-		linkBandwidth:		linkBandwidth,
+		linkBandwidths:		linkBandwidths,
 	}
 	return &ioDeviceManager
 }
@@ -164,7 +183,7 @@ func (DeviceManager *DeviceManager) handleRightButtonPushed() {
 	}
 }
 
-// Sorting of displays by their name, direction, and prediction flag.
+// Sorting of displays by their name, direction (RX, TX, internal), and prediction flag.
 // Parameter allDisplays *map[DisplayTemplate]*CalculatedData - all displays. See DisplayTemplate.
 // Returning *[]DisplayTemplate - sorted display templates (keys of map).
 func getSortedDisplays(allDisplays *map[DisplayTemplate]float64) *[]DisplayTemplate {
@@ -242,13 +261,15 @@ func (DeviceManager *DeviceManager) ClearLcd() {
 	}
 }
 
-// Processing of new average load identified by display template and resulting value.
+// Processing of new average load identified by display template and resulting values.
 // Parameter display *DisplayTemplate - fresh display information.
-// Parameter result float64 - computed average load.
-func (DeviceManager *DeviceManager) UpdateDisplayByLoad(display *DisplayTemplate, result float64) {
+// Parameter result float64 - computed average load (bytes).
+// Parameter packets float64 - computed average frames count.
+func (DeviceManager *DeviceManager) UpdateDisplayByLoad(display *DisplayTemplate, result float64, packets float64) {
 	DeviceManager.displayMutex.Lock()
 	defer DeviceManager.displayMutex.Unlock()
 	(*DeviceManager.allDisplays)[*display] = result
+	(*DeviceManager.packetRates)[*display] = packets
 	if DeviceManager.actualDisplay != nil && *DeviceManager.actualDisplay == *display {
 		DeviceManager.updateDisplayByLoadI(display, result)
 	} else if DeviceManager.actualDisplay == nil {
@@ -258,9 +279,10 @@ func (DeviceManager *DeviceManager) UpdateDisplayByLoad(display *DisplayTemplate
 }
 
 func (DeviceManager *DeviceManager) updateDisplayByLoadI(display *DisplayTemplate, result float64) {
-	line1, line2 := getMeanLines(DeviceManager.smoothingRange, display, result)
+	line1, line2 := getMeanLines(DeviceManager.smoothingRange, display, result,
+		(*DeviceManager.packetRates)[*display], DeviceManager.hasMultipleLinks())
 	DeviceManager.WriteMessageOnLcd(line1, line2)
-	DeviceManager.updateLcdDisplay(result)
+	DeviceManager.updateLcdDisplay(display.interfaceName, result)
 }
 
 // Updating of display by results of ARIMA forecasting model.
@@ -279,12 +301,14 @@ func (DeviceManager *DeviceManager) UpdateDisplayByPrediction(display *DisplayTe
 }
 
 func (DeviceManager *DeviceManager) updateDisplayByPredictionI(display *DisplayTemplate, result float64) {
-	actualLoad := findMeanLoadOfTemplate(DeviceManager.allDisplays, display.dataTypeName, display.direction)
+	actualLoad := findMeanLoadOfTemplate(DeviceManager.allDisplays, display.dataTypeName, display.interfaceName,
+		display.direction)
 	state := getStateFromPredictedAndActualValue(result, actualLoad, DeviceManager.designator,
-		DeviceManager.linkBandwidth)
-	line1, line2 := getPredictionLines(DeviceManager.smoothingRange, display, result, state)
+		DeviceManager.linkBandwidths[display.interfaceName])
+	line1, line2 := getPredictionLines(DeviceManager.smoothingRange, display, result, state,
+		DeviceManager.hasMultipleLinks())
 	DeviceManager.WriteMessageOnLcd(line1, line2)
-	DeviceManager.updateLcdDisplay(result)
+	DeviceManager.updateLcdDisplay(display.interfaceName, result)
 }
 
 // Parsing of state string from predicted load, actual load, and designator fraction.
@@ -315,11 +339,14 @@ func getStateFromPredictedAndActualValue(predictedValue float64, actualValue flo
 // Finding of the mean load of adjacent display that is used for presentation of actual load.
 // Parameter allDisplays *map[DisplayTemplate]float64 - all displays.
 // Parameter dataTypeName string - name of the data type that is searched.
+// Parameter interfaceName string - identifier of the observed link.
 // Prameter direction uint - direction of load measurement.
 // Returning float64 - found actual mean load or 0.0 if it is not found within input constraints.
-func findMeanLoadOfTemplate(allDisplays *map[DisplayTemplate]float64, dataTypeName string, direction uint) float64 {
+func findMeanLoadOfTemplate(allDisplays *map[DisplayTemplate]float64, dataTypeName string, interfaceName string,
+	direction uint) float64 {
 	for display := range *allDisplays {
-		if display.dataTypeName == dataTypeName && display.direction == direction && display.prediction == false {
+		if display.dataTypeName == dataTypeName && display.interfaceName == interfaceName &&
+			display.direction == direction && display.prediction == false {
 			meanLoad := (*allDisplays)[display]
 			return meanLoad
 		}
@@ -327,22 +354,52 @@ func findMeanLoadOfTemplate(allDisplays *map[DisplayTemplate]float64, dataTypeNa
 	return 0.0
 }
 
+// Converting of direction to its string representation shown on LCD.
+// Parameter direction uint - RX, TX, or internal direction. See model.DIRECTION_RX.
+// Returning string - RX, TX, or INT.
+func directionToString(direction uint) string {
+	switch direction {
+	case model.DIRECTION_TX:
+		return DIRECTION_TX
+	case model.DIRECTION_INTERNAL:
+		return DIRECTION_INTERNAL
+	default:
+		return DIRECTION_RX
+	}
+}
+
+// Building of the name shown on LCD.
+// Parameter display *DisplayTemplate - shown display.
+// Parameter showInterface bool - the interface identifier is shown before the data type name.
+// Returning string - data type name, optionally prefixed by interface identifier (for example eth1:HTTP).
+func getDisplayName(display *DisplayTemplate, showInterface bool) string {
+	if showInterface {
+		return display.interfaceName + ":" + display.dataTypeName
+	}
+	return display.dataTypeName
+}
+
+// Checking whether more than one link is observed (interface identifiers must be shown on LCD).
+// Returning bool - true if there are multiple links.
+func (DeviceManager *DeviceManager) hasMultipleLinks() bool {
+	return len(DeviceManager.linkBandwidths) > 1
+}
+
 // Parsing of display template and computed load to string lines of LCD.
 // Parameter smoothingRange uint - smoothing range used by SmoothingCreator.
 // Parameter display *DisplayTemplate - modified display.
 // Parameter result float64 - computed average for specific display.
+// Parameter packets float64 - computed average frames count for specific display.
+// Parameter showInterface bool - the interface identifier is shown before the data type name.
 // Returning line1 string - first LCD line.
 // Returning line2 string - second LCD line.
-func getMeanLines(smoothingRange uint, display *DisplayTemplate, result float64) (line1 string, line2 string) {
-	var direction string
-	if display.direction == 0 {
-		direction = DIRECTION_RX
-	} else {
-		direction = DIRECTION_TX
-	}
-	truncatedName := display.dataTypeName
-	if uint(len(truncatedName)) > LINE_LENGTH - 3 {
-		truncatedName = truncatedName[:LINE_LENGTH-3]
+func getMeanLines(smoothingRange uint, display *DisplayTemplate, result float64, packets float64,
+	showInterface bool) (line1 string, line2 string) {
+	direction := directionToString(display.direction)
+	truncatedName := getDisplayName(display, showInterface)
+	nameLength := LINE_LENGTH - uint(len(direction)) - 1
+	if uint(len(truncatedName)) > nameLength {
+		truncatedName = truncatedName[:nameLength]
 	}
 	var formattedResult string
 	var unit string
@@ -362,8 +419,17 @@ func getMeanLines(smoothingRange uint, display *DisplayTemplate, result float64)
 	} else {
 		rate = "/" + string(smoothingRange/BASIC_RATE)
 	}
+	var formattedPackets string
+	if packets < CONVERSION_TRESHOLD_P_KP {
+		formattedPackets = fmt.Sprint(uint64(packets)) + P_UNIT
+	} else {
+		formattedPackets = fmt.Sprint(uint64(packets/ CONVERSION_TRESHOLD_P_KP)) + KP_UNIT
+	}
 	line1Out := direction + " " + truncatedName
-	line2Out := formattedResult + " " + unit + rate
+	line2Out := formattedResult + " " + unit + rate + " " + formattedPackets + rate
+	if uint(len(line2Out)) > LINE_LENGTH {
+		line2Out = line2Out[:LINE_LENGTH]
+	}
 	return line1Out, line2Out
 }
 
@@ -372,19 +438,16 @@ func getMeanLines(smoothingRange uint, display *DisplayTemplate, result float64)
 // Parameter display *DisplayTemplate - modified display.
 // Parameter result float64 - computed prediction for specific display.
 // Parameter state string - R (load raises) / D (load drops) / S (load is still).
+// Parameter showInterface bool - the interface identifier is shown before the data type name.
 // Returning line1 string - first LCD line.
 // Returning line2 string - second LCD line.
-func getPredictionLines(smoothingRange uint, display *DisplayTemplate, result float64, state string) (
-	line1 string, line2 string) {
-	var direction string
-	if display.direction == 0 {
-		direction = DIRECTION_RX
-	} else {
-		direction = DIRECTION_TX
-	}
-	truncatedName := display.dataTypeName
-	if uint(len(truncatedName)) > LINE_LENGTH - 5 {
-		truncatedName = truncatedName[:LINE_LENGTH-5]
+func getPredictionLines(smoothingRange uint, display *DisplayTemplate, result float64, state string,
+	showInterface bool) (line1 string, line2 string) {
+	direction := directionToString(display.direction)
+	truncatedName := getDisplayName(display, showInterface)
+	nameLength := LINE_LENGTH - uint(len(direction)) - 3
+	if uint(len(truncatedName)) > nameLength {
+		truncatedName = truncatedName[:nameLength]
 	}
 	var formattedResult string
 	var unit string
@@ -445,6 +508,7 @@ func (DeviceManager *DeviceManager) deleteDisplays(foundDisplays *[]DisplayTempl
 			DeviceManager.recoverFromRemovedDisplay()
 		}
 		delete(*DeviceManager.allDisplays, display)
+		delete(*DeviceManager.packetRates, display)
 	}
 }
 
@@ -468,18 +532,21 @@ func (DeviceManager *DeviceManager) setNextDisplay(sortedDisplays *[]DisplayTemp
 	nextDisplay := (*sortedDisplays)[index + 1]
 	if !nextDisplay.prediction {
 		nextValue := (*DeviceManager.allDisplays)[nextDisplay]
-		line1, line2 := getMeanLines(DeviceManager.smoothingRange, &nextDisplay, nextValue)
+		line1, line2 := getMeanLines(DeviceManager.smoothingRange, &nextDisplay, nextValue,
+			(*DeviceManager.packetRates)[nextDisplay], DeviceManager.hasMultipleLinks())
 		DeviceManager.WriteMessageOnLcd(line1, line2)
 		DeviceManager.actualDisplay = &nextDisplay
-		DeviceManager.updateLcdDisplay(nextValue)
+		DeviceManager.updateLcdDisplay(nextDisplay.interfaceName, nextValue)
 	} else {
 		nextValue := (*DeviceManager.allDisplays)[nextDisplay]
-		actualLoad := findMeanLoadOfTemplate(DeviceManager.allDisplays, nextDisplay.dataTypeName, nextDisplay.direction)
+		actualLoad := findMeanLoadOfTemplate(DeviceManager.allDisplays, nextDisplay.dataTypeName,
+			nextDisplay.interfaceName, nextDisplay.direction)
 		state := getStateFromPredictedAndActualValue(nextValue, actualLoad, DeviceManager.designator,
-			DeviceManager.linkBandwidth)
-		line1, line2 := getPredictionLines(DeviceManager.smoothingRange, &nextDisplay, nextValue, state)
+			DeviceManager.linkBandwidths[nextDisplay.interfaceName])
+		line1, line2 := getPredictionLines(DeviceManager.smoothingRange, &nextDisplay, nextValue, state,
+			DeviceManager.hasMultipleLinks())
 		DeviceManager.WriteMessageOnLcd(line1, line2)
-		DeviceManager.updateLcdDisplay(nextValue)
+		DeviceManager.updateLcdDisplay(nextDisplay.interfaceName, nextValue)
 	}
 	DeviceManager.actualDisplay = &nextDisplay
 }
@@ -491,18 +558,20 @@ func (DeviceManager *DeviceManager) setPreviousDisplay(sortedDisplays *[]Display
 	previousDisplay := (*sortedDisplays)[index - 1]
 	if !previousDisplay.prediction {
 		previousValue := (*DeviceManager.allDisplays)[previousDisplay]
-		line1, line2 := getMeanLines(DeviceManager.smoothingRange, &previousDisplay, previousValue)
+		line1, line2 := getMeanLines(DeviceManager.smoothingRange, &previousDisplay, previousValue,
+			(*DeviceManager.packetRates)[previousDisplay], DeviceManager.hasMultipleLinks())
 		DeviceManager.WriteMessageOnLcd(line1, line2)
-		DeviceManager.updateLcdDisplay(previousValue)
+		DeviceManager.updateLcdDisplay(previousDisplay.interfaceName, previousValue)
 	} else {
 		previousValue := (*DeviceManager.allDisplays)[previousDisplay]
 		actualLoad := findMeanLoadOfTemplate(DeviceManager.allDisplays, previousDisplay.dataTypeName,
-			previousDisplay.direction)
+			previousDisplay.interfaceName, previousDisplay.direction)
 		state := getStateFromPredictedAndActualValue(previousValue, actualLoad, DeviceManager.designator,
-			DeviceManager.linkBandwidth)
-		line1, line2 := getPredictionLines(DeviceManager.smoothingRange, &previousDisplay, previousValue, state)
+			DeviceManager.linkBandwidths[previousDisplay.interfaceName])
+		line1, line2 := getPredictionLines(DeviceManager.smoothingRange, &previousDisplay, previousValue, state,
+			DeviceManager.hasMultipleLinks())
 		DeviceManager.WriteMessageOnLcd(line1, line2)
-		DeviceManager.updateLcdDisplay(previousValue)
+		DeviceManager.updateLcdDisplay(previousDisplay.interfaceName, previousValue)
 	}
 	DeviceManager.actualDisplay = &previousDisplay
 }
@@ -516,6 +585,7 @@ func (DeviceManager *DeviceManager) ModifyDataTypeName(dataTypeId uint, dataType
 	displaysToRemove := make([]DisplayTemplate, 0)
 	displaysToAdd := make([]DisplayTemplate, 0)
 	valuesToAdd := make([]float64, 0)
+	packetsToAdd := make([]float64, 0)
 	displaysMap := *DeviceManager.allDisplays
 	for display := range displaysMap {
 		if display.dataTypeId == dataTypeId {
@@ -523,28 +593,56 @@ func (DeviceManager *DeviceManager) ModifyDataTypeName(dataTypeId uint, dataType
 				dataTypeName: dataTypeName,
 				dataTypeId: display.dataTypeId,
 				prediction: display.prediction,
+				interfaceName: display.interfaceName,
 				direction: display.direction,
 			}
 			displaysToAdd = append(displaysToAdd, updatedDisplay)
 			valuesToAdd = append(valuesToAdd, (*DeviceManager.allDisplays)[display])
+			packetsToAdd = append(packetsToAdd, (*DeviceManager.packetRates)[display])
 			displaysToRemove = append(displaysToRemove, display)
 		}
 	}
 	for _, display := range displaysToRemove {
 		delete(*DeviceManager.allDisplays, display)
+		delete(*DeviceManager.packetRates, display)
 	}
 	for i := 0; i < len(valuesToAdd); i++ {
 		(*DeviceManager.allDisplays)[displaysToAdd[i]] = valuesToAdd[i]
+		if !displaysToAdd[i].prediction {
+			(*DeviceManager.packetRates)[displaysToAdd[i]] = packetsToAdd[i]
+		}
 	}
-	if DeviceManager.actualDisplay.dataTypeId == dataTypeId {
+	if DeviceManager.actualDisplay != nil && DeviceManager.actualDisplay.dataTypeId == dataTypeId {
 		DeviceManager.actualDisplay.dataTypeName = dataTypeName
 	}
 }
 
+// Listing of actual loads of all data types, links, and directions (predictions are not included).
+// Returning []LoadRecord - sorted loads converted to per-second rates. See LoadRecord.
+func (DeviceManager *DeviceManager) ListLoads() []LoadRecord {
+	DeviceManager.displayMutex.Lock()
+	defer DeviceManager.displayMutex.Unlock()
+	ratio := float64(BASIC_RATE) / float64(DeviceManager.smoothingRange)
+	loads := make([]LoadRecord, 0, len(*DeviceManager.packetRates))
+	for display, packets := range *DeviceManager.packetRates {
+		loads = append(loads, LoadRecord{
+			DataTypeId: display.dataTypeId,
+			DataTypeName: display.dataTypeName,
+			InterfaceName: display.interfaceName,
+			Direction: directionToString(display.direction),
+			BytesPerSecond: (*DeviceManager.allDisplays)[display] * ratio,
+			PacketsPerSecond: packets * ratio,
+		})
+	}
+	sort.Sort(LoadRecordSlice(loads))
+	return loads
+}
+
 // Updating of LED strip.
+// Parameter interfaceName string - identifier of the link whose bandwidth scales the value.
 // Parameter refreshedValue float64 - new value that is going to be displayed.
-func (DeviceManager *DeviceManager) updateLcdDisplay(refreshedValue float64) {
-	coefficient := float64(SPACE_MAX)/float64(DeviceManager.linkBandwidth)
+func (DeviceManager *DeviceManager) updateLcdDisplay(interfaceName string, refreshedValue float64) {
+	coefficient := float64(SPACE_MAX)/float64(DeviceManager.linkBandwidths[interfaceName])
 	k := uint16(coefficient*refreshedValue)
 	rgbSpace, err := NewRgbSpace(k)
 	if err == nil {
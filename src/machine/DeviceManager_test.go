@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"testing"
+	"model"
+)
+
+// Unit test - formatting of mean load with frames count on LCD lines.
+// Parameter t *testing.T - testing engine.
+func TestGetMeanLinesWithPackets(t *testing.T) {
+	display := DisplayTemplate{dataTypeName: "HTTP", direction: model.DIRECTION_TX}
+
+	t.Log("Formatting of small load ...")
+	line1, line2 := getMeanLines(1000, &display, 5000, 120, false)
+	if line1 != "TX HTTP" || line2 != "5000 B/s 120p/s" {
+		t.Errorf("Unexpected LCD lines: '%s' / '%s'", line1, line2)
+	}
+
+	t.Log("Formatting of large load ...")
+	_, line2 = getMeanLines(1000, &display, 50000, 15000, false)
+	if line2 != "50 kB/s 15kp/s" {
+		t.Errorf("Unexpected second LCD line: '%s'", line2)
+	}
+}
+
+// Unit test - listing of actual loads converted to per-second rates.
+// Parameter t *testing.T - testing engine.
+func TestListLoads(t *testing.T) {
+	captureSources := []model.CaptureSource{{Name: "eth1", LinkBandwidth: 1000000}}
+	deviceManager := NewDeviceManager(&model.PHYConfiguration{}, 2000, 0.1, captureSources)
+	(*deviceManager.allDisplays)[DisplayTemplate{dataTypeId: 1, dataTypeName: "HTTP", interfaceName: "eth1",
+		direction: model.DIRECTION_RX}] = 4000
+	(*deviceManager.packetRates)[DisplayTemplate{dataTypeId: 1, dataTypeName: "HTTP", interfaceName: "eth1",
+		direction: model.DIRECTION_RX}] = 10
+	(*deviceManager.allDisplays)[DisplayTemplate{dataTypeId: 1, dataTypeName: "HTTP", interfaceName: "eth1",
+		direction: model.DIRECTION_RX, prediction: true}] = 5000
+
+	loads := deviceManager.ListLoads()
+	expected := LoadRecord{DataTypeId: 1, DataTypeName: "HTTP", InterfaceName: "eth1", Direction: DIRECTION_RX,
+		BytesPerSecond: 2000, PacketsPerSecond: 5}
+	if len(loads) != 1 || loads[0] != expected {
+		t.Errorf("Expected loads: [%+v]; given loads: %+v", expected, loads)
+	}
+}
@@ -0,0 +1,112 @@
+package machine
+
+import (
+	"model"
+	"net"
+	"bytes"
+	"fmt"
+	"configuration"
+)
+
+// Attribute routerMacAddress *([]byte) - MAC address of monitored router's interface (nil - it is not configured).
+// Attribute localNetworks []*net.IPNet - local IPv4 / IPv6 prefixes (empty - subnet-based direction is disabled).
+type DirectionClassifier struct {
+	routerMacAddress	*([]byte)
+	localNetworks		[]*net.IPNet
+}
+
+// Creating instance of the DirectionClassifier.
+// Parameter routerMacAddress *([]byte) - MAC address of monitored router's interface (nil - it is not configured).
+// Parameter localNetworks []string - local prefixes in CIDR notation.
+// Returning *DirectionClassifier - DirectionClassifier object.
+// Returning error - some local prefix is not a valid CIDR.
+func NewDirectionClassifier(routerMacAddress *([]byte), localNetworks []string) (*DirectionClassifier, error) {
+	compositeError := configuration.NewCompositeError()
+	directionClassifier := DirectionClassifier{
+		routerMacAddress: routerMacAddress,
+	}
+	for _, localNetwork := range localNetworks {
+		_, prefix, err := net.ParseCIDR(localNetwork)
+		if err != nil {
+			compositeError.AddError(1, fmt.Sprintf("Invalid local network %s: %v", localNetwork, err))
+			continue
+		}
+		directionClassifier.localNetworks = append(directionClassifier.localNetworks, prefix)
+	}
+	err := compositeError.Evaluate()
+	if err != nil {
+		return nil, err
+	}
+	return &directionClassifier, nil
+}
+
+// Classification of direction - local prefixes have precedence over router's MAC address; traffic is considered
+// as RX if neither of them resolves direction.
+// Parameter sourceMacAddress []byte - source MAC address of frame (nil if it is not known).
+// Parameter sourceAddress net.IP - source IP address (nil if it is not known).
+// Parameter destinationAddress net.IP - destination IP address (nil if it is not known).
+// Returning uint - RX, TX, or internal direction. See model.DIRECTION_RX.
+func (DirectionClassifier *DirectionClassifier) Classify(sourceMacAddress []byte, sourceAddress net.IP,
+	destinationAddress net.IP) uint {
+	direction, resolved := DirectionClassifier.ClassifyByAddresses(sourceAddress, destinationAddress)
+	if resolved {
+		return direction
+	}
+	direction, resolved = DirectionClassifier.ClassifyByMacAddress(sourceMacAddress)
+	if resolved {
+		return direction
+	}
+	return model.DIRECTION_RX
+}
+
+// Classification of direction by local prefixes: local to non-local traffic is TX, non-local to local traffic is RX,
+// and local to local traffic is internal.
+// Parameter sourceAddress net.IP - source IP address.
+// Parameter destinationAddress net.IP - destination IP address.
+// Returning uint - RX, TX, or internal direction. See model.DIRECTION_RX.
+// Returning bool - false if local prefixes are not configured, some address is not known, or none of the addresses
+// is local (transit traffic).
+func (DirectionClassifier *DirectionClassifier) ClassifyByAddresses(sourceAddress net.IP,
+	destinationAddress net.IP) (uint, bool) {
+	if len(DirectionClassifier.localNetworks) == 0 || sourceAddress == nil || destinationAddress == nil {
+		return model.DIRECTION_RX, false
+	}
+	sourceLocal := DirectionClassifier.isLocal(sourceAddress)
+	destinationLocal := DirectionClassifier.isLocal(destinationAddress)
+	switch {
+	case sourceLocal && destinationLocal:
+		return model.DIRECTION_INTERNAL, true
+	case sourceLocal:
+		return model.DIRECTION_TX, true
+	case destinationLocal:
+		return model.DIRECTION_RX, true
+	default:
+		return model.DIRECTION_RX, false
+	}
+}
+
+// Classification of direction by router's MAC address: frames sent by router are TX, other frames are RX.
+// Parameter sourceMacAddress []byte - source MAC address of frame.
+// Returning uint - RX or TX direction. See model.DIRECTION_RX.
+// Returning bool - false if router's MAC address is not configured or source MAC address is not known.
+func (DirectionClassifier *DirectionClassifier) ClassifyByMacAddress(sourceMacAddress []byte) (uint, bool) {
+	if DirectionClassifier.routerMacAddress == nil || sourceMacAddress == nil {
+		return model.DIRECTION_RX, false
+	}
+	if bytes.Equal(sourceMacAddress, *DirectionClassifier.routerMacAddress) {
+		return model.DIRECTION_TX, true
+	}
+	return model.DIRECTION_RX, true
+}
+
+// Checking whether the address belongs to some local prefix.
+// Parameter address net.IP - IPv4 or IPv6 address.
+// Returning bool - true if the address is local.
+func (DirectionClassifier *DirectionClassifier) isLocal(address net.IP) bool {
+	for _, localNetwork := range DirectionClassifier.localNetworks {
+		if localNetwork.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
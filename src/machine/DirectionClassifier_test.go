@@ -0,0 +1,45 @@
+package machine
+
+import (
+	"testing"
+	"net"
+	"model"
+)
+
+// Testing of subnet-based direction - local prefixes have precedence over router's MAC address.
+func TestClassifyDirection(t *testing.T) {
+	otherMac := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	directionClassifier, err := NewDirectionClassifier(&decoderRouterMac,
+		[]string{"192.168.0.0/16", "2001:db8:1::/48"})
+	if err != nil {
+		t.Fatalf("Building of direction classifier failed: %v", err)
+	}
+	tests := []struct {
+		sourceMac		[]byte
+		source			string
+		destination		string
+		expected		uint
+	}{
+		{otherMac, "192.168.1.10", "8.8.8.8", model.DIRECTION_TX},
+		{decoderRouterMac, "8.8.8.8", "192.168.1.10", model.DIRECTION_RX},
+		{decoderRouterMac, "192.168.1.10", "192.168.2.20", model.DIRECTION_INTERNAL},
+		{otherMac, "2001:db8:1::10", "2001:db8:1::20", model.DIRECTION_INTERNAL},
+		{otherMac, "2001:db8:2::10", "2001:db8:1::20", model.DIRECTION_RX},
+		// transit and non-IP traffic falls back to router's MAC address
+		{decoderRouterMac, "10.0.0.1", "8.8.8.8", model.DIRECTION_TX},
+		{decoderRouterMac, "", "", model.DIRECTION_TX},
+		{otherMac, "", "", model.DIRECTION_RX},
+	}
+	for _, test := range tests {
+		direction := directionClassifier.Classify(test.sourceMac, net.ParseIP(test.source),
+			net.ParseIP(test.destination))
+		if direction != test.expected {
+			t.Errorf("Wrong direction of %s -> %s: expected %d, got %d", test.source, test.destination,
+				test.expected, direction)
+		}
+	}
+	_, err = NewDirectionClassifier(&decoderRouterMac, []string{"192.168.0.0/33"})
+	if err == nil {
+		t.Errorf("Invalid local network should be rejected")
+	}
+}
@@ -6,11 +6,13 @@ import (
 
 // Attribute dataTypeId uint - ID of the data type (unique characterisation of the data type).
 // Attribute dataTypeName string - identification of the data type displayed on LCD.
-// Attribute direction uint - RX: 0, TX: 1.
+// Attribute interfaceName string - identifier of the observed link. See model.CaptureSource.
+// Attribute direction uint - RX: 0, TX: 1, internal: 2.
 // Attribute prediction	bool - state of the forecasting (switched on / off).
 type DisplayTemplate struct {
 	dataTypeId		uint
 	dataTypeName 	string
+	interfaceName	string
 	direction		uint
 	prediction		bool
 }
@@ -34,7 +36,8 @@ func (s DisplayTemplateSlice) Swap(i, j int) {
 // Comparing of two display elements in slice (sort interface). See sort.
 // Parameter i int - first display template.
 // Parameter j int - second display template.
-// Returning bool - true if "i" element has precedence over "j" element.
+// Returning bool - true if "i" element has precedence over "j" element (name, then interface, then RX, TX,
+// and internal direction, then prediction).
 func (s DisplayTemplateSlice) Less(i, j int) bool {
 	nameI := s[i].dataTypeName
 	nameJ := s[j].dataTypeName
@@ -42,10 +45,15 @@ func (s DisplayTemplateSlice) Less(i, j int) bool {
 	directionJ := s[j].direction
 	predictionI := s[i].prediction
 	namesComparison := strings.Compare(nameI, nameJ)
+	interfacesComparison := strings.Compare(s[i].interfaceName, s[j].interfaceName)
 	if namesComparison == -1 {
 		return true
 	} else if namesComparison == 1 {
 		return false
+	} else if interfacesComparison == -1 {
+		return true
+	} else if interfacesComparison == 1 {
+		return false
 	} else if directionI < directionJ {
 		return true
 	} else if directionI > directionJ {
@@ -0,0 +1,293 @@
+package machine
+
+import (
+	"model"
+	"configuration"
+	"net"
+	"sync"
+	"time"
+	"fmt"
+)
+
+// Data sources that can be selected in the network configuration.
+const DATA_SOURCE_CAPTURE = "capture"
+const DATA_SOURCE_NETFLOW = "netflow"
+const DATA_SOURCE_SFLOW = "sflow"
+// Default UDP ports of NetFlow / IPFIX and sFlow collectors.
+const PORT_NETFLOW = uint(2055)
+const PORT_SFLOW = uint(6343)
+// Maximum size of received UDP datagram.
+const DATAGRAM_MAX_SIZE = 65535
+// Maximum number of parts into which one flow record is spread (one part per second for shorter flows).
+const FLOW_SPREAD_PARTS_MAX = uint64(300)
+
+// Attribute routerMacAddress *([]byte) - MAC address of monitored router's interface (nil if it is not configured).
+// Attribute directionClassifier *DirectionClassifier - classification of flow direction. See DirectionClassifier.
+// Attribute networkConfiguration *model.NetworkConfiguration - network configuration settings.
+// See model.NetworkConfiguration.
+// Attribute statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
+// Attribute connection *net.UDPConn - listening UDP socket.
+// Attribute netflowDecoder *NetflowDecoder - decoding of NetFlow / IPFIX packets. See NetflowDecoder.
+// Attribute sflowDecoder *SflowDecoder - decoding of sFlow datagrams. See SflowDecoder.
+// Attribute dataAggregator *DataAggregator - aggregation of flow records between two flushes. See DataAggregator.
+// Attribute aggregatorMutex *sync.Mutex - synchronisation of receiving and flushing goroutines.
+// Attribute captureSource model.CaptureSource - link whose router's MAC address and interface identifier are used
+// for collected flows. See model.CaptureSource.
+type FlowCollector struct {
+	routerMacAddress		*([]byte)
+	directionClassifier		*DirectionClassifier
+	networkConfiguration	*model.NetworkConfiguration
+	statisticalData			model.Storage
+	connection				*net.UDPConn
+	netflowDecoder			*NetflowDecoder
+	sflowDecoder			*SflowDecoder
+	dataAggregator			*DataAggregator
+	aggregatorMutex			*sync.Mutex
+	captureSource			model.CaptureSource
+}
+
+// Creating instance of the FlowCollector.
+// Parameter conf *model.NetworkConfiguration - network configuration settings. See model.NetworkConfiguration.
+// Parameter captureSource model.CaptureSource - link whose router's MAC address and interface identifier are used
+// for collected flows. See model.CaptureSource.
+// Parameter statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
+// Returning *FlowCollector - FlowCollector object.
+func NewFlowCollector(conf *model.NetworkConfiguration, captureSource model.CaptureSource,
+	statisticalData model.Storage) *FlowCollector {
+	flowCollector := FlowCollector {
+		networkConfiguration: conf,
+		captureSource: captureSource,
+		statisticalData: statisticalData,
+		netflowDecoder: NewNetflowDecoder(),
+		dataAggregator: NewDataAggregator(),
+		aggregatorMutex: &sync.Mutex{},
+	}
+	return &flowCollector
+}
+
+// Starting of the flow collector (NetFlow / IPFIX or sFlow according to the data source) - datagrams are received
+// and periodically flushed to the database in separated goroutines.
+func (FlowCollector *FlowCollector) StartCollecting() {
+	FlowCollector.readRouterMacAddress()
+	FlowCollector.buildDirectionClassifier()
+	FlowCollector.sflowDecoder = NewSflowDecoder(FlowCollector.directionClassifier)
+	FlowCollector.openListener()
+	go FlowCollector.receiveDatagrams()
+	go FlowCollector.flushPeriodically()
+}
+
+// Converting of string to MAC address (byte array format). The address is optional for flow collectors, because
+// direction can be also exported by the flow exporter.
+func (FlowCollector *FlowCollector) readRouterMacAddress() {
+	macAddress := FlowCollector.captureSource.RouterMacAddress
+	if macAddress == "" {
+		return
+	}
+	hw, err := net.ParseMAC(macAddress)
+	if err != nil {
+		configuration.Error.Panicf("Error reading of router's MAC address %s: %v", macAddress, err)
+	}
+	array := []byte(hw)
+	FlowCollector.routerMacAddress = &array
+}
+
+// Building of direction classifier from router's MAC address and local networks.
+func (FlowCollector *FlowCollector) buildDirectionClassifier() {
+	directionClassifier, err := NewDirectionClassifier(FlowCollector.routerMacAddress,
+		FlowCollector.networkConfiguration.LocalNetworks)
+	if err != nil {
+		configuration.Error.Panicf("Error reading of local networks: %v", err)
+	}
+	FlowCollector.directionClassifier = directionClassifier
+}
+
+// Opening of the UDP socket on the configured collector port.
+func (FlowCollector *FlowCollector) openListener() {
+	port := FlowCollector.networkConfiguration.CollectorPort
+	if port == 0 && FlowCollector.networkConfiguration.DataSource == DATA_SOURCE_SFLOW {
+		port = PORT_SFLOW
+	} else if port == 0 {
+		port = PORT_NETFLOW
+	}
+	address, err01 := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", port))
+	if err01 != nil {
+		configuration.Error.Panicf("Error resolving of the collector port %d: %v", port, err01)
+	}
+	connection, err02 := net.ListenUDP("udp", address)
+	if err02 != nil {
+		configuration.Error.Panicf("Error opening of the collector port %d: %v", port, err02)
+	}
+	FlowCollector.connection = connection
+	configuration.Info.Printf("Flow collector is listening on UDP port %d", port)
+}
+
+// Receiving and decoding of datagrams from flow exporters (infinite loop).
+func (FlowCollector *FlowCollector) receiveDatagrams() {
+	buffer := make([]byte, DATAGRAM_MAX_SIZE)
+	for {
+		length, exporter, err := FlowCollector.connection.ReadFromUDP(buffer)
+		if err != nil {
+			configuration.Warning.Printf("Error receiving of the flow datagram: %v", err)
+			continue
+		}
+		if FlowCollector.networkConfiguration.DataSource == DATA_SOURCE_SFLOW {
+			FlowCollector.processSflowDatagram(exporter.IP.String(), buffer[:length])
+		} else {
+			FlowCollector.processNetflowDatagram(exporter.IP.String(), buffer[:length])
+		}
+	}
+}
+
+// Decoding of one sFlow datagram and adding of the sampled frames to the aggregator.
+// Parameter agent string - address of the sFlow agent.
+// Parameter datagram []byte - received UDP payload.
+func (FlowCollector *FlowCollector) processSflowDatagram(agent string, datagram []byte) {
+	frames, err := FlowCollector.sflowDecoder.DecodeDatagram(datagram)
+	if err != nil {
+		configuration.Warning.Printf("Error decoding of the sFlow datagram from %s: %v", agent, err)
+	}
+	if len(frames) == 0 {
+		return
+	}
+	actualTime := time.Now()
+	FlowCollector.aggregatorMutex.Lock()
+	defer FlowCollector.aggregatorMutex.Unlock()
+	for _, frame := range frames {
+		frame.RawDataType.InterfaceName = FlowCollector.captureSource.Name
+		FlowCollector.dataAggregator.AddEntry(frame.RawDataType, frame.Bytes, frame.Packets, actualTime)
+	}
+}
+
+// Decoding of one NetFlow / IPFIX datagram and adding of the decoded records to the aggregator.
+// Parameter exporter string - address of the exporting device.
+// Parameter datagram []byte - received UDP payload.
+func (FlowCollector *FlowCollector) processNetflowDatagram(exporter string, datagram []byte) {
+	records, err := FlowCollector.netflowDecoder.DecodeDatagram(exporter, datagram)
+	if err != nil {
+		configuration.Warning.Printf("Error decoding of the flow datagram from %s: %v", exporter, err)
+	}
+	if len(records) == 0 {
+		return
+	}
+	actualTime := time.Now()
+	FlowCollector.aggregatorMutex.Lock()
+	defer FlowCollector.aggregatorMutex.Unlock()
+	for _, record := range records {
+		rawDataType, valid := FlowCollector.buildRawDataType(record)
+		if valid {
+			spreadFlowRecord(FlowCollector.dataAggregator, rawDataType, record, actualTime)
+		}
+	}
+}
+
+// Adding of flow record to the aggregator - bytes and packets are spread evenly over seconds between the first and
+// the last packet of flow (in at most FLOW_SPREAD_PARTS_MAX parts), so long flows don't appear as one spike at
+// export time. Records without exported times are counted at receive time; times of exporters whose clock runs
+// ahead are shifted back to receive time.
+// Parameter dataAggregator *DataAggregator - aggregator of flow records. See DataAggregator.
+// Parameter rawDataType model.RawDataType - raw data type of flow record. See model.RawDataType.
+// Parameter record *FlowRecord - decoded flow record. See FlowRecord.
+// Parameter receiveTime time.Time - receive time of the flow datagram. See time.Time.
+func spreadFlowRecord(dataAggregator *DataAggregator, rawDataType model.RawDataType, record *FlowRecord,
+	receiveTime time.Time) {
+	end := record.End
+	start := record.Start
+	if end.IsZero() {
+		end = receiveTime
+	} else if end.After(receiveTime) {
+		start = start.Add(receiveTime.Sub(end))
+		end = receiveTime
+	}
+	if record.Start.IsZero() || start.After(end) {
+		start = end
+	}
+	seconds := uint64(end.Truncate(time.Second).Sub(start.Truncate(time.Second)) / time.Second) + 1
+	parts := seconds
+	if parts > FLOW_SPREAD_PARTS_MAX {
+		parts = FLOW_SPREAD_PARTS_MAX
+	}
+	for i := uint64(0); i < parts; i++ {
+		bytes := record.Bytes / parts
+		if i < record.Bytes % parts {
+			bytes++
+		}
+		packets := record.Packets / parts
+		if i < record.Packets % parts {
+			packets++
+		}
+		if bytes == 0 && packets == 0 {
+			continue
+		}
+		partStart := start.Add(time.Duration(i * seconds / parts) * time.Second)
+		dataAggregator.AddEntry(rawDataType, bytes, packets, partStart)
+	}
+}
+
+// Converting of flow record to raw data type (the same way as captured frames are decoded).
+// Parameter record *FlowRecord - decoded flow record. See FlowRecord.
+// Returning model.RawDataType - raw data type of flow record. See model.RawDataType.
+// Returning bool - the record describes IPv4 or IPv6 flow.
+func (FlowCollector *FlowCollector) buildRawDataType(record *FlowRecord) (model.RawDataType, bool) {
+	rawDataType := model.RawDataType{
+		TransportProtocol: uint(record.Protocol),
+		Direction: FlowCollector.resolveDirection(record),
+		SrcAddress: model.NewIPAddress(record.SrcAddress),
+		DstAddress: model.NewIPAddress(record.DstAddress),
+		InterfaceName: FlowCollector.captureSource.Name,
+	}
+	switch record.IpVersion {
+	case 4:
+		rawDataType.NetworkProtocol = uint(ETHER_TYPE_IPV4)
+	case 6:
+		rawDataType.NetworkProtocol = uint(ETHER_TYPE_IPV6)
+	default:
+		return rawDataType, false
+	}
+	if record.Protocol == PROTOCOL_TCP || record.Protocol == PROTOCOL_UDP {
+		rawDataType.SrcPort = uint(record.SrcPort)
+		rawDataType.DstPort = uint(record.DstPort)
+	} else if (record.Protocol == PROTOCOL_ICMP && record.IpVersion == 4) ||
+		(record.Protocol == PROTOCOL_ICMPV6 && record.IpVersion == 6) {
+		// exporters encode ICMP type and code into destination port (type * 256 + code)
+		rawDataType.IcmpPresent = true
+		rawDataType.IcmpType = uint(record.DstPort >> 8)
+		rawDataType.IcmpCode = uint(record.DstPort & 0xff)
+	}
+	return rawDataType, true
+}
+
+// Resolving of flow direction - local networks and router's MAC address are used if they resolve direction,
+// otherwise exported flow direction is used (egress flow - TX).
+// Parameter record *FlowRecord - decoded flow record. See FlowRecord.
+// Returning uint - RX, TX, or internal direction. See model.DIRECTION_RX.
+func (FlowCollector *FlowCollector) resolveDirection(record *FlowRecord) uint {
+	direction, resolved := FlowCollector.directionClassifier.ClassifyByAddresses(record.SrcAddress,
+		record.DstAddress)
+	if resolved {
+		return direction
+	}
+	direction, resolved = FlowCollector.directionClassifier.ClassifyByMacAddress(record.SrcMac)
+	if resolved {
+		return direction
+	}
+	if record.FlowDirection == 1 {
+		return model.DIRECTION_TX
+	}
+	return model.DIRECTION_RX
+}
+
+// Periodical flushing of aggregated records to the database (infinite loop).
+func (FlowCollector *FlowCollector) flushPeriodically() {
+	ticker := time.NewTicker(time.Duration(FlowCollector.networkConfiguration.DataBuffer) * time.Millisecond)
+	for range ticker.C {
+		FlowCollector.aggregatorMutex.Lock()
+		dataAggregator := FlowCollector.dataAggregator
+		FlowCollector.dataAggregator = NewDataAggregator()
+		FlowCollector.aggregatorMutex.Unlock()
+		if !dataAggregator.IsEmpty() {
+			FlowCollector.statisticalData.WriteNewDataEntries(dataAggregator.BuildSlice())
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package machine
+
+import (
+	"model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default idle timeout of flows [ms] - the flow is closed if no frame has been seen for this time.
+const FLOW_IDLE_TIMEOUT_DEFAULT = uint(15000)
+// Default active timeout of flows [ms] - long-lived flows are closed and started again after this time.
+const FLOW_ACTIVE_TIMEOUT_DEFAULT = uint(60000)
+// Default retention of closed flows [ms] (the longest window of top talkers).
+const FLOW_RETENTION_DEFAULT = uint(600000)
+// Default maximum number of active flows (and of retained closed flows).
+const FLOW_TABLE_SIZE_DEFAULT = uint(100000)
+// Default number of listed top flows and top hosts.
+const TOP_TALKERS_COUNT_DEFAULT = uint(10)
+// Default window of top talkers [s].
+const TOP_TALKERS_WINDOW_DEFAULT = uint(60)
+
+// Identification of unidirectional flow (5-tuple on one link).
+// Attribute interfaceName string - identifier of the observed link.
+// Attribute networkProtocol uint - EtherType (IPv4 or IPv6).
+// Attribute transportProtocol uint - protocol field of IPv4 / IPv6 packet.
+// Attribute srcAddress model.IPAddress - source address. See model.IPAddress.
+// Attribute dstAddress model.IPAddress - destination address. See model.IPAddress.
+// Attribute srcPort uint - TCP / UDP source port (0 for other protocols).
+// Attribute dstPort uint - TCP / UDP destination port (0 for other protocols).
+type flowKey struct {
+	interfaceName		string
+	networkProtocol		uint
+	transportProtocol	uint
+	srcAddress			model.IPAddress
+	dstAddress			model.IPAddress
+	srcPort				uint
+	dstPort				uint
+}
+
+// Counters of one flow.
+// Attribute key flowKey - identification of the flow. See flowKey.
+// Attribute direction uint - RX, TX, or internal direction of the flow.
+// Attribute serverName string - server name of the flow (empty - the flow hasn't been identified).
+// Attribute bytes uint64 - number of bytes.
+// Attribute packets uint64 - number of frames.
+// Attribute firstSeen time.Time - arrival of the first frame. See time.Time.
+// Attribute lastSeen time.Time - arrival of the last frame. See time.Time.
+type flowEntry struct {
+	key			flowKey
+	direction	uint
+	serverName	string
+	bytes		uint64
+	packets		uint64
+	firstSeen	time.Time
+	lastSeen	time.Time
+}
+
+// Flow that is listed among top talkers (exported over REST); counters relate to the selected window.
+// Attribute InterfaceName string - identifier of the observed link. See model.CaptureSource.
+// Attribute NetworkProtocol uint - EtherType (IPv4 or IPv6).
+// Attribute TransportProtocol uint - protocol field of IPv4 / IPv6 packet.
+// Attribute SrcAddress string - source address.
+// Attribute DstAddress string - destination address.
+// Attribute SrcPort uint - TCP / UDP source port.
+// Attribute DstPort uint - TCP / UDP destination port.
+// Attribute Direction string - RX, TX, or INT.
+// Attribute ServerName string - server name of the flow (TLS SNI, HTTP Host, or DNS query name).
+// Attribute Bytes uint64 - number of bytes within the window.
+// Attribute Packets uint64 - number of frames within the window.
+// Attribute FirstSeen time.Time - arrival of the first frame of the flow. See time.Time.
+// Attribute LastSeen time.Time - arrival of the last frame of the flow. See time.Time.
+type TrafficFlow struct {
+	InterfaceName		string
+	NetworkProtocol		uint
+	TransportProtocol	uint
+	SrcAddress			string
+	DstAddress			string
+	SrcPort				uint
+	DstPort				uint
+	Direction			string
+	ServerName			string
+	Bytes				uint64
+	Packets				uint64
+	FirstSeen			time.Time
+	LastSeen			time.Time
+}
+
+// Host that is listed among top talkers (exported over REST); counters relate to the selected window.
+// Attribute Address string - IPv4 / IPv6 address.
+// Attribute Bytes uint64 - number of sent and received bytes.
+// Attribute Packets uint64 - number of sent and received frames.
+// Attribute SentBytes uint64 - number of bytes sent by the host.
+// Attribute ReceivedBytes uint64 - number of bytes received by the host.
+type HostTraffic struct {
+	Address			string
+	Bytes			uint64
+	Packets			uint64
+	SentBytes		uint64
+	ReceivedBytes	uint64
+}
+
+// Top flows and top hosts by bytes (exported over REST).
+// Attribute Window uint - window of the listing [s].
+// Attribute Flows []TrafficFlow - flows ordered by bytes. See TrafficFlow.
+// Attribute Hosts []HostTraffic - hosts ordered by bytes. See HostTraffic.
+type TopTalkers struct {
+	Window		uint
+	Flows		[]TrafficFlow
+	Hosts		[]HostTraffic
+}
+
+// Table of 5-tuple flows that is maintained by frames parsers. Active flows are closed on idle or active timeout and
+// closed flows are retained for the longest window of top talkers. Flows are updated by aggregated frames buckets,
+// so first and last seen times have resolution of the buckets (DataBuffer).
+// Attribute mutex *sync.Mutex - synchronisation of parse workers and readers. See sync.Mutex.
+// Attribute active map[flowKey](*flowEntry) - active flows. See flowEntry.
+// Attribute closed [](*flowEntry) - closed flows ordered by closing. See flowEntry.
+// Attribute idleTimeout time.Duration - idle timeout of flows. See time.Duration.
+// Attribute activeTimeout time.Duration - active timeout of flows. See time.Duration.
+// Attribute retention time.Duration - retention of closed flows. See time.Duration.
+// Attribute size int - maximum number of active flows and of closed flows.
+// Attribute lastSeen time.Time - arrival of the latest frame (clock of replayed files). See time.Time.
+type FlowTable struct {
+	mutex			*sync.Mutex
+	active			map[flowKey](*flowEntry)
+	closed			[](*flowEntry)
+	idleTimeout		time.Duration
+	activeTimeout	time.Duration
+	retention		time.Duration
+	size			int
+	lastSeen		time.Time
+}
+
+// Creating instance of the FlowTable.
+// Parameter conf *model.NetworkConfiguration - network configuration settings (timeouts, retention, and size).
+// See model.NetworkConfiguration.
+// Returning *FlowTable - FlowTable object without flows.
+func NewFlowTable(conf *model.NetworkConfiguration) *FlowTable {
+	flowTable := FlowTable{
+		mutex: &sync.Mutex{},
+		active: make(map[flowKey](*flowEntry)),
+		idleTimeout: millisecondsOrDefault(conf.FlowIdleTimeout, FLOW_IDLE_TIMEOUT_DEFAULT),
+		activeTimeout: millisecondsOrDefault(conf.FlowActiveTimeout, FLOW_ACTIVE_TIMEOUT_DEFAULT),
+		retention: millisecondsOrDefault(conf.FlowRetention, FLOW_RETENTION_DEFAULT),
+		size: int(conf.FlowTableSize),
+	}
+	if flowTable.size == 0 {
+		flowTable.size = int(FLOW_TABLE_SIZE_DEFAULT)
+	}
+	return &flowTable
+}
+
+// Converting of configured interval to duration.
+// Parameter milliseconds uint - configured interval [ms] (0 - default interval).
+// Parameter defaultMilliseconds uint - default interval [ms].
+// Returning time.Duration - interval. See time.Duration.
+func millisecondsOrDefault(milliseconds uint, defaultMilliseconds uint) time.Duration {
+	if milliseconds == 0 {
+		milliseconds = defaultMilliseconds
+	}
+	return time.Duration(milliseconds) * time.Millisecond
+}
+
+// Adding of aggregated entries of one frames bucket to flows; entries without IP addresses are skipped and new flows
+// are not tracked if the table is full.
+// Parameter entries *[](*model.RawData) - aggregated entries. See model.RawData.
+func (FlowTable *FlowTable) AddEntries(entries *[](*model.RawData)) {
+	FlowTable.mutex.Lock()
+	defer FlowTable.mutex.Unlock()
+	for _, entry := range *entries {
+		if !entry.SrcAddress.IsKnown() || !entry.DstAddress.IsKnown() {
+			continue
+		}
+		if entry.Time.After(FlowTable.lastSeen) {
+			FlowTable.lastSeen = entry.Time
+		}
+		key := flowKey{
+			interfaceName: entry.InterfaceName,
+			networkProtocol: entry.NetworkProtocol,
+			transportProtocol: entry.TransportProtocol,
+			srcAddress: entry.SrcAddress,
+			dstAddress: entry.DstAddress,
+			srcPort: entry.SrcPort,
+			dstPort: entry.DstPort,
+		}
+		flow, present := FlowTable.active[key]
+		if !present {
+			if len(FlowTable.active) >= FlowTable.size {
+				continue
+			}
+			flow = &flowEntry{key: key, firstSeen: entry.Time, lastSeen: entry.Time}
+			FlowTable.active[key] = flow
+		}
+		flow.direction = entry.Direction
+		if entry.ServerName != "" {
+			flow.serverName = entry.ServerName
+		}
+		flow.bytes += entry.Bytes
+		flow.packets += entry.Packets
+		if entry.Time.Before(flow.firstSeen) {
+			flow.firstSeen = entry.Time
+		}
+		if entry.Time.After(flow.lastSeen) {
+			flow.lastSeen = entry.Time
+		}
+	}
+	FlowTable.expire(FlowTable.now())
+}
+
+// Actual time of the table - wall clock or arrival of the latest frame if it is later (replayed files).
+// Returning time.Time - actual time. See time.Time.
+func (FlowTable *FlowTable) now() time.Time {
+	now := time.Now()
+	if FlowTable.lastSeen.After(now) {
+		return FlowTable.lastSeen
+	}
+	return now
+}
+
+// Closing of flows on idle or active timeout and removing of closed flows after retention (mutex must be locked).
+// Parameter now time.Time - actual time. See time.Time.
+func (FlowTable *FlowTable) expire(now time.Time) {
+	for key, flow := range FlowTable.active {
+		if now.Sub(flow.lastSeen) > FlowTable.idleTimeout || now.Sub(flow.firstSeen) > FlowTable.activeTimeout {
+			delete(FlowTable.active, key)
+			FlowTable.closed = append(FlowTable.closed, flow)
+		}
+	}
+	removed := 0
+	for removed < len(FlowTable.closed) && (len(FlowTable.closed) - removed > FlowTable.size ||
+		now.Sub(FlowTable.closed[removed].lastSeen) > FlowTable.retention) {
+		removed++
+	}
+	if removed != 0 {
+		FlowTable.closed = append([](*flowEntry){}, FlowTable.closed[removed:]...)
+	}
+}
+
+// Listing of top flows and top hosts by bytes within the window. Bytes and frames of flows that only partly overlap
+// the window are counted proportionally to the overlap.
+// Parameter count uint - maximum number of listed flows and hosts.
+// Parameter window uint - window [s] (it is limited by retention of closed flows).
+// Returning TopTalkers - top flows and hosts. See TopTalkers.
+func (FlowTable *FlowTable) ListTopTalkers(count uint, window uint) TopTalkers {
+	FlowTable.mutex.Lock()
+	defer FlowTable.mutex.Unlock()
+	now := FlowTable.now()
+	FlowTable.expire(now)
+	windowDuration := time.Duration(window) * time.Second
+	if windowDuration > FlowTable.retention {
+		windowDuration = FlowTable.retention
+	}
+	windowStart := now.Add(-windowDuration)
+	flows := make(map[flowKey](*TrafficFlow))
+	addFlow := func(flow *flowEntry) {
+		if flow.lastSeen.Before(windowStart) {
+			return
+		}
+		bytes, packets := flow.bytes, flow.packets
+		duration := flow.lastSeen.Sub(flow.firstSeen)
+		if flow.firstSeen.Before(windowStart) && duration > 0 {
+			overlap := float64(flow.lastSeen.Sub(windowStart)) / float64(duration)
+			bytes = uint64(float64(bytes) * overlap)
+			packets = uint64(float64(packets) * overlap)
+		}
+		trafficFlow, present := flows[flow.key]
+		if !present {
+			trafficFlow = &TrafficFlow{
+				InterfaceName: flow.key.interfaceName,
+				NetworkProtocol: flow.key.networkProtocol,
+				TransportProtocol: flow.key.transportProtocol,
+				SrcAddress: flow.key.srcAddress.ToIP().String(),
+				DstAddress: flow.key.dstAddress.ToIP().String(),
+				SrcPort: flow.key.srcPort,
+				DstPort: flow.key.dstPort,
+				FirstSeen: flow.firstSeen,
+				LastSeen: flow.lastSeen,
+			}
+			flows[flow.key] = trafficFlow
+		}
+		trafficFlow.Direction = directionToString(flow.direction)
+		if flow.serverName != "" {
+			trafficFlow.ServerName = flow.serverName
+		}
+		trafficFlow.Bytes += bytes
+		trafficFlow.Packets += packets
+		if flow.firstSeen.Before(trafficFlow.FirstSeen) {
+			trafficFlow.FirstSeen = flow.firstSeen
+		}
+		if flow.lastSeen.After(trafficFlow.LastSeen) {
+			trafficFlow.LastSeen = flow.lastSeen
+		}
+	}
+	for _, flow := range FlowTable.closed {
+		addFlow(flow)
+	}
+	for _, flow := range FlowTable.active {
+		addFlow(flow)
+	}
+	topTalkers := TopTalkers{Window: uint(windowDuration / time.Second), Flows: []TrafficFlow{},
+		Hosts: []HostTraffic{}}
+	hosts := make(map[string](*HostTraffic))
+	for _, trafficFlow := range flows {
+		topTalkers.Flows = append(topTalkers.Flows, *trafficFlow)
+		for _, address := range []string{trafficFlow.SrcAddress, trafficFlow.DstAddress} {
+			host, present := hosts[address]
+			if !present {
+				host = &HostTraffic{Address: address}
+				hosts[address] = host
+			}
+			host.Bytes += trafficFlow.Bytes
+			host.Packets += trafficFlow.Packets
+			if address == trafficFlow.SrcAddress {
+				host.SentBytes += trafficFlow.Bytes
+			} else {
+				host.ReceivedBytes += trafficFlow.Bytes
+			}
+		}
+	}
+	for _, host := range hosts {
+		topTalkers.Hosts = append(topTalkers.Hosts, *host)
+	}
+	sort.Slice(topTalkers.Flows, func(i, j int) bool {
+		return topTalkers.Flows[i].Bytes > topTalkers.Flows[j].Bytes
+	})
+	sort.Slice(topTalkers.Hosts, func(i, j int) bool {
+		if topTalkers.Hosts[i].Bytes == topTalkers.Hosts[j].Bytes {
+			return topTalkers.Hosts[i].Address < topTalkers.Hosts[j].Address
+		}
+		return topTalkers.Hosts[i].Bytes > topTalkers.Hosts[j].Bytes
+	})
+	if uint(len(topTalkers.Flows)) > count {
+		topTalkers.Flows = topTalkers.Flows[:count]
+	}
+	if uint(len(topTalkers.Hosts)) > count {
+		topTalkers.Hosts = topTalkers.Hosts[:count]
+	}
+	return topTalkers
+}
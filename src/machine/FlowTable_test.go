@@ -0,0 +1,129 @@
+package machine
+
+import (
+	"model"
+	"net"
+	"testing"
+	"time"
+)
+
+// Building of aggregated entry of one flow.
+// Parameter src string - source IP address.
+// Parameter dst string - destination IP address.
+// Parameter dstPort uint - TCP destination port.
+// Parameter bytes uint64 - number of bytes.
+// Parameter timestamp time.Time - time of the entry. See time.Time.
+// Returning *model.RawData - aggregated entry. See model.RawData.
+func buildFlowEntry(src string, dst string, dstPort uint, bytes uint64, timestamp time.Time) *model.RawData {
+	return &model.RawData{Bytes: bytes, Packets: 1, Time: timestamp, RawDataType: &model.RawDataType{
+		NetworkProtocol: uint(ETHER_TYPE_IPV4),
+		TransportProtocol: uint(PROTOCOL_TCP),
+		SrcPort: 50000,
+		DstPort: dstPort,
+		Direction: model.DIRECTION_TX,
+		SrcAddress: model.NewIPAddress(net.ParseIP(src)),
+		DstAddress: model.NewIPAddress(net.ParseIP(dst)),
+		InterfaceName: "eth1",
+	}}
+}
+
+// Unit test - flows are closed on idle and active timeouts, non-IP entries are skipped, and the table size is bounded.
+// Parameter t *testing.T - testing engine.
+func TestFlowTableTimeouts(t *testing.T) {
+	// entries are in the future, so the table uses their clock (as with replayed files)
+	start := time.Now().Add(24 * time.Hour)
+	flowTable := NewFlowTable(&model.NetworkConfiguration{FlowTableSize: 2})
+	add := func(entries ...*model.RawData) {
+		flowTable.AddEntries(&entries)
+	}
+
+	add(buildFlowEntry("192.168.1.10", "10.0.0.1", 443, 100, start),
+		&model.RawData{Bytes: 60, Packets: 1, Time: start, RawDataType: &model.RawDataType{}})
+	add(buildFlowEntry("192.168.1.10", "10.0.0.2", 443, 100, start.Add(10 * time.Second)))
+	add(buildFlowEntry("192.168.1.10", "10.0.0.3", 443, 100, start.Add(10 * time.Second)))
+	if len(flowTable.active) != 2 || len(flowTable.closed) != 0 {
+		t.Errorf("Expected 2 active flows (size limit, non-IP entry); given active: %d, closed: %d",
+			len(flowTable.active), len(flowTable.closed))
+	}
+
+	t.Log("Idle timeout ...")
+	add(buildFlowEntry("192.168.1.10", "10.0.0.2", 443, 100, start.Add(20 * time.Second)))
+	if len(flowTable.active) != 1 || len(flowTable.closed) != 1 || flowTable.closed[0].key.dstPort != 443 ||
+		!flowTable.closed[0].key.dstAddress.ToIP().Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("Expected idle flow to be closed; given active: %d, closed: %+v", len(flowTable.active),
+			flowTable.closed)
+	}
+
+	t.Log("Active timeout ...")
+	for seconds := 30; seconds <= 80; seconds += 10 {
+		add(buildFlowEntry("192.168.1.10", "10.0.0.2", 443, 100, start.Add(time.Duration(seconds) * time.Second)))
+	}
+	if len(flowTable.active) != 0 || len(flowTable.closed) != 2 || flowTable.closed[1].packets != 8 {
+		t.Fatalf("Expected long-lived flow to be closed after 60s (the oldest closed flow is removed by size " +
+			"limit); given active: %d, closed: %d",
+			len(flowTable.active), len(flowTable.closed))
+	}
+	add(buildFlowEntry("192.168.1.10", "10.0.0.2", 443, 100, start.Add(85 * time.Second)))
+	if flow, present := flowTable.active[flowTable.closed[1].key]; !present || flow.packets != 1 {
+		t.Errorf("Expected long-lived flow to be started again; given active: %d", len(flowTable.active))
+	}
+}
+
+// Unit test - top flows and top hosts by bytes within selected window.
+// Parameter t *testing.T - testing engine.
+func TestFlowTableTopTalkers(t *testing.T) {
+	start := time.Now().Add(24 * time.Hour)
+	flowTable := NewFlowTable(&model.NetworkConfiguration{FlowIdleTimeout: 600000, FlowActiveTimeout: 600000})
+	entries := [](*model.RawData){
+		buildFlowEntry("192.168.1.10", "10.0.0.1", 443, 1000, start),
+		buildFlowEntry("192.168.1.10", "10.0.0.1", 443, 1000, start.Add(100 * time.Second)),
+		buildFlowEntry("192.168.1.20", "10.0.0.1", 80, 1500, start.Add(100 * time.Second)),
+		buildFlowEntry("192.168.1.30", "10.0.0.2", 53, 100, start.Add(100 * time.Second)),
+	}
+	entries[3].ServerName = "example.com"
+	flowTable.AddEntries(&entries)
+
+	tests := []struct {
+		name			string
+		count			uint
+		window			uint
+		expectedFlows	[]uint64
+		expectedHosts	[]string
+	}{
+		{"all flows", 10, 600, []uint64{2000, 1500, 100}, []string{"10.0.0.1", "192.168.1.10", "192.168.1.20",
+			"10.0.0.2", "192.168.1.30"}},
+		{"top flow", 1, 600, []uint64{2000}, []string{"10.0.0.1"}},
+		{"partial overlap", 10, 50, []uint64{1500, 1000, 100}, []string{"10.0.0.1", "192.168.1.20",
+			"192.168.1.10", "10.0.0.2", "192.168.1.30"}},
+	}
+	for _, test := range tests {
+		topTalkers := flowTable.ListTopTalkers(test.count, test.window)
+		if len(topTalkers.Flows) != len(test.expectedFlows) || len(topTalkers.Hosts) != len(test.expectedHosts) {
+			t.Errorf("%s: expected %d flows and %d hosts; given: %+v", test.name, len(test.expectedFlows),
+				len(test.expectedHosts), topTalkers)
+			continue
+		}
+		for i, bytes := range test.expectedFlows {
+			if topTalkers.Flows[i].Bytes != bytes {
+				t.Errorf("%s: expected %d bytes of flow %d; given flow: %+v", test.name, bytes, i,
+					topTalkers.Flows[i])
+			}
+		}
+		for i, address := range test.expectedHosts {
+			if topTalkers.Hosts[i].Address != address {
+				t.Errorf("%s: expected host %s at %d; given host: %+v", test.name, address, i, topTalkers.Hosts[i])
+			}
+		}
+	}
+
+	topTalkers := flowTable.ListTopTalkers(10, 600)
+	if len(topTalkers.Flows) != 3 || len(topTalkers.Hosts) == 0 {
+		t.Fatalf("Expected 3 flows; given: %+v", topTalkers)
+	}
+	if flow := topTalkers.Flows[2]; flow.ServerName != "example.com" || flow.Direction != "TX" || flow.DstPort != 53 {
+		t.Errorf("Expected DNS flow with server name and direction; given flow: %+v", flow)
+	}
+	if host := topTalkers.Hosts[0]; host.ReceivedBytes != 3500 || host.SentBytes != 0 || host.Packets != 3 {
+		t.Errorf("Expected top host to receive all its bytes; given host: %+v", host)
+	}
+}
@@ -0,0 +1,226 @@
+package machine
+
+import (
+	"fmt"
+	"encoding/binary"
+	"configuration"
+)
+
+// Frames are captured as TZSP datagrams sent by remote sniffer (MikroTik).
+const ENCAPSULATION_TZSP = "tzsp"
+// Frames are captured without encapsulation directly from the switch mirror (SPAN) port.
+const ENCAPSULATION_ETHERNET = "ethernet"
+// Frames are captured with Linux cooked capture header (capturing on 'any' pseudo-device).
+const ENCAPSULATION_LINUX_SLL = "linux-sll"
+// Capture filter of TZSP datagrams (default port).
+const FILTER_TZSP = "udp port 37008"
+// Default listening UDP port of TZSP datagrams.
+const PORT_TZSP = uint16(37008)
+// Length of Linux cooked capture header.
+const SLL_HEADER_LENGTH = 16
+// Length of Ethernet 2 header (without VLAN tags).
+const ETHERNET_HEADER_LENGTH = 14
+
+// Unwrapping of captured frames into the original Ethernet 2 frames. The implementation is selected by
+// encapsulation mode in network configuration.
+type FrameDecapsulator interface {
+	// Capture (BPF) filter that passes only frames with the selected encapsulation; empty string - no filter.
+	CaptureFilter() string
+	// Unwrapping of captured frame; nil is returned if the frame doesn't carry the selected encapsulation.
+	Unwrap(frame *[]byte) *[]byte
+}
+
+// Decapsulator whose encapsulation carries metadata of the original frame (TZSP tags).
+type TaggedDecapsulator interface {
+	// Unwrapping of captured frame with decoding of encapsulation header; nil frame and nil header are returned if
+	// the frame doesn't carry the selected encapsulation, error if the encapsulation header is malformed.
+	UnwrapWithHeader(frame *[]byte) (*[]byte, *TzspHeader, error)
+}
+
+// Creating of frame decapsulator by encapsulation mode; TZSP is used if the mode is not specified.
+// Parameter encapsulation string - encapsulation mode (tzsp, ethernet, linux-sll, erspan, vxlan).
+// Parameter tzspPorts []uint - listening UDP ports of TZSP datagrams (empty - PORT_TZSP).
+// Returning FrameDecapsulator - decapsulator of the selected mode. See FrameDecapsulator.
+// Returning error - unknown encapsulation mode or invalid TZSP port.
+func NewFrameDecapsulator(encapsulation string, tzspPorts []uint) (FrameDecapsulator, error) {
+	switch encapsulation {
+	case ENCAPSULATION_TZSP, "":
+		tzspDecapsulator, err := NewTzspDecapsulator(tzspPorts)
+		if err != nil {
+			return nil, err
+		}
+		return tzspDecapsulator, nil
+	case ENCAPSULATION_ETHERNET:
+		return &EthernetDecapsulator{}, nil
+	case ENCAPSULATION_LINUX_SLL:
+		return &LinuxSllDecapsulator{}, nil
+	case ENCAPSULATION_ERSPAN:
+		return &ErspanDecapsulator{}, nil
+	case ENCAPSULATION_VXLAN:
+		return &VxlanDecapsulator{}, nil
+	default:
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("unknown encapsulation mode: %s: supported modes are %s, %s, " +
+			"%s, %s, and %s", encapsulation, ENCAPSULATION_TZSP, ENCAPSULATION_ETHERNET, ENCAPSULATION_LINUX_SLL,
+			ENCAPSULATION_ERSPAN, ENCAPSULATION_VXLAN))
+		return nil, compositeError.Evaluate()
+	}
+}
+
+// Decapsulator of frames that are wrapped in TZSP datagrams.
+// Attribute ports []uint16 - listening UDP ports of TZSP datagrams (empty - PORT_TZSP).
+type TzspDecapsulator struct {
+	ports	[]uint16
+}
+
+// Creating instance of the TzspDecapsulator.
+// Parameter ports []uint - listening UDP ports of TZSP datagrams (empty - PORT_TZSP).
+// Returning *TzspDecapsulator - TzspDecapsulator object.
+// Returning error - port out of range or duplicate port.
+func NewTzspDecapsulator(ports []uint) (*TzspDecapsulator, error) {
+	compositeError := configuration.NewCompositeError()
+	tzspDecapsulator := TzspDecapsulator{}
+	for _, port := range ports {
+		if port == 0 || port > 65535 {
+			compositeError.AddError(1, fmt.Sprintf("TZSP port %d is out of range 1-65535", port))
+			continue
+		}
+		if isTzspPort(tzspDecapsulator.ports, uint16(port)) {
+			compositeError.AddError(1, fmt.Sprintf("TZSP port %d is listed more than once", port))
+			continue
+		}
+		tzspDecapsulator.ports = append(tzspDecapsulator.ports, uint16(port))
+	}
+	err := compositeError.Evaluate()
+	if err != nil {
+		return nil, err
+	}
+	return &tzspDecapsulator, nil
+}
+
+// Capture filter of TZSP datagrams on listening ports.
+// Returning string - BPF expression.
+func (TzspDecapsulator *TzspDecapsulator) CaptureFilter() string {
+	if len(TzspDecapsulator.ports) == 0 {
+		return FILTER_TZSP
+	}
+	filter := ""
+	for i, port := range TzspDecapsulator.ports {
+		if i != 0 {
+			filter += " or "
+		}
+		filter += fmt.Sprintf("udp port %d", port)
+	}
+	return filter
+}
+
+// Unwrapping of the frame from TZSP datagram.
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - original frame or nil if the frame is not a valid TZSP datagram.
+func (TzspDecapsulator *TzspDecapsulator) Unwrap(frame *[]byte) *[]byte {
+	originalFrame, _, err := TzspDecapsulator.UnwrapWithHeader(frame)
+	if err != nil {
+		return nil
+	}
+	return originalFrame
+}
+
+// Unwrapping of the frame from TZSP datagram with decoding of TZSP header.
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - original frame or nil if the frame is not a valid TZSP datagram.
+// Returning *TzspHeader - decoded TZSP header or nil if the frame is not a valid TZSP datagram. See TzspHeader.
+// Returning error - malformed TZSP datagram. See DecodeTzsp.
+func (TzspDecapsulator *TzspDecapsulator) UnwrapWithHeader(frame *[]byte) (*[]byte, *TzspHeader, error) {
+	headerIndex, found := findTzspHeader(frame, TzspDecapsulator.ports)
+	if !found || headerIndex > uint(len(*frame)) {
+		return nil, nil, nil
+	}
+	header, cutFrameX, err := DecodeTzsp((*frame)[headerIndex:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cutFrameX, header, nil
+}
+
+// Decapsulator of frames that are captured directly from the mirror port (no encapsulation).
+type EthernetDecapsulator struct {}
+
+// Capture filter of raw Ethernet frames - all frames are passed.
+// Returning string - empty BPF expression.
+func (EthernetDecapsulator *EthernetDecapsulator) CaptureFilter() string {
+	return ""
+}
+
+// Captured frame is already the original Ethernet 2 frame.
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - the same frame or nil if it is shorter than Ethernet 2 header.
+func (EthernetDecapsulator *EthernetDecapsulator) Unwrap(frame *[]byte) *[]byte {
+	if len(*frame) < ETHERNET_HEADER_LENGTH {
+		return nil
+	}
+	return frame
+}
+
+// Decapsulator of frames with Linux cooked capture (SLL) header.
+type LinuxSllDecapsulator struct {}
+
+// Capture filter of Linux cooked frames - all frames are passed.
+// Returning string - empty BPF expression.
+func (LinuxSllDecapsulator *LinuxSllDecapsulator) CaptureFilter() string {
+	return ""
+}
+
+// Rebuilding of Ethernet 2 frame from Linux cooked capture header - source MAC address is taken from link-layer
+// address of the SLL header and EtherType from its protocol field; destination address is not known (zeros).
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - rebuilt Ethernet 2 frame or nil if the frame is shorter than SLL header.
+func (LinuxSllDecapsulator *LinuxSllDecapsulator) Unwrap(frame *[]byte) *[]byte {
+	framex := *frame
+	if len(framex) < SLL_HEADER_LENGTH {
+		return nil
+	}
+	payload := framex[SLL_HEADER_LENGTH:]
+	ethernetFrame := make([]byte, ETHERNET_HEADER_LENGTH + len(payload))
+	addressLength := binary.BigEndian.Uint16(framex[4:6])
+	if addressLength == 6 {
+		copy(ethernetFrame[6:12], framex[6:12])
+	}
+	copy(ethernetFrame[12:14], framex[14:16])
+	copy(ethernetFrame[ETHERNET_HEADER_LENGTH:], payload)
+	return &ethernetFrame
+}
+
+// Checking whether the UDP port is a listening port of TZSP datagrams.
+// Parameter ports []uint16 - listening ports (empty - PORT_TZSP).
+// Parameter port uint16 - checked UDP port.
+// Returning bool - the port is a listening port.
+func isTzspPort(ports []uint16, port uint16) bool {
+	if len(ports) == 0 {
+		return port == PORT_TZSP
+	}
+	for _, listeningPort := range ports {
+		if listeningPort == port {
+			return true
+		}
+	}
+	return false
+}
+
+// Searching for TZSP header in UDP datagram (IPv4 or IPv6) sent to or from TZSP port.
+// Parameter frame *[]byte - captured frame.
+// Parameter ports []uint16 - listening ports of TZSP datagrams (empty - PORT_TZSP).
+// Returning uint - index of TZSP header.
+// Returning bool - the frame is a TZSP datagram.
+func findTzspHeader(frame *[]byte, ports []uint16) (uint, bool) {
+	framex := *frame
+	protocol, startIndex, found := locateOuterPayload(framex)
+	if !found || protocol != PROTOCOL_UDP || uint(len(framex)) < startIndex + 8 {
+		return 0, false
+	}
+	sourcePort := binary.BigEndian.Uint16(framex[startIndex : startIndex + 2])
+	destinationPort := binary.BigEndian.Uint16(framex[startIndex + 2 : startIndex + 4])
+	if !isTzspPort(ports, sourcePort) && !isTzspPort(ports, destinationPort) {
+		return 0, false
+	}
+	return startIndex + 8, true
+}
\ No newline at end of file
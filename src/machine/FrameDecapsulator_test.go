@@ -0,0 +1,50 @@
+package machine
+
+import (
+	"testing"
+)
+
+// Unit test - validation of TZSP ports, capture filter of listening ports, and unwrapping of datagrams sent to them.
+// Parameter t *testing.T - testing engine.
+func TestTzspPorts(t *testing.T) {
+	for _, ports := range [][]uint{{0}, {70000}, {37008, 37008}} {
+		if _, err := NewFrameDecapsulator(ENCAPSULATION_TZSP, ports); err == nil {
+			t.Errorf("Expected error of invalid TZSP ports: %v", ports)
+		}
+	}
+
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	buildDatagram := func(port byte) []byte {
+		return buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_UDP),
+			[]byte{0x90, port, 0x90, port, 0x00, 0x10, 0x00, 0x00}, []byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_END},
+			frame)
+	}
+	tests := []struct {
+		name			string
+		ports			[]uint
+		filter			string
+		unwrapped		[]byte
+		notUnwrapped	[]byte
+	}{
+		{"default port", nil, FILTER_TZSP, buildDatagram(0x90), buildDatagram(0x91)},
+		{"several ports", []uint{37009, 37010}, "udp port 37009 or udp port 37010", buildDatagram(0x92),
+			buildDatagram(0x90)},
+	}
+	for _, test := range tests {
+		decapsulator, err := NewFrameDecapsulator(ENCAPSULATION_TZSP, test.ports)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if filter := decapsulator.CaptureFilter(); filter != test.filter {
+			t.Errorf("%s: expected capture filter: %s; given filter: %s", test.name, test.filter, filter)
+		}
+		if unwrapped := decapsulator.Unwrap(&test.unwrapped); unwrapped == nil || len(*unwrapped) != len(frame) {
+			t.Errorf("%s: expected unwrapped frame", test.name)
+		}
+		if unwrapped := decapsulator.Unwrap(&test.notUnwrapped); unwrapped != nil {
+			t.Errorf("%s: datagram of other port shouldn't be unwrapped", test.name)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package machine
+
+import (
+	"model"
+	"encoding/binary"
+	"net"
+)
+
+// Customer VLAN tag (802.1Q).
+const ETHER_TYPE_VLAN = uint16(0x8100)
+// Service VLAN tag (802.1ad - QinQ).
+const ETHER_TYPE_QINQ = uint16(0x88a8)
+// Legacy service VLAN tag used by some vendors before 802.1ad was standardised.
+const ETHER_TYPE_QINQ_LEGACY = uint16(0x9100)
+// Maximum number of walked VLAN tags (QinQ).
+const VLAN_MAX_TAGS = 2
+// Length of VLAN tag (TCI and encapsulated EtherType).
+const VLAN_TAG_LENGTH = uint(4)
+// Mask of VLAN identifier inside TCI field.
+const VLAN_ID_MASK = uint16(0x0fff)
+// Length of fixed IPv6 header.
+const IPV6_HEADER_LENGTH = uint(40)
+// IPv6 extension headers (next header values).
+const IPV6_HOP_BY_HOP = uint8(0)
+const IPV6_ROUTING = uint8(43)
+const IPV6_FRAGMENT = uint8(44)
+const IPV6_AUTHENTICATION = uint8(51)
+const IPV6_DESTINATION_OPTIONS = uint8(60)
+const IPV6_MOBILITY = uint8(135)
+const IPV6_HOST_IDENTITY = uint8(139)
+const IPV6_SHIM6 = uint8(140)
+// Length of IPv6 fragment header.
+const IPV6_FRAGMENT_HEADER_LENGTH = uint(8)
+// Mask of fragment offset inside IPv6 fragment header.
+const IPV6_FRAGMENT_OFFSET_MASK = uint16(0xfff8)
+// Maximum number of walked IPv6 extension headers (protection against malformed chains).
+const IPV6_MAX_EXTENSION_HEADERS = 16
+
+// Attribute directionClassifier *DirectionClassifier - classification of flow direction. See DirectionClassifier.
+type FrameDecoder struct {
+	directionClassifier	*DirectionClassifier
+}
+
+// Creating instance of the FrameDecoder.
+// Parameter directionClassifier *DirectionClassifier - classification of flow direction. See DirectionClassifier.
+// Returning *FrameDecoder - FrameDecoder object.
+func NewFrameDecoder(directionClassifier *DirectionClassifier) *FrameDecoder {
+	frameDecoder := FrameDecoder{
+		directionClassifier: directionClassifier,
+	}
+	return &frameDecoder
+}
+
+// Decoding of the raw data type (protocols, addresses, ports or ICMP type and code, VLAN, and direction) from the
+// original Ethernet 2 frame.
+// Parameter originalFrameX []byte - unwrapped Ethernet 2 frame.
+// Returning model.RawDataType - decoded raw data type. See model.RawDataType.
+// Returning bool - false if the frame is shorter than Ethernet 2 header.
+func (FrameDecoder *FrameDecoder) DecodeFrame(originalFrameX []byte) (model.RawDataType, bool) {
+	rawDataType, _, decoded := FrameDecoder.DecodeFrameWithPayload(originalFrameX)
+	return rawDataType, decoded
+}
+
+// Decoding of the raw data type from the original Ethernet 2 frame together with TCP / UDP payload that is
+// inspected by application-layer classification. See ApplicationClassifier.
+// Parameter originalFrameX []byte - unwrapped Ethernet 2 frame.
+// Returning model.RawDataType - decoded raw data type. See model.RawDataType.
+// Returning []byte - TCP / UDP payload (nil for other protocols or truncated headers).
+// Returning bool - false if the frame is shorter than Ethernet 2 header.
+func (FrameDecoder *FrameDecoder) DecodeFrameWithPayload(originalFrameX []byte) (model.RawDataType, []byte, bool) {
+	rawDataType := model.RawDataType{}
+	var payload []byte
+	length := uint(len(originalFrameX))
+	if length < ETHERNET_HEADER_LENGTH {
+		return rawDataType, nil, false
+	}
+	// ethernet 2
+	ethertypeU := binary.BigEndian.Uint16(originalFrameX[12:14])
+	sourceMacAddress := originalFrameX[6:12]
+	var sourceAddress, destinationAddress net.IP
+	// vlan tags - identifier of the outer tag is used
+	ethertypeU, startIndex, vlanId := skipVlanTags(originalFrameX, ethertypeU)
+	rawDataType.VlanId = vlanId
+	rawDataType.NetworkProtocol = uint(ethertypeU)
+	// ipv4
+	if ethertypeU == ETHER_TYPE_IPV4 && length >= startIndex + 20 {
+		ihl := originalFrameX[startIndex] & 0x0f
+		ihlU := uint(ihl) * 4
+		protocolU := uint8(originalFrameX[startIndex + 9])
+		sourceAddress = net.IP(originalFrameX[startIndex + 12 : startIndex + 16])
+		destinationAddress = net.IP(originalFrameX[startIndex + 16 : startIndex + 20])
+		startIndex += ihlU
+		rawDataType.TransportProtocol = uint(protocolU)
+		// tcp or udp
+		payload = decodePorts(originalFrameX, startIndex, protocolU, &rawDataType)
+		// icmp
+		decodeIcmp(originalFrameX, startIndex, protocolU == PROTOCOL_ICMP, &rawDataType)
+		// ipv6
+	} else if ethertypeU == ETHER_TYPE_IPV6 && length >= startIndex + IPV6_HEADER_LENGTH {
+		nextHeaderU := uint8(originalFrameX[startIndex + 6])
+		sourceAddress = net.IP(originalFrameX[startIndex + 8 : startIndex + 24])
+		destinationAddress = net.IP(originalFrameX[startIndex + 24 : startIndex + 40])
+		startIndex += IPV6_HEADER_LENGTH
+		protocolU, transportIndex, portsPresent := walkIpv6ExtensionHeaders(originalFrameX, startIndex, nextHeaderU)
+		rawDataType.TransportProtocol = uint(protocolU)
+		// tcp or udp
+		if portsPresent {
+			payload = decodePorts(originalFrameX, transportIndex, protocolU, &rawDataType)
+			decodeIcmp(originalFrameX, transportIndex, protocolU == PROTOCOL_ICMPV6, &rawDataType)
+		}
+	}
+	rawDataType.SrcAddress = model.NewIPAddress(sourceAddress)
+	rawDataType.DstAddress = model.NewIPAddress(destinationAddress)
+	rawDataType.Direction = FrameDecoder.directionClassifier.Classify(sourceMacAddress, sourceAddress,
+		destinationAddress)
+	return rawDataType, payload, true
+}
+
+// Skipping of VLAN tags (at most VLAN_MAX_TAGS) that follow after Ethernet 2 header.
+// Parameter originalFrameX []byte - Ethernet 2 frame.
+// Parameter ethertype uint16 - EtherType field of Ethernet 2 header.
+// Returning uint16 - EtherType of the network layer (the last read EtherType if the tags are truncated).
+// Returning uint - index of the first byte of the network layer header.
+// Returning uint - VLAN identifier of the outer tag (0 - the frame is untagged).
+func skipVlanTags(originalFrameX []byte, ethertype uint16) (uint16, uint, uint) {
+	length := uint(len(originalFrameX))
+	startIndex := uint(ETHERNET_HEADER_LENGTH)
+	vlanId := uint(0)
+	for tags := 0; tags < VLAN_MAX_TAGS && isVlanEtherType(ethertype) && length >= startIndex + VLAN_TAG_LENGTH;
+		tags++ {
+		if tags == 0 {
+			tci := binary.BigEndian.Uint16(originalFrameX[startIndex : startIndex + 2])
+			vlanId = uint(tci & VLAN_ID_MASK)
+		}
+		ethertype = binary.BigEndian.Uint16(originalFrameX[startIndex + 2 : startIndex + 4])
+		startIndex += VLAN_TAG_LENGTH
+	}
+	return ethertype, startIndex, vlanId
+}
+
+// Walking through the chain of IPv6 extension headers up to the upper-layer (transport) header.
+// Parameter originalFrameX []byte - unwrapped Ethernet 2 frame.
+// Parameter startIndex uint - index of the first byte after fixed IPv6 header.
+// Parameter nextHeader uint8 - next header field of fixed IPv6 header.
+// Returning protocol uint8 - upper-layer protocol (the last next header value if the chain is truncated).
+// Returning transportIndex uint - index of the first byte of upper-layer header.
+// Returning portsPresent bool - false if the chain is truncated or the packet is not the first fragment (ports
+// are carried only by the first fragment).
+func walkIpv6ExtensionHeaders(originalFrameX []byte, startIndex uint, nextHeader uint8) (protocol uint8,
+	transportIndex uint, portsPresent bool) {
+	length := uint(len(originalFrameX))
+	firstFragment := true
+	for headers := 0; headers < IPV6_MAX_EXTENSION_HEADERS && isIpv6ExtensionHeader(nextHeader); headers++ {
+		if length < startIndex + 8 {
+			return nextHeader, startIndex, false
+		}
+		var headerLength uint
+		switch nextHeader {
+		case IPV6_FRAGMENT:
+			offset := binary.BigEndian.Uint16(originalFrameX[startIndex + 2 : startIndex + 4])
+			firstFragment = firstFragment && offset & IPV6_FRAGMENT_OFFSET_MASK == 0
+			headerLength = IPV6_FRAGMENT_HEADER_LENGTH
+		case IPV6_AUTHENTICATION:
+			headerLength = (uint(originalFrameX[startIndex + 1]) + 2) * 4
+		default:
+			headerLength = (uint(originalFrameX[startIndex + 1]) + 1) * 8
+		}
+		nextHeader = originalFrameX[startIndex]
+		startIndex += headerLength
+	}
+	if isIpv6ExtensionHeader(nextHeader) {
+		return nextHeader, startIndex, false
+	}
+	return nextHeader, startIndex, firstFragment
+}
+
+// Checking if the IPv6 next header value identifies extension header.
+// Parameter nextHeader uint8 - next header field.
+// Returning bool - true if the next header is an extension header.
+func isIpv6ExtensionHeader(nextHeader uint8) bool {
+	switch nextHeader {
+	case IPV6_HOP_BY_HOP, IPV6_ROUTING, IPV6_FRAGMENT, IPV6_AUTHENTICATION, IPV6_DESTINATION_OPTIONS,
+		IPV6_MOBILITY, IPV6_HOST_IDENTITY, IPV6_SHIM6:
+		return true
+	default:
+		return false
+	}
+}
+
+// Decoding of TCP or UDP ports; ports are left unset for other protocols or truncated headers.
+// Parameter originalFrameX []byte - unwrapped Ethernet 2 frame.
+// Parameter startIndex uint - index of the first byte of transport header.
+// Parameter protocol uint8 - transport protocol.
+// Parameter rawDataType *model.RawDataType - raw data type to which the ports are written. See model.RawDataType.
+// Returning []byte - TCP / UDP payload (nil if the ports are not decoded or TCP options are truncated).
+func decodePorts(originalFrameX []byte, startIndex uint, protocol uint8, rawDataType *model.RawDataType) []byte {
+	length := uint(len(originalFrameX))
+	if (protocol == PROTOCOL_UDP && length >= startIndex + 8) ||
+		(protocol == PROTOCOL_TCP && length >= startIndex + 20) {
+		sourcePortU := binary.BigEndian.Uint16(originalFrameX[startIndex : startIndex + 2])
+		destinationPortU := binary.BigEndian.Uint16(originalFrameX[startIndex + 2 : startIndex + 4])
+		rawDataType.SrcPort = uint(sourcePortU)
+		rawDataType.DstPort = uint(destinationPortU)
+		payloadIndex := startIndex + 8
+		if protocol == PROTOCOL_TCP {
+			dataOffset := uint(originalFrameX[startIndex + 12] >> 4) * 4
+			if dataOffset < 20 {
+				return nil
+			}
+			payloadIndex = startIndex + dataOffset
+		}
+		if payloadIndex <= length {
+			return originalFrameX[payloadIndex:]
+		}
+	}
+	return nil
+}
+
+// Decoding of ICMP / ICMPv6 type and code; they are left unset for other protocols or truncated headers.
+// Parameter originalFrameX []byte - unwrapped Ethernet 2 frame.
+// Parameter startIndex uint - index of the first byte of ICMP header.
+// Parameter icmp bool - the transport protocol is ICMP (IPv4) or ICMPv6 (IPv6).
+// Parameter rawDataType *model.RawDataType - raw data type to which the type and code are written.
+// See model.RawDataType.
+func decodeIcmp(originalFrameX []byte, startIndex uint, icmp bool, rawDataType *model.RawDataType) {
+	if icmp && uint(len(originalFrameX)) >= startIndex + 2 {
+		rawDataType.IcmpPresent = true
+		rawDataType.IcmpType = uint(originalFrameX[startIndex])
+		rawDataType.IcmpCode = uint(originalFrameX[startIndex + 1])
+	}
+}
+
+// Checking if the EtherType identifies VLAN tag (802.1Q, 802.1ad, or legacy QinQ).
+// Parameter ethertype uint16 - EtherType field.
+// Returning bool - true if the EtherType belongs to VLAN tag.
+func isVlanEtherType(ethertype uint16) bool {
+	return ethertype == ETHER_TYPE_VLAN || ethertype == ETHER_TYPE_QINQ || ethertype == ETHER_TYPE_QINQ_LEGACY
+}
@@ -0,0 +1,202 @@
+package machine
+
+import (
+	"testing"
+	"model"
+	"net"
+)
+
+// MAC address of the monitored router that is used by tested decoder.
+var decoderRouterMac = []byte{0x20, 0x89, 0x84, 0x41, 0x4e, 0xd8}
+
+// Building of Ethernet 2 header.
+// Parameter sourceAddress []byte - source MAC address.
+// Parameter ethertype uint16 - EtherType field.
+// Returning []byte - Ethernet 2 header.
+func buildEthernetHeader(sourceAddress []byte, ethertype uint16) []byte {
+	header := make([]byte, 14)
+	copy(header[6:12], sourceAddress)
+	header[12] = byte(ethertype >> 8)
+	header[13] = byte(ethertype)
+	return header
+}
+
+// Building of IPv4 header without options.
+// Parameter protocol uint8 - transport protocol.
+// Returning []byte - IPv4 header.
+func buildIpv4Header(protocol uint8) []byte {
+	header := make([]byte, 20)
+	header[0] = 0x45
+	header[9] = protocol
+	return header
+}
+
+// Building of fixed IPv6 header.
+// Parameter nextHeader uint8 - next header field.
+// Returning []byte - IPv6 header.
+func buildIpv6Header(nextHeader uint8) []byte {
+	header := make([]byte, 40)
+	header[0] = 0x60
+	header[6] = nextHeader
+	return header
+}
+
+// Building of TCP header.
+// Parameter sourcePort uint16 - source port.
+// Parameter destinationPort uint16 - destination port.
+// Returning []byte - TCP header.
+func buildTcpHeader(sourcePort uint16, destinationPort uint16) []byte {
+	header := make([]byte, 20)
+	header[0] = byte(sourcePort >> 8)
+	header[1] = byte(sourcePort)
+	header[2] = byte(destinationPort >> 8)
+	header[3] = byte(destinationPort)
+	header[12] = 0x50
+	return header
+}
+
+// Concatenation of frame parts.
+// Parameter parts ...[]byte - frame headers and payloads.
+// Returning []byte - built frame.
+func buildFrame(parts ...[]byte) []byte {
+	var frame []byte
+	for _, part := range parts {
+		frame = append(frame, part...)
+	}
+	return frame
+}
+
+// Comparing of decoded raw data type against expected one.
+// Parameter frame []byte - decoded frame.
+// Parameter expected model.RawDataType - expected raw data type.
+// Parameter t *testing.T - testing engine.
+func checkDecodedFrame(frame []byte, expected model.RawDataType, t *testing.T) {
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	frameDecoder := NewFrameDecoder(directionClassifier)
+	decoded, valid := frameDecoder.DecodeFrame(frame)
+	if !valid {
+		t.Errorf("Expected valid frame, but the frame has been rejected.")
+		return
+	}
+	// addresses are verified by TestDecodeFrameAddresses
+	decoded.SrcAddress = model.IPAddress{}
+	decoded.DstAddress = model.IPAddress{}
+	if decoded != expected {
+		t.Errorf("Expected raw data type: %+v; given raw data type: %+v", expected, decoded)
+	}
+}
+
+// Unit test - decoding of untagged, 802.1Q and QinQ frames.
+// Parameter t *testing.T - testing engine.
+func TestDecodeFrameVlan(t *testing.T) {
+	otherMac := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	t.Log("Decoding of untagged frame ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_TCP), buildTcpHeader(443, 50000)),
+		model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 443, DstPort: 50000,
+			Direction: 1}, t)
+
+	t.Log("Decoding of 802.1Q tagged frame ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(otherMac, ETHER_TYPE_VLAN), []byte{0x20, 0x0a, 0x08, 0x00},
+		buildIpv4Header(PROTOCOL_TCP), buildTcpHeader(50000, 443)),
+		model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000, DstPort: 443,
+			VlanId: 10}, t)
+
+	t.Log("Decoding of QinQ tagged frame ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(otherMac, ETHER_TYPE_QINQ), []byte{0x00, 0x64, 0x81, 0x00},
+		[]byte{0x00, 0x0a, 0x86, 0xdd}, buildIpv6Header(PROTOCOL_TCP), buildTcpHeader(22, 60000)),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 6, SrcPort: 22, DstPort: 60000,
+			VlanId: 100}, t)
+}
+
+// Unit test - decoding of IPv6 packets with chain of extension headers.
+// Parameter t *testing.T - testing engine.
+func TestDecodeFrameIpv6ExtensionHeaders(t *testing.T) {
+	hopByHop := []byte{IPV6_DESTINATION_OPTIONS, 0, 0, 0, 0, 0, 0, 0}
+	destinationOptions := []byte{IPV6_FRAGMENT, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	firstFragment := []byte{PROTOCOL_TCP, 0, 0x00, 0x01, 0, 0, 0, 1}
+	nextFragment := []byte{PROTOCOL_TCP, 0, 0x05, 0xa8, 0, 0, 0, 1}
+
+	t.Log("Decoding of the first fragment ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_HOP_BY_HOP), hopByHop, destinationOptions, firstFragment, buildTcpHeader(443, 50000)),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 6, SrcPort: 443, DstPort: 50000,
+			Direction: 1}, t)
+
+	t.Log("Decoding of the next fragment (ports are not present) ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_HOP_BY_HOP), hopByHop, destinationOptions, nextFragment, buildTcpHeader(443, 50000)),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 6, Direction: 1}, t)
+
+	t.Log("Decoding of truncated chain of extension headers ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_HOP_BY_HOP), hopByHop, destinationOptions[:4]),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 60, Direction: 1}, t)
+}
+
+// Unit test - decoding of ICMP and ICMPv6 types and codes.
+// Parameter t *testing.T - testing engine.
+func TestDecodeFrameIcmp(t *testing.T) {
+	t.Log("Decoding of ICMP echo request ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_ICMP), []byte{8, 0, 0xf7, 0xff, 0, 0, 0, 0}),
+		model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true, IcmpType: 8,
+			Direction: 1}, t)
+
+	t.Log("Decoding of ICMPv6 neighbour solicitation behind extension header ...")
+	hopByHop := []byte{PROTOCOL_ICMPV6, 0, 0, 0, 0, 0, 0, 0}
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_HOP_BY_HOP), hopByHop, []byte{135, 0, 0, 0}),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 58, IcmpPresent: true, IcmpType: 135,
+			Direction: 1}, t)
+
+	t.Log("Decoding of ICMP destination unreachable (port unreachable) ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_ICMP), []byte{3, 3, 0, 0}),
+		model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true, IcmpType: 3, IcmpCode: 3,
+			Direction: 1}, t)
+
+	t.Log("Decoding of truncated ICMP header ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_ICMP), []byte{8}),
+		model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, Direction: 1}, t)
+
+	t.Log("ICMP type is not decoded for other protocols ...")
+	checkDecodedFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(PROTOCOL_ICMP), []byte{8, 0, 0, 0}),
+		model.RawDataType{NetworkProtocol: 34525, TransportProtocol: 1, Direction: 1}, t)
+}
+
+// Unit test - decoding of IPv4 and IPv6 addresses.
+func TestDecodeFrameAddresses(t *testing.T) {
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	frameDecoder := NewFrameDecoder(directionClassifier)
+
+	t.Log("Decoding of IPv4 addresses ...")
+	ipv4Header := buildIpv4Header(PROTOCOL_TCP)
+	copy(ipv4Header[12:20], []byte{192, 168, 1, 10, 8, 8, 8, 8})
+	decoded, _ := frameDecoder.DecodeFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		ipv4Header, buildTcpHeader(50000, 53)))
+	if !decoded.SrcAddress.ToIP().Equal(net.ParseIP("192.168.1.10")) ||
+		!decoded.DstAddress.ToIP().Equal(net.ParseIP("8.8.8.8")) {
+		t.Errorf("Wrongly decoded IPv4 addresses: %v -> %v", decoded.SrcAddress.ToIP(), decoded.DstAddress.ToIP())
+	}
+
+	t.Log("Decoding of IPv6 addresses ...")
+	ipv6Header := buildIpv6Header(PROTOCOL_TCP)
+	copy(ipv6Header[8:24], net.ParseIP("2001:db8::1"))
+	copy(ipv6Header[24:40], net.ParseIP("2001:db8::2"))
+	decoded, _ = frameDecoder.DecodeFrame(buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		ipv6Header, buildTcpHeader(50000, 53)))
+	if !decoded.SrcAddress.ToIP().Equal(net.ParseIP("2001:db8::1")) ||
+		!decoded.DstAddress.ToIP().Equal(net.ParseIP("2001:db8::2")) {
+		t.Errorf("Wrongly decoded IPv6 addresses: %v -> %v", decoded.SrcAddress.ToIP(), decoded.DstAddress.ToIP())
+	}
+
+	t.Log("Decoding of non-IP frame ...")
+	decoded, _ = frameDecoder.DecodeFrame(buildEthernetHeader(decoderRouterMac, 0x0806))
+	if decoded.SrcAddress.IsKnown() || decoded.DstAddress.IsKnown() {
+		t.Errorf("Addresses of non-IP frame should not be known: %+v", decoded)
+	}
+}
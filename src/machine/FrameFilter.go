@@ -0,0 +1,51 @@
+package machine
+
+import (
+	"fmt"
+	"sync"
+	"configuration"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+)
+
+// BPF filter that is applied to original (decapsulated) Ethernet 2 frames. Compiled program of pcap shares its packet
+// header between calls, so matching is serialised for parse workers.
+// Attribute expression string - BPF expression.
+// Attribute bpf *pcap.BPF - compiled program. See pcap.BPF.
+// Attribute mutex *sync.Mutex - synchronisation of parse workers. See sync.Mutex.
+type FrameFilter struct {
+	expression	string
+	bpf			*pcap.BPF
+	mutex		*sync.Mutex
+}
+
+// Creating instance of the FrameFilter (compiling of the expression).
+// Parameter expression string - BPF expression (tcpdump syntax).
+// Parameter snapshotLength uint - maximum length of matched frames.
+// Returning *FrameFilter - compiled filter.
+// Returning error - invalid BPF expression.
+func NewFrameFilter(expression string, snapshotLength uint) (*FrameFilter, error) {
+	bpf, err := pcap.NewBPF(layers.LinkTypeEthernet, int(snapshotLength), expression)
+	if err != nil {
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("invalid BPF expression %s: %v", expression, err))
+		return nil, compositeError.Evaluate()
+	}
+	frameFilter := FrameFilter{
+		expression: expression,
+		bpf: bpf,
+		mutex: &sync.Mutex{},
+	}
+	return &frameFilter, nil
+}
+
+// Matching of the original frame.
+// Parameter frame []byte - original Ethernet 2 frame.
+// Returning bool - the frame is passed by the filter.
+func (FrameFilter *FrameFilter) Matches(frame []byte) bool {
+	captureInfo := gopacket.CaptureInfo{CaptureLength: len(frame), Length: len(frame)}
+	FrameFilter.mutex.Lock()
+	defer FrameFilter.mutex.Unlock()
+	return FrameFilter.bpf.Matches(captureInfo, frame)
+}
@@ -7,43 +7,86 @@ import (
 	"time"
 	"github.com/google/gopacket"
 	"net"
-	"encoding/binary"
-	"bytes"
 )
 
 // Initial capacity of the frames buffer.
 const STARTING_MAP_SIZE uint = 8000
+// Maximum number of frames in one frames bucket (default of BucketFrames setting).
 const BUFFER_MAX_SIZE uint = 250000
-const FILTER_TZSP = "udp port 37008"
-const TAG_TYPE_PADDING = byte(0x00)
-const TAG_TYPE_END = byte(0x01)
 const ETHER_TYPE_IPV4 = uint16(2048)
 const ETHER_TYPE_IPV6 = uint16(34525)
 const PROTOCOL_UDP = uint8(17)
 const PROTOCOL_TCP = uint8(6)
-const PORT_TZSP = uint16(37008)
+const PROTOCOL_ICMP = uint8(1)
+const PROTOCOL_ICMPV6 = uint8(58)
+// Results of processing of one captured frame.
+const FRAME_PROCESSED = uint(0)
+const FRAME_NOT_ENCAPSULATED = uint(1)
+const FRAME_MALFORMED = uint(2)
+const FRAME_PARSE_ERROR = uint(3)
+const FRAME_FILTERED = uint(4)
 
 // Attribute routerMacAddress *([]byte) - MAC address of monitored router's interface.
 // Attribute conf model.NetworkConfiguration - network configuration settings. See model.NetworkConfiguration.
-// Attribute statisticalData *model.StatisticalData - instance that control access to SQL database.
-// See model.StatisticalData.
+// Attribute framesPipeline *FramesPipeline - pipeline to which captured frames buckets are submitted.
+// See FramesPipeline.
 // Attribute handler *pcap.Handle - incoming frames handler. See pcap.Handle.
+// Attribute decapsulator FrameDecapsulator - unwrapping of captured frames to original Ethernet 2 frames.
+// See FrameDecapsulator.
+// Attribute frameDecoder *FrameDecoder - decoding of raw data types from original frames. See FrameDecoder.
+// Attribute captureSource model.CaptureSource - observed link (adapter, router's MAC address, and interface
+// identifier). See model.CaptureSource.
+// Attribute captureStatistics *CaptureStatistics - health counters of capturing. See CaptureStatistics.
+// Attribute sensorClock *sensorClock - conversion of TZSP timestamp tags (nil - capture timestamps are used).
+// See sensorClock.
+// Attribute applicationClassifier *ApplicationClassifier - flow-sticky reading of server names (nil - frames are
+// classified only by their headers). See ApplicationClassifier.
+// Attribute flowTable *FlowTable - table of flows that is shared by all frames parsers (nil - flows are not tracked).
+// See FlowTable.
+// Attribute innerFilter *FrameFilter - filter of original frames (nil - all frames are accounted). See FrameFilter.
 type FramesParser struct {
 	routerMacAddress		*([]byte)
 	networkConfiguration 	*model.NetworkConfiguration
-	statisticalData 		*model.StatisticalData
+	framesPipeline			*FramesPipeline
 	handler					*pcap.Handle
+	decapsulator			FrameDecapsulator
+	frameDecoder			*FrameDecoder
+	captureSource			model.CaptureSource
+	captureStatistics		*CaptureStatistics
+	sensorClock				*sensorClock
+	applicationClassifier	*ApplicationClassifier
+	flowTable				*FlowTable
+	innerFilter				*FrameFilter
+}
+
+// Captured frame with its capture timestamp.
+// Attribute data []byte - captured frame.
+// Attribute timestamp time.Time - capture timestamp (rebased to replay start for replayed files). See time.Time.
+// Attribute length uint - original length of captured frame (it is longer than data if the frame has been truncated
+// by snapshot length; 0 - unknown).
+type capturedFrame struct {
+	data		[]byte
+	timestamp	time.Time
+	length		uint
 }
 
 // Creating instance of the FramesParser.
 // Parameter conf model.NetworkConfiguration - network configuration settings. See model.NetworkConfiguration.
-// Parameter statisticalData *model.StatisticalData - instance that control access to SQL database.
-// See model.StatisticalData.
+// Parameter captureSource model.CaptureSource - observed link. See model.CaptureSource.
+// Parameter framesPipeline *FramesPipeline - pipeline to which captured frames buckets are submitted.
+// See FramesPipeline.
+// Parameter captureStatistics *CaptureStatistics - health counters of capturing. See CaptureStatistics.
+// Parameter flowTable *FlowTable - table of flows that is shared by all frames parsers. See FlowTable.
 // Returning *FramesParser - FramesParser object.
-func NewFramesParser(conf *model.NetworkConfiguration, statisticalData *model.StatisticalData) *FramesParser {
+func NewFramesParser(conf *model.NetworkConfiguration, captureSource model.CaptureSource,
+	framesPipeline *FramesPipeline, captureStatistics *CaptureStatistics, flowTable *FlowTable) *FramesParser {
 	framesParser := FramesParser {
 		networkConfiguration: conf,
-		statisticalData: statisticalData,
+		captureSource: captureSource,
+		framesPipeline: framesPipeline,
+		captureStatistics: captureStatistics,
+		applicationClassifier: NewApplicationClassifier(),
+		flowTable: flowTable,
 	}
 	return &framesParser
 }
@@ -51,232 +94,324 @@ func NewFramesParser(conf *model.NetworkConfiguration, statisticalData *model.St
 // Starting of the frames capturing under selected network configuration.
 func (FramesParser *FramesParser) StartCapturing() {
 	FramesParser.readRouterMacAddress()
+	FramesParser.buildDecapsulator()
 	FramesParser.openNetworkAdapter()
+	FramesParser.captureStatistics.RegisterSource(FramesParser.captureSource.Name)
+	if FramesParser.networkConfiguration.ReplayFile == "" {
+		go FramesParser.sampleStatistics()
+	}
 	FramesParser.processFrames()
 }
 
-// Converting of string to MAC address (byte array format).
+// Converting of string to MAC address (byte array format) and building of direction classifier.
 func (FramesParser *FramesParser) readRouterMacAddress() {
-	macAddress := FramesParser.networkConfiguration.RouterMacAddress
+	macAddress := FramesParser.captureSource.RouterMacAddress
 	hw, err := net.ParseMAC(macAddress)
 	if err != nil {
 		configuration.Error.Panicf("Error reading of router's MAC address %s: %v", macAddress, err)
 	}
 	array := []byte(hw)
 	FramesParser.routerMacAddress = &array
+	directionClassifier, err := NewDirectionClassifier(FramesParser.routerMacAddress,
+		FramesParser.networkConfiguration.LocalNetworks)
+	if err != nil {
+		configuration.Error.Panicf("Error reading of local networks: %v", err)
+	}
+	FramesParser.frameDecoder = NewFrameDecoder(directionClassifier)
+}
+
+// Selecting of the frame decapsulator according to configured encapsulation mode and compiling of the filter of
+// original frames.
+func (FramesParser *FramesParser) buildDecapsulator() {
+	encapsulation := FramesParser.networkConfiguration.Encapsulation
+	decapsulator, err := NewFrameDecapsulator(encapsulation, FramesParser.networkConfiguration.TzspPorts)
+	if err != nil {
+		configuration.Error.Panicf("Error selecting of the frames encapsulation %s: %v", encapsulation, err)
+	}
+	FramesParser.decapsulator = decapsulator
+	innerFilter := FramesParser.networkConfiguration.InnerFilter
+	if innerFilter != "" {
+		FramesParser.innerFilter, err = NewFrameFilter(innerFilter, FramesParser.networkConfiguration.MaximumFrameSize)
+		if err != nil {
+			configuration.Error.Panicf("Error compiling of the filter of original frames: %v", err)
+		}
+		configuration.Info.Printf("Filter of original frames is applied: %s.", innerFilter)
+	}
+	if FramesParser.networkConfiguration.TzspTimestamps {
+		if _, supported := decapsulator.(TaggedDecapsulator); supported {
+			FramesParser.sensorClock = newSensorClock()
+		} else {
+			configuration.Warning.Printf("Encapsulation %s doesn't carry timestamps - capture timestamps are used.",
+				encapsulation)
+		}
+	}
 }
 
-// Opening of the network adapter and setting of TZSP filter.
+// Opening of the network adapter (or replayed capture file) and setting of capture filter - the filter of capture
+// source or the filter that belongs to the selected encapsulation.
 func (FramesParser *FramesParser) openNetworkAdapter() {
+	var handler *pcap.Handle
+	if FramesParser.networkConfiguration.ReplayFile != "" {
+		handler = FramesParser.openReplayFile()
+	} else {
+		handler = FramesParser.openLiveAdapter()
+	}
+
+	captureFilter := FramesParser.captureSource.CaptureFilter
+	if captureFilter == "" {
+		captureFilter = FramesParser.decapsulator.CaptureFilter()
+	}
+	if captureFilter != "" {
+		configuration.Info.Printf("Setting of capture filter: %s.", captureFilter)
+		err := handler.SetBPFFilter(captureFilter)
+		if err != nil {
+			configuration.Error.Panicf("Error applying of capture filter %s: %v", captureFilter, err)
+		}
+		configuration.Info.Println("Capture filter is applied.")
+	}
+	FramesParser.handler = handler
+}
+
+// Opening of the live network adapter.
+// Returning *pcap.Handle - opened network adapter. See pcap.Handle.
+func (FramesParser *FramesParser) openLiveAdapter() *pcap.Handle {
 	configuration.Info.Println("Opening of the network adapter.")
 	readTimeout := time.Duration(FramesParser.networkConfiguration.ReadTimeout) * time.Millisecond
-	handler, err01 := pcap.OpenLive(FramesParser.networkConfiguration.AdapterName,
+	handler, err := pcap.OpenLive(FramesParser.captureSource.AdapterName,
 		int32(FramesParser.networkConfiguration.MaximumFrameSize),
 		false, readTimeout)
-	if err01 != nil {
+	if err != nil {
 		configuration.Error.Panicf("Error opening device %s: %v",
-			FramesParser.networkConfiguration.AdapterName, err01)
+			FramesParser.captureSource.AdapterName, err)
 	}
 	configuration.Info.Println("Network adapter is open.")
+	return handler
+}
 
-	configuration.Info.Println("Setting of TZSP filter.")
-	err02 := handler.SetBPFFilter(FILTER_TZSP)
-	if err02 != nil {
-		configuration.Error.Panicf("Error applying of TZSP filter: %v", err02)
+// Opening of the pcap or pcapng file that is replayed instead of the live network adapter.
+// Returning *pcap.Handle - opened capture file. See pcap.Handle.
+func (FramesParser *FramesParser) openReplayFile() *pcap.Handle {
+	replayFile := FramesParser.networkConfiguration.ReplayFile
+	if FramesParser.networkConfiguration.ReplaySpeed < 0 {
+		configuration.Error.Panicf("Invalid replay speed %v: the speed cannot be negative",
+			FramesParser.networkConfiguration.ReplaySpeed)
 	}
-	configuration.Info.Println("TZSP filter is applied.")
-	FramesParser.handler = handler
+	configuration.Info.Printf("Opening of the replayed capture file %s.", replayFile)
+	handler, err := pcap.OpenOffline(replayFile)
+	if err != nil {
+		configuration.Error.Panicf("Error opening capture file %s: %v", replayFile, err)
+	}
+	configuration.Info.Println("Replayed capture file is open.")
+	return handler
 }
 
-// Sequential processing of frames.
+// Capture stage - frames are collected into buckets that are submitted to the frames pipeline on every tick or
+// when the bucket is full. Replayed files are never dropped by the pipeline (the replay is slowed down instead).
 func (FramesParser *FramesParser) processFrames() {
 	configuration.Info.Println("Starting of frames processing.")
 	go func() {
-		framesRing := make([](*[]byte), BUFFER_MAX_SIZE)
-		actualRingSize := uint(0)
+		replay := FramesParser.networkConfiguration.ReplayFile != ""
+		bucket := make([]capturedFrame, 0, STARTING_MAP_SIZE)
 		tickChannel := time.Tick(time.Millisecond * time.Duration(FramesParser.networkConfiguration.DataBuffer))
 		handler := FramesParser.handler
 		defer handler.Close()
 		framesSource := gopacket.NewPacketSource(handler, handler.LinkType())
 		framesSource.Lazy = true
-		for frame := range framesSource.Packets() {
-			frameData := frame.Data()
-			framesRing[actualRingSize] = &frameData
-			select {
-			case <- tickChannel:
-				go FramesParser.processFramesBucket(framesRing, actualRingSize)
-				actualRingSize = uint(0)
-			default:
-				actualRingSize++
-			}
-		}
-		configuration.Info.Println("Frames processing finished.")
-	}()
-}
-
-// Processing of frames bucket by using aggregation on bytes over same raw data types.
-// Parameter buffer []*gopacket.Packet - buffered network frames.
-func (FramesParser *FramesParser) processFramesBucket(frames [](*[]byte), size uint) {
-	repository := make(map[model.RawDataType](*model.RawData), STARTING_MAP_SIZE)
-	for i:=uint(0); i<size+1; i++ {
-		// template
-		rawDataType := model.RawDataType{
-			NetworkProtocol:   0,
-			TransportProtocol: 0,
-			SrcPort:           0,
-			DstPort:           0,
-			Direction:         0,
+		framesChannel := framesSource.Packets()
+		var pacer *replayPacer
+		if replay {
+			pacer = newReplayPacer(FramesParser.networkConfiguration.ReplaySpeed)
 		}
-		startIndex := uint(0)
-		// ethernet 2
-		originalFrame := unwrapTzsp(frames[i])
-		originalFrameX := *originalFrame
-		length := len(originalFrameX)
-		if length >= 14 {
-			ethertype := []byte{originalFrameX[startIndex + 12], originalFrameX[startIndex + 13]}
-			ethertypeU := binary.BigEndian.Uint16(ethertype)
-			sourceAddress := originalFrameX[startIndex + 6 : startIndex + 12]
-			if bytes.Equal(sourceAddress, *(FramesParser.routerMacAddress)) {
-				rawDataType.Direction = 1
-			}
-			startIndex = uint(14)
-			rawDataType.NetworkProtocol = uint(ethertypeU)
-			// ipv4
-			if length >= 34 && ethertypeU == ETHER_TYPE_IPV4 {
-				ihl := originalFrameX[startIndex] & 0x0f
-				ihlU := uint8(ihl) * 4
-				protocol := originalFrameX[startIndex + 9]
-				protocolU := uint8(protocol)
-				startIndex += uint(ihlU)
-				rawDataType.TransportProtocol = uint(protocolU)
-				// tcp or udp
-				if (length >= 42 && protocolU == PROTOCOL_UDP) || (length >= 54 && protocolU == PROTOCOL_TCP) {
-					sourcePort := []byte{originalFrameX[startIndex], originalFrameX[startIndex + 1]}
-					destinationPort := []byte{originalFrameX[startIndex + 2], originalFrameX[startIndex + 3]}
-					sourcePortU := binary.BigEndian.Uint16(sourcePort)
-					destinationPortU := binary.BigEndian.Uint16(destinationPort)
-					rawDataType.SrcPort = uint(sourcePortU)
-					rawDataType.DstPort = uint(destinationPortU)
+		for {
+			select {
+			case frame, open := <-framesChannel:
+				if !open {
+					// remaining frames of the finished capture (end of the replayed file)
+					if len(bucket) != 0 {
+						FramesParser.submitBucket(bucket, true)
+					}
+					configuration.Info.Println("Frames processing finished.")
+					return
 				}
-				// ipv6
-			} else if length >= 54 && ethertypeU == ETHER_TYPE_IPV6 {
-				nextHeader := originalFrameX[startIndex + 6]
-				nextHeaderU := uint8(nextHeader)
-				startIndex += uint(40)
-				rawDataType.TransportProtocol = uint(nextHeaderU)
-				// tcp or udp
-				if (length >= 62 && nextHeaderU == PROTOCOL_UDP) || (length >= 74 && nextHeaderU == PROTOCOL_TCP) {
-					sourcePort := []byte{originalFrameX[startIndex], originalFrameX[startIndex + 1]}
-					destinationPort := []byte{originalFrameX[startIndex + 2], originalFrameX[startIndex + 3]}
-					sourcePortU := binary.BigEndian.Uint16(sourcePort)
-					destinationPortU := binary.BigEndian.Uint16(destinationPort)
-					rawDataType.SrcPort = uint(sourcePortU)
-					rawDataType.DstPort = uint(destinationPortU)
+				timestamp := frame.Metadata().Timestamp
+				if pacer != nil {
+					timestamp = pacer.waitForFrame(timestamp)
+				} else if timestamp.IsZero() {
+					timestamp = time.Now()
 				}
-			}
-			// increasing of counters
-			_, present := repository[rawDataType]
-			if present == true {
-				data := repository[rawDataType]
-				newBytes := data.Bytes + uint(len(originalFrameX))
-				actualTime := time.Now()
-				newDataEntry := model.RawData{
-					Bytes: newBytes,
-					Time: actualTime,
-					RawDataType: &rawDataType,
+				bucket = append(bucket, capturedFrame{data: frame.Data(), timestamp: timestamp,
+					length: uint(frame.Metadata().Length)})
+				// the bucket is full before the next tick (high rate or fast replay) - it must be submitted now
+				if uint(len(bucket)) == FramesParser.framesPipeline.bucketFrames {
+					FramesParser.captureStatistics.AddEarlyBucketFlush(FramesParser.captureSource.Name)
+					FramesParser.submitBucket(bucket, replay)
+					bucket = make([]capturedFrame, 0, STARTING_MAP_SIZE)
 				}
-				repository[rawDataType] = &newDataEntry
-			} else {
-				rawData := model.RawData{
-					Bytes: uint(len(originalFrameX)),
-					Time: time.Now(),
-					RawDataType: &rawDataType,
+			case <-tickChannel:
+				if len(bucket) != 0 {
+					FramesParser.submitBucket(bucket, replay)
+					bucket = make([]capturedFrame, 0, STARTING_MAP_SIZE)
 				}
-				repository[rawDataType] = &rawData
 			}
 		}
+	}()
+}
+
+// Submitting of frames bucket to the frames pipeline.
+// Parameter frames []capturedFrame - captured frames (the slice must not be reused by the capture stage).
+// Parameter block bool - the capture stage waits for free queue slot regardless of drop policy.
+func (FramesParser *FramesParser) submitBucket(frames []capturedFrame, block bool) {
+	FramesParser.framesPipeline.Submit(&FramesBucket{
+		frames: frames,
+		framesParser: FramesParser,
+	}, block)
+}
+
+// Attribute speed float64 - replay speed multiplier (0 - as fast as possible).
+// Attribute firstFrameTime time.Time - capture timestamp of the first replayed frame. See time.Time.
+// Attribute replayStart time.Time - wall-clock time at which the first frame has been replayed. See time.Time.
+type replayPacer struct {
+	speed			float64
+	firstFrameTime	time.Time
+	replayStart		time.Time
+}
+
+// Creating of the pacer that keeps recorded gaps between frames during replay of the capture file.
+// Parameter speed float64 - replay speed multiplier (0 - as fast as possible).
+// Returning *replayPacer - replayPacer object.
+func newReplayPacer(speed float64) *replayPacer {
+	return &replayPacer{
+		speed: speed,
+	}
+}
+
+// Waiting until the replayed frame should be released according to its capture timestamp and replay speed.
+// Parameter frameTime time.Time - capture timestamp of the replayed frame. See time.Time.
+// Returning time.Time - capture timestamp rebased to the replay start; recorded gaps are kept unchanged for every
+// replay speed (the speed only shortens or lengthens waiting), so rates of replayed traffic match the recorded ones.
+// See time.Time.
+func (replayPacer *replayPacer) waitForFrame(frameTime time.Time) time.Time {
+	if replayPacer.replayStart.IsZero() {
+		replayPacer.firstFrameTime = frameTime
+		replayPacer.replayStart = time.Now()
+		return replayPacer.replayStart
 	}
-	// if there are some entries, sent them to DB
-	if len(repository) != 0 {
-		// building of slice from map and sending of slice to DB
-		slice := make([](*model.RawData), len(repository))
-		i := uint(0)
-		for  _, value := range repository {
-			slice[i] = value
-			i++
+	recordedOffset := frameTime.Sub(replayPacer.firstFrameTime)
+	if replayPacer.speed != 0 {
+		replayedOffset := time.Duration(float64(recordedOffset) / replayPacer.speed)
+		delay := time.Until(replayPacer.replayStart.Add(replayedOffset))
+		if delay > 0 {
+			time.Sleep(delay)
 		}
-		go FramesParser.statisticalData.WriteNewDataEntries(&slice)
 	}
+	return replayPacer.replayStart.Add(recordedOffset)
 }
 
-// Unwrapping of TZSP datagram.
-// Parameter frame *[]byte - original frame.
-// Returning *[]byte - unwrapped original frame.
-func unwrapTzsp(frame *[]byte) *[]byte {
-	startIndex := uint(0)
-	framex := *frame
-	length := uint(len(framex))
-	if length > 14 {
-		ethertype := []byte{framex[startIndex + 12], framex[startIndex + 13]}
-		ethertypeU := binary.BigEndian.Uint16(ethertype)
-		startIndex = uint(14)
-		if length >= 34 && ethertypeU == ETHER_TYPE_IPV4 {
-			ihl := framex[startIndex] & 0x0f
-			ihlU := uint8(ihl) * 4
-			protocol := framex[startIndex + 9]
-			protocolU := uint8(protocol)
-			startIndex += uint(ihlU)
-			if length >= 14 + uint(ihlU) && protocolU == PROTOCOL_UDP {
-				sourcePort := []byte{framex[startIndex], framex[startIndex+1]}
-				destinationPort := []byte{framex[startIndex+2], framex[startIndex+3]}
-				sourcePortU := binary.BigEndian.Uint16(sourcePort)
-				destinationPortU := binary.BigEndian.Uint16(destinationPort)
-				if length >= 22+uint(ihlU) && (sourcePortU == PORT_TZSP || destinationPortU == PORT_TZSP) {
-					startIndex += uint(8)
-					startIndex = getTzspPayloadIndex(frame, startIndex)
-					cutFrameX := framex[startIndex:]
-					return &cutFrameX
-				}
-			}
-		} else if length >= 54 && ethertypeU == ETHER_TYPE_IPV6 {
-			nextHeader := framex[startIndex + 6]
-			nextHeaderU := uint8(nextHeader)
-			startIndex += uint(40)
-			if length >= 62 && nextHeaderU == PROTOCOL_UDP {
-				sourcePort := []byte{framex[startIndex], framex[startIndex + 1]}
-				destinationPort := []byte{framex[startIndex + 2], framex[startIndex + 3]}
-				sourcePortU := binary.BigEndian.Uint16(sourcePort)
-				destinationPortU := binary.BigEndian.Uint16(destinationPort)
-				if length >= 67 && (sourcePortU == PORT_TZSP || destinationPortU == PORT_TZSP) {
-					startIndex += uint(8)
-					startIndex = getTzspPayloadIndex(frame, startIndex)
-					cutFrameX := framex[startIndex:]
-					return &cutFrameX
-				}
-			}
+// Periodical sampling of pcap statistics (kernel and interface drops) of the live network adapter.
+func (FramesParser *FramesParser) sampleStatistics() {
+	interval := FramesParser.networkConfiguration.StatisticsInterval
+	if interval == 0 {
+		interval = STATISTICS_INTERVAL_DEFAULT
+	}
+	interfaceName := FramesParser.captureSource.Name
+	tickChannel := time.Tick(time.Millisecond * time.Duration(interval))
+	for range tickChannel {
+		stats, err := FramesParser.handler.Stats()
+		if err != nil {
+			configuration.Warning.Printf("Capture statistics of %s cannot be read: %v", interfaceName, err)
+			continue
+		}
+		newDrops := FramesParser.captureStatistics.SetHandleStatistics(interfaceName, uint64(stats.PacketsReceived),
+			uint64(stats.PacketsDropped), uint64(stats.PacketsIfDropped), time.Now())
+		if newDrops != 0 {
+			configuration.Warning.Printf("%d frames have been dropped on %s since the last sampling (kernel drops: " +
+				"%d, interface drops: %d).", newDrops, interfaceName, stats.PacketsDropped, stats.PacketsIfDropped)
 		}
 	}
-	return nil
 }
 
-// Reading of index of first data byte wrapped in TZSP datagram.
-// Parameter tzspDatagram *[]byte - TZSP datagram.
-// Parameter nextIndex uint - Index from which we would like to read TZSP tag type.
-// Returning uint - Final index of first data byte.
-func getTzspPayloadIndex(tzspDatagram *[]byte, nextIndex uint) uint {
-	datagram := *tzspDatagram
-	tagType := datagram[nextIndex]
-	nextIndex = nextIndex + 4
-	if tagType == TAG_TYPE_PADDING {
-		index := nextIndex + 1
-		getTzspPayloadIndex(tzspDatagram, index)
-	} else if tagType == TAG_TYPE_END {
-		index := nextIndex + 1
-		return index
+// Processing of frames bucket by using aggregation on bytes and frames count over same raw data types (called
+// by parse workers of the frames pipeline). Aggregated entries update the flow table.
+// Parameter frames []capturedFrame - buffered network frames.
+// Returning *DataAggregator - aggregated entries. See DataAggregator.
+func (FramesParser *FramesParser) processFramesBucket(frames []capturedFrame) *DataAggregator {
+	dataAggregator := NewDataAggregator()
+	var nonEncapsulated, malformed, parseErrors uint64
+	for i := range frames {
+		switch FramesParser.processFrame(&frames[i], dataAggregator) {
+		case FRAME_NOT_ENCAPSULATED:
+			nonEncapsulated++
+		case FRAME_MALFORMED:
+			malformed++
+		case FRAME_PARSE_ERROR:
+			parseErrors++
+		}
+	}
+	if nonEncapsulated != 0 || malformed != 0 || parseErrors != 0 {
+		FramesParser.captureStatistics.AddFrameErrors(FramesParser.captureSource.Name, nonEncapsulated, malformed,
+			parseErrors)
+	}
+	if FramesParser.flowTable != nil {
+		FramesParser.flowTable.AddEntries(dataAggregator.BuildSlice())
+	}
+	return dataAggregator
+}
+
+// Unwrapping and decoding of one frame; runtime errors caused by corrupted headers are recovered. The frame is
+// aggregated with its capture timestamp or with the timestamp carried by TZSP (if it is enabled). Truncated frames
+// are accounted by their original length (snapshot length of capture or original length tag of TZSP). Original frames
+// that don't match the inner filter are ignored. Server names of TCP / UDP flows are assigned by application
+// classifier.
+// Parameter frame *capturedFrame - captured frame. See capturedFrame.
+// Parameter dataAggregator *DataAggregator - aggregator to which decoded frame is added. See DataAggregator.
+// Returning result uint - processing result (FRAME_PROCESSED, FRAME_NOT_ENCAPSULATED, FRAME_MALFORMED,
+// FRAME_PARSE_ERROR, or FRAME_FILTERED).
+func (FramesParser *FramesParser) processFrame(frame *capturedFrame, dataAggregator *DataAggregator) (result uint) {
+	defer func() {
+		if recover() != nil {
+			result = FRAME_PARSE_ERROR
+		}
+	}()
+	var originalFrame *[]byte
+	var header *TzspHeader
+	if taggedDecapsulator, tagged := FramesParser.decapsulator.(TaggedDecapsulator); tagged {
+		var err error
+		originalFrame, header, err = taggedDecapsulator.UnwrapWithHeader(&frame.data)
+		if err != nil {
+			return FRAME_MALFORMED
+		}
 	} else {
-		tagLength := uint(datagram[1])
-		index := nextIndex + tagLength + 2
-		return getTzspPayloadIndex(tzspDatagram, index)
+		originalFrame = FramesParser.decapsulator.Unwrap(&frame.data)
+	}
+	if originalFrame == nil {
+		return FRAME_NOT_ENCAPSULATED
 	}
-	return uint(0)
-}
\ No newline at end of file
+	originalFrameX := *originalFrame
+	if FramesParser.innerFilter != nil && !FramesParser.innerFilter.Matches(originalFrameX) {
+		return FRAME_FILTERED
+	}
+	rawDataType, payload, decoded := FramesParser.frameDecoder.DecodeFrameWithPayload(originalFrameX)
+	if !decoded {
+		return FRAME_MALFORMED
+	}
+	rawDataType.InterfaceName = FramesParser.captureSource.Name
+	bytes := uint(len(originalFrameX))
+	if frame.length > uint(len(frame.data)) {
+		bytes += frame.length - uint(len(frame.data))
+	}
+	timestamp := frame.timestamp
+	if header != nil {
+		if header.OriginalLength > bytes {
+			bytes = header.OriginalLength
+		}
+		if FramesParser.sensorClock != nil && header.TimestampPresent {
+			timestamp = FramesParser.sensorClock.convert(header.Timestamp, frame.timestamp)
+		}
+	}
+	if FramesParser.applicationClassifier != nil {
+		FramesParser.applicationClassifier.Classify(&rawDataType, payload, timestamp)
+	}
+	// increasing of counters
+	dataAggregator.AddEntry(rawDataType, uint64(bytes), 1, timestamp)
+	return FRAME_PROCESSED
+}
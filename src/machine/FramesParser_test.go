@@ -0,0 +1,217 @@
+package machine
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// Unit test - classification of processed frames (non-encapsulated, malformed, and corrupted frames).
+// Parameter t *testing.T - testing engine.
+func TestProcessFrame(t *testing.T) {
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	framesParser := FramesParser{
+		decapsulator: &EthernetDecapsulator{},
+		frameDecoder: NewFrameDecoder(directionClassifier),
+	}
+	dataAggregator := NewDataAggregator()
+
+	validFrame := capturedFrame{data: buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_TCP), buildTcpHeader(443, 50000)), timestamp: time.Now()}
+	if result := framesParser.processFrame(&validFrame, dataAggregator); result != FRAME_PROCESSED {
+		t.Errorf("Expected processed frame; given result: %d", result)
+	}
+	shortFrame := capturedFrame{data: []byte{0x00, 0x01}, timestamp: time.Now()}
+	if result := framesParser.processFrame(&shortFrame, dataAggregator); result != FRAME_NOT_ENCAPSULATED {
+		t.Errorf("Expected non-encapsulated frame; given result: %d", result)
+	}
+
+	t.Log("Malformed TZSP datagram ...")
+	framesParser.decapsulator = &TzspDecapsulator{}
+	malformedFrame := capturedFrame{data: buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4),
+		buildIpv4Header(PROTOCOL_UDP), []byte{0x90, 0x90, 0x90, 0x90, 0x00, 0x10, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00})}
+	if result := framesParser.processFrame(&malformedFrame, dataAggregator); result != FRAME_MALFORMED {
+		t.Errorf("Expected malformed frame; given result: %d", result)
+	}
+
+	t.Log("Recovering of runtime error during unwrapping ...")
+	framesParser.decapsulator = &corruptedDecapsulatorStub{}
+	if result := framesParser.processFrame(&validFrame, dataAggregator); result != FRAME_PARSE_ERROR {
+		t.Errorf("Expected parse error; given result: %d", result)
+	}
+	if len(*dataAggregator.BuildSlice()) != 1 {
+		t.Errorf("Only the valid frame should be aggregated: %+v", *dataAggregator.BuildSlice())
+	}
+}
+
+// Decapsulator that fails on runtime error as if it read beyond corrupted header.
+type corruptedDecapsulatorStub struct {}
+
+func (corruptedDecapsulatorStub *corruptedDecapsulatorStub) CaptureFilter() string {
+	return ""
+}
+
+func (corruptedDecapsulatorStub *corruptedDecapsulatorStub) Unwrap(frame *[]byte) *[]byte {
+	panic("index out of range of corrupted header")
+}
+
+// Decapsulator of unencapsulated frames that reports the same sensor timestamp for all frames.
+// Attribute timestamp uint32 - reported sensor timestamp.
+type timestampDecapsulatorStub struct {
+	timestamp	uint32
+}
+
+func (timestampDecapsulatorStub *timestampDecapsulatorStub) CaptureFilter() string {
+	return ""
+}
+
+func (timestampDecapsulatorStub *timestampDecapsulatorStub) Unwrap(frame *[]byte) *[]byte {
+	return frame
+}
+
+func (timestampDecapsulatorStub *timestampDecapsulatorStub) UnwrapWithHeader(frame *[]byte) (*[]byte, *TzspHeader,
+	error) {
+	return frame, &TzspHeader{Timestamp: timestampDecapsulatorStub.timestamp, TimestampPresent: true}, nil
+}
+
+// Unit test - capture timestamps and sensor timestamps are passed to aggregated entries.
+// Parameter t *testing.T - testing engine.
+func TestProcessFrameTimestamps(t *testing.T) {
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	decapsulator := &timestampDecapsulatorStub{}
+	framesParser := FramesParser{
+		decapsulator: decapsulator,
+		frameDecoder: NewFrameDecoder(directionClassifier),
+	}
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	captureTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Log("Capture timestamps ...")
+	frames := []capturedFrame{
+		{data: frame, timestamp: captureTime.Add(time.Second)},
+		{data: frame, timestamp: captureTime},
+		{data: frame, timestamp: captureTime.Add(500 * time.Millisecond)},
+	}
+	entries := *framesParser.processFramesBucket(frames).BuildSlice()
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	if len(entries) != 2 {
+		t.Fatalf("Expected one entry per second of capture time; given entries: %+v", entries)
+	}
+	if entries[0].Packets != 2 || !entries[0].Time.Equal(captureTime.Add(500 * time.Millisecond)) {
+		t.Errorf("Expected two frames at the latest capture time of the first second; given entry: %+v",
+			*entries[0])
+	}
+	if entries[1].Packets != 1 || !entries[1].Time.Equal(captureTime.Add(time.Second)) {
+		t.Errorf("Expected one frame at the capture time of the second second; given entry: %+v", *entries[1])
+	}
+
+	t.Log("Sensor timestamps ...")
+	framesParser.sensorClock = newSensorClock()
+	decapsulator.timestamp = 100
+	framesParser.processFramesBucket([]capturedFrame{{data: frame, timestamp: captureTime}})
+	decapsulator.timestamp = 500
+	entries = *framesParser.processFramesBucket([]capturedFrame{{data: frame,
+		timestamp: captureTime.Add(10 * time.Millisecond)}}).BuildSlice()
+	expectedTime := captureTime.Add(400 * time.Microsecond)
+	if len(entries) != 1 || !entries[0].Time.Equal(expectedTime) {
+		t.Errorf("Expected one entry at %v; given entries: %+v", expectedTime, entries)
+	}
+}
+
+// Unit test - truncated frames are accounted by their original length.
+// Parameter t *testing.T - testing engine.
+func TestProcessFrameOriginalLength(t *testing.T) {
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	framesParser := FramesParser{
+		decapsulator: &EthernetDecapsulator{},
+		frameDecoder: NewFrameDecoder(directionClassifier),
+	}
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	frameLength := uint(len(frame))
+	tzspHeader := []byte{0x01, 0x00, 0x00, 0x01}
+
+	tests := []struct {
+		name			string
+		decapsulator	FrameDecapsulator
+		frame			capturedFrame
+		expected		uint64
+	}{
+		{"complete frame", &EthernetDecapsulator{}, capturedFrame{data: frame, length: frameLength},
+			uint64(frameLength)},
+		{"unknown length", &EthernetDecapsulator{}, capturedFrame{data: frame}, uint64(frameLength)},
+		{"snapshot length", &EthernetDecapsulator{}, capturedFrame{data: frame, length: 1500}, 1500},
+		{"TZSP original length", &TzspDecapsulator{}, capturedFrame{data: buildFrame(
+			buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_UDP),
+			[]byte{0x90, 0x90, 0x90, 0x90, 0x00, 0x10, 0x00, 0x00}, tzspHeader,
+			[]byte{TAG_TYPE_ORIGINAL_LENGTH, 0x02, 0x05, 0xdc, TAG_TYPE_END}, frame)}, 1500},
+		{"TZSP without original length", &TzspDecapsulator{}, capturedFrame{data: buildFrame(
+			buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_UDP),
+			[]byte{0x90, 0x90, 0x90, 0x90, 0x00, 0x10, 0x00, 0x00}, tzspHeader, []byte{TAG_TYPE_END}, frame)},
+			uint64(frameLength)},
+	}
+	for _, test := range tests {
+		framesParser.decapsulator = test.decapsulator
+		dataAggregator := NewDataAggregator()
+		if result := framesParser.processFrame(&test.frame, dataAggregator); result != FRAME_PROCESSED {
+			t.Errorf("%s: expected processed frame; given result: %d", test.name, result)
+			continue
+		}
+		entries := *dataAggregator.BuildSlice()
+		if len(entries) != 1 {
+			t.Errorf("%s: expected one entry; given entries: %d", test.name, len(entries))
+		} else if entries[0].Bytes != test.expected {
+			t.Errorf("%s: expected bytes: %d; given bytes: %d", test.name, test.expected, entries[0].Bytes)
+		}
+	}
+}
+
+// Unit test - conversion of sensor timestamps including re-anchoring of the clock.
+// Parameter t *testing.T - testing engine.
+func TestSensorClock(t *testing.T) {
+	clock := newSensorClock()
+	captureTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name		string
+		value		uint32
+		captureTime	time.Time
+		expected	time.Time
+	}{
+		{"anchor", 1000, captureTime, captureTime},
+		{"following frame", 3000, captureTime.Add(time.Millisecond), captureTime.Add(2 * time.Millisecond)},
+		{"reordered frame", 500, captureTime.Add(time.Millisecond), captureTime.Add(-500 * time.Microsecond)},
+		{"wrap-around", 0xffffffff, captureTime, captureTime.Add(-1001 * time.Microsecond)},
+		{"sensor restart", 10, captureTime.Add(time.Hour), captureTime.Add(time.Hour)},
+		{"after restart", 20, captureTime.Add(time.Hour), captureTime.Add(time.Hour + 10 * time.Microsecond)},
+	}
+	for _, test := range tests {
+		converted := clock.convert(test.value, test.captureTime)
+		if !converted.Equal(test.expected) {
+			t.Errorf("%s: expected time: %v; given time: %v", test.name, test.expected, converted)
+		}
+	}
+}
+
+// Unit test - capture timestamps of replayed files are rebased to the replay start with recorded gaps at every speed.
+// Parameter t *testing.T - testing engine.
+func TestReplayPacerTimestamps(t *testing.T) {
+	recordedTime := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	pacer := replayPacer{speed: 0}
+	start := pacer.waitForFrame(recordedTime)
+	replayed := pacer.waitForFrame(recordedTime.Add(time.Minute))
+	if replayed.Sub(start) != time.Minute {
+		t.Errorf("Expected recorded gap of 1m; given gap: %v", replayed.Sub(start))
+	}
+	pacer = replayPacer{speed: 1000}
+	start = pacer.waitForFrame(recordedTime)
+	replayed = pacer.waitForFrame(recordedTime.Add(time.Second))
+	if replayed.Sub(start) != time.Second {
+		t.Errorf("Expected recorded gap of 1s at speed 1000; given gap: %v", replayed.Sub(start))
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond || elapsed > 500 * time.Millisecond {
+		t.Errorf("Expected waiting of about 1ms at speed 1000; given waiting: %v", elapsed)
+	}
+}
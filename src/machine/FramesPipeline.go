@@ -0,0 +1,248 @@
+package machine
+
+import (
+	"model"
+	"configuration"
+	"fmt"
+	"sync"
+)
+
+// Policies that are applied by the capture stage when the queue of frames buckets is full.
+// The capture stage waits until some parse worker is free (frames are buffered and dropped by kernel).
+const DROP_POLICY_BLOCK = "block"
+// The submitted (newest) bucket is dropped.
+const DROP_POLICY_NEWEST = "drop-newest"
+// The oldest queued bucket is dropped and the submitted bucket is queued instead.
+const DROP_POLICY_OLDEST = "drop-oldest"
+// Default number of parse workers.
+const PARSE_WORKERS_DEFAULT = uint(2)
+// Default capacity of the queue of frames buckets.
+const PIPELINE_CAPACITY_DEFAULT = uint(4)
+// Default budget of captured frames in the pipeline [MiB].
+const PIPELINE_MEMORY_DEFAULT = uint(256)
+// Maximum number of aggregated entries that are written to the database in one batch.
+const WRITER_BATCH_LIMIT = 20000
+
+// Frames captured on one link during one tick (or until the bucket is full).
+// Attribute frames []capturedFrame - captured frames with their timestamps. See capturedFrame.
+// Attribute framesParser *FramesParser - parser of the link that unwraps and decodes the frames. See FramesParser.
+// Attribute size uint64 - captured bytes of the frames (reserved from the memory budget of the pipeline).
+type FramesBucket struct {
+	frames			[]capturedFrame
+	framesParser	*FramesParser
+	size			uint64
+}
+
+// Bounded frame-processing pipeline shared by all frames parsers: capture stages submit frames buckets into bounded
+// queue, fixed pool of parse workers aggregates them, and single writer writes batches of aggregated entries to
+// the database.
+// Attribute statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
+// Attribute captureStatistics *CaptureStatistics - health counters (dropped frames). See CaptureStatistics.
+// Attribute dropPolicy string - policy applied when the bucket queue is full (block, drop-newest, drop-oldest).
+// Attribute parseWorkers uint - number of parse workers.
+// Attribute bucketFrames uint - maximum number of frames in one frames bucket.
+// Attribute maximumFrameSize uint - maximum size of the captured frame [bytes].
+// Attribute memoryLimit uint64 - budget of captured bytes of queued and parsed buckets.
+// Attribute memoryUsed uint64 - captured bytes of queued and parsed buckets.
+// Attribute memoryMutex *sync.Mutex - semaphore that controls access to memoryUsed. See sync.Mutex.
+// Attribute memoryReleased *sync.Cond - signalisation of released memory to blocked capture stages. See sync.Cond.
+// Attribute bucketQueue chan *FramesBucket - bounded queue between capture stages and parse workers.
+// Attribute entriesQueue chan *[](*model.RawData) - bounded queue between parse workers and the writer.
+type FramesPipeline struct {
+	statisticalData		model.Storage
+	captureStatistics	*CaptureStatistics
+	dropPolicy			string
+	parseWorkers		uint
+	bucketFrames		uint
+	maximumFrameSize	uint
+	memoryLimit			uint64
+	memoryUsed			uint64
+	memoryMutex			*sync.Mutex
+	memoryReleased		*sync.Cond
+	bucketQueue			chan *FramesBucket
+	entriesQueue		chan *[](*model.RawData)
+}
+
+// Creating instance of the FramesPipeline.
+// Parameter conf *model.NetworkConfiguration - network configuration settings (workers, capacity, memory budget, and
+// drop policy). See model.NetworkConfiguration.
+// Parameter statisticalData model.Storage - storage of data types and data entries.
+// See model.Storage.
+// Parameter captureStatistics *CaptureStatistics - health counters (dropped frames). See CaptureStatistics.
+// Returning *FramesPipeline - FramesPipeline object.
+// Returning error - unknown drop policy or too large frames buckets.
+func NewFramesPipeline(conf *model.NetworkConfiguration, statisticalData model.Storage,
+	captureStatistics *CaptureStatistics) (*FramesPipeline, error) {
+	compositeError := configuration.NewCompositeError()
+	dropPolicy := conf.DropPolicy
+	switch dropPolicy {
+	case "":
+		dropPolicy = DROP_POLICY_BLOCK
+	case DROP_POLICY_BLOCK, DROP_POLICY_NEWEST, DROP_POLICY_OLDEST:
+	default:
+		compositeError.AddError(1, fmt.Sprintf("unknown drop policy: %s: supported policies are %s, %s, and %s",
+			dropPolicy, DROP_POLICY_BLOCK, DROP_POLICY_NEWEST, DROP_POLICY_OLDEST))
+	}
+	bucketFrames := conf.BucketFrames
+	if bucketFrames == 0 {
+		bucketFrames = BUFFER_MAX_SIZE
+	} else if bucketFrames > BUFFER_MAX_SIZE {
+		compositeError.AddError(2, fmt.Sprintf("too many frames in one bucket: %d: the maximum is %d frames",
+			bucketFrames, BUFFER_MAX_SIZE))
+	}
+	if err := compositeError.Evaluate(); err != nil {
+		return nil, err
+	}
+	parseWorkers := conf.ParseWorkers
+	if parseWorkers == 0 {
+		parseWorkers = PARSE_WORKERS_DEFAULT
+	}
+	capacity := conf.PipelineCapacity
+	if capacity == 0 {
+		capacity = PIPELINE_CAPACITY_DEFAULT
+	}
+	memoryLimit := conf.PipelineMemory
+	if memoryLimit == 0 {
+		memoryLimit = PIPELINE_MEMORY_DEFAULT
+	}
+	memoryMutex := &sync.Mutex{}
+	framesPipeline := FramesPipeline{
+		statisticalData: statisticalData,
+		captureStatistics: captureStatistics,
+		dropPolicy: dropPolicy,
+		parseWorkers: parseWorkers,
+		bucketFrames: bucketFrames,
+		maximumFrameSize: conf.MaximumFrameSize,
+		memoryLimit: uint64(memoryLimit) << 20,
+		memoryMutex: memoryMutex,
+		memoryReleased: sync.NewCond(memoryMutex),
+		bucketQueue: make(chan *FramesBucket, capacity),
+		entriesQueue: make(chan *[](*model.RawData), parseWorkers),
+	}
+	return &framesPipeline, nil
+}
+
+// Starting of parse workers and the writer. Worst-case memory of captured frames is the budget of the pipeline and
+// one bucket of full-size frames per capture stage.
+func (FramesPipeline *FramesPipeline) StartPipeline() {
+	configuration.Info.Printf("Starting of frames pipeline (parse workers: %d, queue capacity: %d, drop policy: %s).",
+		FramesPipeline.parseWorkers, cap(FramesPipeline.bucketQueue), FramesPipeline.dropPolicy)
+	configuration.Info.Printf("Worst-case memory of captured frames: %d MiB in the pipeline and %d MiB per capture " +
+		"source (%d frames of %d bytes in one bucket).", FramesPipeline.memoryLimit >> 20,
+		(uint64(FramesPipeline.bucketFrames) * uint64(FramesPipeline.maximumFrameSize)) >> 20,
+		FramesPipeline.bucketFrames, FramesPipeline.maximumFrameSize)
+	for i := uint(0); i < FramesPipeline.parseWorkers; i++ {
+		go FramesPipeline.parseBuckets()
+	}
+	go FramesPipeline.writeEntries()
+}
+
+// Submitting of captured frames bucket to parse workers; the drop policy is applied if the queue is full or if the
+// memory budget is exhausted.
+// Parameter bucket *FramesBucket - captured frames. See FramesBucket.
+// Parameter block bool - the capture stage waits for free queue slot and memory regardless of drop policy (replayed
+// files).
+func (FramesPipeline *FramesPipeline) Submit(bucket *FramesBucket, block bool) {
+	bucket.size = 0
+	for _, frame := range bucket.frames {
+		bucket.size += uint64(len(frame.data))
+	}
+	if block || FramesPipeline.dropPolicy == DROP_POLICY_BLOCK {
+		FramesPipeline.memoryMutex.Lock()
+		for !FramesPipeline.reserveMemory(bucket.size) {
+			FramesPipeline.memoryReleased.Wait()
+		}
+		FramesPipeline.memoryMutex.Unlock()
+		FramesPipeline.bucketQueue <- bucket
+		return
+	}
+	for {
+		FramesPipeline.memoryMutex.Lock()
+		reserved := FramesPipeline.reserveMemory(bucket.size)
+		FramesPipeline.memoryMutex.Unlock()
+		if reserved {
+			select {
+			case FramesPipeline.bucketQueue <- bucket:
+				return
+			default:
+				FramesPipeline.releaseMemory(bucket.size)
+			}
+		}
+		if FramesPipeline.dropPolicy == DROP_POLICY_NEWEST {
+			FramesPipeline.dropBucket(bucket)
+			return
+		}
+		// drop-oldest: one slot and its memory are released (it may be taken by parse worker meanwhile)
+		select {
+		case oldestBucket := <-FramesPipeline.bucketQueue:
+			FramesPipeline.releaseMemory(oldestBucket.size)
+			FramesPipeline.dropBucket(oldestBucket)
+		default:
+			// the budget is held by parsed buckets only - there is no older bucket to drop
+			if !reserved {
+				FramesPipeline.dropBucket(bucket)
+				return
+			}
+		}
+	}
+}
+
+// Reserving of memory of the bucket within the budget; the bucket is always accepted by the empty pipeline (single
+// bucket may be larger than the budget). The caller must hold memoryMutex.
+// Parameter size uint64 - captured bytes of the bucket.
+// Returning bool - the memory is reserved.
+func (FramesPipeline *FramesPipeline) reserveMemory(size uint64) bool {
+	if FramesPipeline.memoryUsed != 0 && FramesPipeline.memoryUsed + size > FramesPipeline.memoryLimit {
+		return false
+	}
+	FramesPipeline.memoryUsed += size
+	return true
+}
+
+// Releasing of memory of the parsed or dropped bucket; blocked capture stages are woken up.
+// Parameter size uint64 - captured bytes of the bucket.
+func (FramesPipeline *FramesPipeline) releaseMemory(size uint64) {
+	FramesPipeline.memoryMutex.Lock()
+	FramesPipeline.memoryUsed -= size
+	FramesPipeline.memoryMutex.Unlock()
+	FramesPipeline.memoryReleased.Broadcast()
+}
+
+// Dropping of frames bucket that doesn't fit into the queue.
+// Parameter bucket *FramesBucket - dropped frames. See FramesBucket.
+func (FramesPipeline *FramesPipeline) dropBucket(bucket *FramesBucket) {
+	interfaceName := bucket.framesParser.captureSource.Name
+	FramesPipeline.captureStatistics.AddPipelineDrops(interfaceName, uint64(len(bucket.frames)))
+	configuration.Warning.Printf("Frames pipeline is full - %d frames of %s have been dropped.",
+		len(bucket.frames), interfaceName)
+}
+
+// Parse worker - aggregation of queued frames buckets and passing of aggregated entries to the writer.
+func (FramesPipeline *FramesPipeline) parseBuckets() {
+	for bucket := range FramesPipeline.bucketQueue {
+		dataAggregator := bucket.framesParser.processFramesBucket(bucket.frames)
+		FramesPipeline.releaseMemory(bucket.size)
+		if !dataAggregator.IsEmpty() {
+			FramesPipeline.entriesQueue <- dataAggregator.BuildSlice()
+		}
+	}
+}
+
+// Writer - writing of aggregated entries to the database; entries that are already waiting in the queue are merged
+// into one batch (one transaction).
+func (FramesPipeline *FramesPipeline) writeEntries() {
+	for entries := range FramesPipeline.entriesQueue {
+		batch := *entries
+		merging := true
+		for merging && len(batch) < WRITER_BATCH_LIMIT {
+			select {
+			case nextEntries := <-FramesPipeline.entriesQueue:
+				batch = append(batch, *nextEntries...)
+			default:
+				merging = false
+			}
+		}
+		FramesPipeline.statisticalData.WriteNewDataEntries(&batch)
+	}
+}
@@ -0,0 +1,117 @@
+package machine
+
+import (
+	"testing"
+	"model"
+)
+
+// Building of the pipeline whose workers are not started (queued buckets stay in the queue).
+// Parameter dropPolicy string - tested drop policy.
+// Parameter t *testing.T - testing engine.
+// Returning *FramesPipeline - tested pipeline with capacity of two buckets.
+// Returning *CaptureStatistics - counters of dropped frames.
+func buildStoppedPipeline(dropPolicy string, t *testing.T) (*FramesPipeline, *CaptureStatistics) {
+	captureStatistics := NewCaptureStatistics()
+	framesPipeline, err := NewFramesPipeline(&model.NetworkConfiguration{PipelineCapacity: 2, DropPolicy: dropPolicy},
+		nil, captureStatistics)
+	if err != nil {
+		t.Fatalf("Pipeline cannot be built: %v", err)
+	}
+	return framesPipeline, captureStatistics
+}
+
+// Building of frames bucket with the selected number of frames.
+// Parameter framesParser *FramesParser - parser of the bucket.
+// Parameter size int - number of frames.
+// Returning *FramesBucket - built bucket.
+func buildBucket(framesParser *FramesParser, size int) *FramesBucket {
+	return &FramesBucket{frames: make([]capturedFrame, size), framesParser: framesParser}
+}
+
+// Unit test - drop policies of the full pipeline.
+// Parameter t *testing.T - testing engine.
+func TestFramesPipelineDropPolicies(t *testing.T) {
+	framesParser := &FramesParser{captureSource: model.CaptureSource{Name: "eth1"}}
+
+	t.Log("Dropping of the newest bucket ...")
+	framesPipeline, captureStatistics := buildStoppedPipeline(DROP_POLICY_NEWEST, t)
+	first := buildBucket(framesParser, 1)
+	framesPipeline.Submit(first, false)
+	framesPipeline.Submit(buildBucket(framesParser, 2), false)
+	framesPipeline.Submit(buildBucket(framesParser, 3), false)
+	if drops := captureStatistics.ListCounters()[0].PipelineDrops; drops != 3 {
+		t.Errorf("Expected dropped frames: 3; given dropped frames: %d", drops)
+	}
+	if <-framesPipeline.bucketQueue != first {
+		t.Errorf("The oldest bucket should stay in the queue.")
+	}
+
+	t.Log("Dropping of the oldest bucket ...")
+	framesPipeline, captureStatistics = buildStoppedPipeline(DROP_POLICY_OLDEST, t)
+	framesPipeline.Submit(first, false)
+	framesPipeline.Submit(buildBucket(framesParser, 2), false)
+	newest := buildBucket(framesParser, 3)
+	framesPipeline.Submit(newest, false)
+	if drops := captureStatistics.ListCounters()[0].PipelineDrops; drops != 1 {
+		t.Errorf("Expected dropped frames: 1; given dropped frames: %d", drops)
+	}
+	<-framesPipeline.bucketQueue
+	if <-framesPipeline.bucketQueue != newest {
+		t.Errorf("The newest bucket should be queued.")
+	}
+}
+
+// Unit test - validation of the drop policy.
+// Parameter t *testing.T - testing engine.
+func TestFramesPipelineUnknownPolicy(t *testing.T) {
+	_, err := NewFramesPipeline(&model.NetworkConfiguration{DropPolicy: "drop-all"}, nil, NewCaptureStatistics())
+	if err == nil {
+		t.Errorf("Unknown drop policy should be rejected.")
+	}
+}
+
+// Unit test - buckets are dropped once the memory budget of queued frames is exhausted.
+// Parameter t *testing.T - testing engine.
+func TestFramesPipelineMemoryBudget(t *testing.T) {
+	framesParser := &FramesParser{captureSource: model.CaptureSource{Name: "eth1"}}
+	captureStatistics := NewCaptureStatistics()
+	framesPipeline, err := NewFramesPipeline(&model.NetworkConfiguration{PipelineCapacity: 4, PipelineMemory: 1,
+		DropPolicy: DROP_POLICY_NEWEST}, nil, captureStatistics)
+	if err != nil {
+		t.Fatalf("Pipeline cannot be built: %v", err)
+	}
+	// 300 KiB per bucket - the third bucket exceeds 1 MiB budget although the queue has free slots
+	buildLargeBucket := func() *FramesBucket {
+		bucket := buildBucket(framesParser, 3)
+		for i := range bucket.frames {
+			bucket.frames[i].data = make([]byte, 100 << 10)
+		}
+		return bucket
+	}
+	for i := 0; i < 4; i++ {
+		framesPipeline.Submit(buildLargeBucket(), false)
+	}
+	if drops := captureStatistics.ListCounters()[0].PipelineDrops; drops != 3 {
+		t.Errorf("Expected dropped frames: 3; given dropped frames: %d", drops)
+	}
+	if queued := len(framesPipeline.bucketQueue); queued != 3 {
+		t.Errorf("Expected queued buckets: 3; given queued buckets: %d", queued)
+	}
+
+	t.Log("Releasing of memory of the parsed bucket ...")
+	framesPipeline.releaseMemory((<-framesPipeline.bucketQueue).size)
+	framesPipeline.Submit(buildLargeBucket(), false)
+	if queued := len(framesPipeline.bucketQueue); queued != 3 {
+		t.Errorf("Expected queued buckets: 3; given queued buckets: %d", queued)
+	}
+}
+
+// Unit test - validation of the number of frames in one bucket.
+// Parameter t *testing.T - testing engine.
+func TestFramesPipelineBucketFrames(t *testing.T) {
+	_, err := NewFramesPipeline(&model.NetworkConfiguration{BucketFrames: BUFFER_MAX_SIZE + 1}, nil,
+		NewCaptureStatistics())
+	if err == nil {
+		t.Errorf("Too large frames buckets should be rejected.")
+	}
+}
@@ -14,37 +14,46 @@ const THREAD_SLEEPING_DELAY = 50
 // model.LoadAnalyserConfiguration.
 // Attribute deviceManager *DeviceManager - device manager that is notified when a new real-time load is computed.
 // See DeviceManager.
-// Attribute statisticalData *model.StatisticalData - source of captured statistical data. See model.StatisticalData.
+// Attribute statisticalData model.Storage - source of captured statistical data. See model.Storage.
 // Attribute smoothingCreator *SmoothingCreator - tools that are used for performing of smoothing over defined range.
 // See SmoothingCreator.
+// Attribute captureSources []model.CaptureSource - analysed links. See model.CaptureSource.
+// Attribute directions []uint - analysed traffic directions. See model.NetworkConfiguration.ListDirections.
 type LoadAnalyser struct {
 	configuration		*model.LoadAnalyserConfiguration
 	deviceManager		*DeviceManager
-	statisticalData 	*model.StatisticalData
+	statisticalData 	model.Storage
 	smoothingCreator	*SmoothingCreator
+	captureSources		[]model.CaptureSource
+	directions			[]uint
 }
 
 // Creating of the instance of LoadAnalyser structure.
 // Parameter configuration *model.LoadAnalyserConfiguration - computation interval and depth.
 // Parameter deviceManager *DeviceManager - device manager that is notified when a new real-time load is computed.
 // See DeviceManager.
-// Parameter statisticalData *model.StatisticalData - source of captured statistical data. See model.StatisticalData.
+// Parameter statisticalData model.Storage - source of captured statistical data. See model.Storage.
 // Parameter smoothingCreator *SmoothingCreator - tools that are used for performing of smoothing over defined range.
 // See SmoothingCreator.
+// Parameter captureSources []model.CaptureSource - analysed links. See model.CaptureSource.
+// Parameter directions []uint - analysed traffic directions. See model.NetworkConfiguration.ListDirections.
 // Returning *LoadAnalyser - reference to created object.
 func NewLoadAnalyser(configuration *model.LoadAnalyserConfiguration, deviceManager *DeviceManager,
-	statisticalData *model.StatisticalData, smoothingCreator *SmoothingCreator) *LoadAnalyser {
+	statisticalData model.Storage, smoothingCreator *SmoothingCreator, captureSources []model.CaptureSource,
+	directions []uint) *LoadAnalyser {
 	realTimeLoader := LoadAnalyser{
 		statisticalData: statisticalData,
 		smoothingCreator: smoothingCreator,
 		deviceManager: deviceManager,
 		configuration: configuration,
+		directions: directions,
+		captureSources: captureSources,
 	}
 	return &realTimeLoader
 }
 
-// Starting of periodical computation of load over all configured data types that are stored in database (both RX and
-// TX direction).
+// Starting of periodical computation of load over all configured data types that are stored in database (all links;
+// RX, TX, and optionally internal direction).
 func (RealTimeLoader *LoadAnalyser) StartMachine() {
 	configuration.Info.Println("Starting of the real-time load analyser.")
 	depth := RealTimeLoader.configuration.ComputeDepth
@@ -80,60 +89,56 @@ func (RealTimeLoader *LoadAnalyser) computeAverageLoad(limit *time.Time) {
 // Computation of mean load over last time range - machine that processes one data type.
 // Parameter limit time.Time - time that specidied lower bound of computation interval over which an average is
 // performed. See time.Time.
-// Parameter dataType *model.DataType - Analysed data type for which both RX and TX traffic is processed.
+// Parameter dataType *model.DataType - Analysed data type for which traffic of all links and directions is processed.
 // Parameter waitGroup *sync.WaitGroup - Design pattern of synchronised computation.
 func (RealTimeLoader *LoadAnalyser) workingAverager(dataType *model.DataType, limit *time.Time,
 	waitGroup *sync.WaitGroup) {
 	dataTypeName := dataType.Name
-	// list	data
-	rxData, err01 := RealTimeLoader.statisticalData.ListLastDataEntries(dataTypeName, *limit, uint(0))
-	txData, err02 := RealTimeLoader.statisticalData.ListLastDataEntries(dataTypeName, *limit, uint(1))
-	if err01 == nil && err02 == nil {
-		// smooth data
-		smoothedRxData := RealTimeLoader.smoothingCreator.SmoothData(rxData)
-		smoothedTxData := RealTimeLoader.smoothingCreator.SmoothData(txData)
-		// compute averages
-		rxAverage := averageLoad(smoothedRxData)
-		txAverage := averageLoad(smoothedTxData)
-		// building of output structures
-		loadIdRx := DisplayTemplate{
-			dataTypeId: dataType.ID,
-			dataTypeName: dataType.Name,
-			direction: 0,
-			prediction: false,
-		}
-		loadIdTx := DisplayTemplate{
-			dataTypeId: dataType.ID,
-			dataTypeName: dataType.Name,
-			direction: 1,
-			prediction: false,
+	for _, captureSource := range RealTimeLoader.captureSources {
+		for _, direction := range RealTimeLoader.directions {
+			// list	data
+			data, err := RealTimeLoader.statisticalData.ListLastDataEntries(dataTypeName, *limit, direction,
+				captureSource.Name)
+			if err != nil {
+				configuration.Error.Panicf("An error occurred during fetching of last " +
+					"statistical entries (%s %s): %v", captureSource.Name, directionToString(direction), err)
+			}
+			// smooth data
+			smoothedData := RealTimeLoader.smoothingCreator.SmoothData(data)
+			// compute averages of bytes and frames
+			average, packetsAverage := averageLoad(smoothedData)
+			// building of output structure
+			loadId := DisplayTemplate{
+				dataTypeId: dataType.ID,
+				dataTypeName: dataType.Name,
+				interfaceName: captureSource.Name,
+				direction: direction,
+				prediction: false,
+			}
+			// notify device manager
+			RealTimeLoader.deviceManager.UpdateDisplayByLoad(&loadId, average, packetsAverage)
 		}
-		// notify device manager
-		RealTimeLoader.deviceManager.UpdateDisplayByLoad(&loadIdRx, rxAverage)
-		RealTimeLoader.deviceManager.UpdateDisplayByLoad(&loadIdTx, txAverage)
-	} else if err01 != nil {
-		configuration.Error.Panicf("An error occurred during fetching of last " +
-			"statistical entries (RX): %v", err01)
-	} else {
-		configuration.Error.Panicf("An error occurred during fetching of last " +
-			"statistical entries (TX): %v", err02)
 	}
 	waitGroup.Done()
 }
 
 // Average computation from captured statistics.
 // Parameter data *[](*model.FinalData) - input slice with data from which average is computed.
-// Returning float64 - computed average.
-func averageLoad(data *[](*model.FinalData)) float64 {
+// Returning float64 - computed average of bytes.
+// Returning float64 - computed average of frames.
+func averageLoad(data *[](*model.FinalData)) (float64, float64) {
 	dataRef := *data
 	if len(dataRef) != 0 {
 		var sum uint64 = 0
+		var packetsSum uint64 = 0
 		for i := range dataRef {
 			sum += uint64(dataRef[i].DataElement)
+			packetsSum += dataRef[i].Packets
 		}
 		average := float64(sum) / float64(len(dataRef))
-		return average
+		packetsAverage := float64(packetsSum) / float64(len(dataRef))
+		return average, packetsAverage
 	} else {
-		return float64(0)
+		return float64(0), float64(0)
 	}
 }
\ No newline at end of file
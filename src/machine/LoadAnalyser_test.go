@@ -0,0 +1,46 @@
+package machine
+
+import (
+	"model"
+	"testing"
+	"time"
+)
+
+// Unit test - computation of average loads of all links and directions from in-memory storage.
+// Parameter t *testing.T - testing engine.
+func TestComputeAverageLoad(t *testing.T) {
+	storage := model.NewMemoryStorage(nil)
+	dataType, _ := storage.WriteNewDataType(&model.DataType{Name: "HTTPS", NetworkProtocol: 2048,
+		TransportProtocol: 6, Port: 443})
+	start := time.Now().Add(-5 * time.Second)
+	var rawData [](*model.RawData)
+	for i := 0; i < 4; i++ {
+		rawData = append(rawData, &model.RawData{Bytes: 1000, Packets: 10, Time: start.Add(time.Duration(i) *
+			time.Second), RawDataType: &model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 6,
+			SrcPort: 50000, DstPort: 443, Direction: model.DIRECTION_TX, InterfaceName: "eth1"}})
+	}
+	storage.WriteNewDataEntries(&rawData)
+
+	captureSources := []model.CaptureSource{{Name: "eth1", LinkBandwidth: 1000000}}
+	deviceManager := NewDeviceManager(&model.PHYConfiguration{}, 1000, 0.1, captureSources)
+	// another display is shown, so the LCD and LED strip are not updated
+	deviceManager.actualDisplay = &DisplayTemplate{dataTypeName: "other"}
+	loadAnalyser := NewLoadAnalyser(&model.LoadAnalyserConfiguration{SmoothingRange: 1000}, deviceManager,
+		storage, NewSmoothingCreator(1000, 1), captureSources, []uint{model.DIRECTION_RX, model.DIRECTION_TX})
+	limit := start.Add(-time.Second)
+	loadAnalyser.computeAverageLoad(&limit)
+
+	tx := DisplayTemplate{dataTypeId: dataType.ID, dataTypeName: "HTTPS", interfaceName: "eth1",
+		direction: model.DIRECTION_TX}
+	rx := tx
+	rx.direction = model.DIRECTION_RX
+	if load, present := (*deviceManager.allDisplays)[tx]; !present || load == 0 {
+		t.Errorf("Expected non-zero TX load; given: %v", load)
+	}
+	if packets := (*deviceManager.packetRates)[tx]; packets == 0 {
+		t.Errorf("Expected non-zero TX frames rate; given: %v", packets)
+	}
+	if load, present := (*deviceManager.allDisplays)[rx]; !present || load != 0 {
+		t.Errorf("Expected zero RX load; given: %v (present: %v)", load, present)
+	}
+}
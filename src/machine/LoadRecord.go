@@ -0,0 +1,46 @@
+package machine
+
+// Actual load of one data type on one link and in one direction (exported over REST).
+// Attribute DataTypeId uint - ID of the data type.
+// Attribute DataTypeName string - name of the data type.
+// Attribute InterfaceName string - identifier of the observed link. See model.CaptureSource.
+// Attribute Direction string - RX, TX, or INT.
+// Attribute BytesPerSecond float64 - mean load [bytes/s].
+// Attribute PacketsPerSecond float64 - mean frames rate [frames/s].
+type LoadRecord struct {
+	DataTypeId			uint
+	DataTypeName		string
+	InterfaceName		string
+	Direction			string
+	BytesPerSecond		float64
+	PacketsPerSecond	float64
+}
+
+// Slice with load records that is used for sorting.
+type LoadRecordSlice []LoadRecord
+
+// Method that returns number of records in slice (sort interface). See sort.
+// Returning int - slice length.
+func (s LoadRecordSlice) Len() int {
+	return len(s)
+}
+
+// Swapping of two records in slice (sort interface). See sort.
+// Parameter i int - first record.
+// Parameter j int - second record.
+func (s LoadRecordSlice) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Comparing of two records in slice (sort interface). See sort.
+// Parameter i int - first record.
+// Parameter j int - second record.
+// Returning bool - true if "i" record has precedence over "j" record (name, then interface, then direction).
+func (s LoadRecordSlice) Less(i, j int) bool {
+	if s[i].DataTypeName != s[j].DataTypeName {
+		return s[i].DataTypeName < s[j].DataTypeName
+	} else if s[i].InterfaceName != s[j].InterfaceName {
+		return s[i].InterfaceName < s[j].InterfaceName
+	}
+	return s[i].Direction < s[j].Direction
+}
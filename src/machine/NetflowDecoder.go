@@ -0,0 +1,457 @@
+package machine
+
+import (
+	"net"
+	"fmt"
+	"encoding/binary"
+	"configuration"
+	"time"
+)
+
+// Supported versions of flow export protocols.
+const NETFLOW_V5 = uint16(5)
+const NETFLOW_V9 = uint16(9)
+const IPFIX_VERSION = uint16(10)
+// Lengths of packet headers and fixed records.
+const NETFLOW_V5_HEADER_LENGTH = uint(24)
+const NETFLOW_V5_RECORD_LENGTH = uint(48)
+const NETFLOW_V9_HEADER_LENGTH = uint(20)
+const IPFIX_HEADER_LENGTH = uint(16)
+const FLOW_SET_HEADER_LENGTH = uint(4)
+// Identifiers of flow sets (sets in IPFIX terminology).
+const NETFLOW_V9_TEMPLATE_SET = uint16(0)
+const NETFLOW_V9_OPTIONS_TEMPLATE_SET = uint16(1)
+const IPFIX_TEMPLATE_SET = uint16(2)
+const IPFIX_OPTIONS_TEMPLATE_SET = uint16(3)
+const FLOW_MINIMUM_DATA_SET = uint16(256)
+// IPFIX field length that signalises variable-length encoding.
+const IPFIX_VARIABLE_LENGTH = uint16(65535)
+// IPFIX bit that signalises enterprise-specific information element.
+const IPFIX_ENTERPRISE_BIT = uint16(0x8000)
+// Mask of sampling interval in NetFlow v5 header.
+const NETFLOW_V5_SAMPLING_MASK = uint16(0x3fff)
+// Information elements (field types) shared by NetFlow v9 and IPFIX.
+const FIELD_BYTES = uint16(1)
+const FIELD_PACKETS = uint16(2)
+const FIELD_PROTOCOL = uint16(4)
+const FIELD_SRC_PORT = uint16(7)
+const FIELD_IPV4_SRC_ADDR = uint16(8)
+const FIELD_DST_PORT = uint16(11)
+const FIELD_LAST_SWITCHED = uint16(21)
+const FIELD_FIRST_SWITCHED = uint16(22)
+const FIELD_IPV4_DST_ADDR = uint16(12)
+const FIELD_IPV6_SRC_ADDR = uint16(27)
+const FIELD_IPV6_DST_ADDR = uint16(28)
+const FIELD_SAMPLING_INTERVAL = uint16(34)
+const FIELD_SRC_MAC = uint16(56)
+const FIELD_IP_VERSION = uint16(60)
+const FIELD_DIRECTION = uint16(61)
+const FIELD_FLOW_START_SECONDS = uint16(150)
+const FIELD_FLOW_END_SECONDS = uint16(151)
+const FIELD_FLOW_START_MILLISECONDS = uint16(152)
+const FIELD_FLOW_END_MILLISECONDS = uint16(153)
+// Flow direction is not exported.
+const FLOW_DIRECTION_UNKNOWN = -1
+
+// Attribute Bytes uint64 - number of bytes in flow (already multiplied by sampling interval).
+// Attribute Packets uint64 - number of packets in flow (already multiplied by sampling interval).
+// Attribute IpVersion uint8 - IP version (4 or 6).
+// Attribute Protocol uint8 - transport protocol.
+// Attribute SrcPort uint16 - source port.
+// Attribute DstPort uint16 - destination port.
+// Attribute SrcAddress net.IP - source IP address (nil if it is not exported).
+// Attribute DstAddress net.IP - destination IP address (nil if it is not exported).
+// Attribute SrcMac net.HardwareAddr - source MAC address (nil if it is not exported).
+// Attribute FlowDirection int - ingress (0), egress (1), or unknown (-1) direction of flow.
+// Attribute Start time.Time - time of the first packet of flow (zero if it is not exported). See time.Time.
+// Attribute End time.Time - time of the last packet of flow (zero if it is not exported). See time.Time.
+type FlowRecord struct {
+	Bytes			uint64
+	Packets			uint64
+	IpVersion		uint8
+	Protocol		uint8
+	SrcPort			uint16
+	DstPort			uint16
+	SrcAddress		net.IP
+	DstAddress		net.IP
+	SrcMac			net.HardwareAddr
+	FlowDirection	int
+	Start			time.Time
+	End				time.Time
+}
+
+// Attribute fieldType uint16 - information element identifier.
+// Attribute length uint16 - length of field in record (IPFIX_VARIABLE_LENGTH - variable length).
+// Attribute enterprise bool - enterprise-specific information element (it is skipped).
+type templateField struct {
+	fieldType		uint16
+	length			uint16
+	enterprise		bool
+}
+
+// Clock of the exporter that is used for conversion of flow start and end times.
+// Attribute exportTime time.Time - export time from packet header. See time.Time.
+// Attribute sysUptime uint32 - uptime of the exporter at export time [ms] (NetFlow v5 / v9).
+// Attribute uptimePresent bool - switched times of records are relative to the uptime (NetFlow v5 / v9).
+type exportClock struct {
+	exportTime		time.Time
+	sysUptime		uint32
+	uptimePresent	bool
+}
+
+// Conversion of exporter's uptime to absolute time.
+// Parameter uptime uint32 - uptime of the exporter [ms].
+// Returning time.Time - absolute time. See time.Time.
+func (exportClock *exportClock) uptimeToTime(uptime uint32) time.Time {
+	// uptime counter wraps around after 49.7 days
+	return exportClock.exportTime.Add(-time.Duration(int32(exportClock.sysUptime - uptime)) * time.Millisecond)
+}
+
+// Attribute exporter string - address of the exporting device.
+// Attribute domain uint32 - source ID (NetFlow v9) or observation domain ID (IPFIX).
+// Attribute templateId uint16 - identifier of template.
+// Attribute version uint16 - NetFlow / IPFIX version.
+type templateKey struct {
+	exporter		string
+	domain			uint32
+	templateId		uint16
+	version			uint16
+}
+
+// Attribute templates map[templateKey]([]templateField) - received templates of data records.
+type NetflowDecoder struct {
+	templates		map[templateKey]([]templateField)
+}
+
+// Creating instance of the NetflowDecoder.
+// Returning *NetflowDecoder - NetflowDecoder object with empty templates cache.
+func NewNetflowDecoder() *NetflowDecoder {
+	netflowDecoder := NetflowDecoder{
+		templates: make(map[templateKey]([]templateField)),
+	}
+	return &netflowDecoder
+}
+
+// Decoding of flow records from NetFlow v5, NetFlow v9, or IPFIX packet. Templates are remembered per exporter.
+// Parameter exporter string - address of the exporting device.
+// Parameter datagram []byte - received UDP payload.
+// Returning []*FlowRecord - decoded flow records (records of unknown templates are skipped). See FlowRecord.
+// Returning error - malformed packet or unsupported version.
+func (NetflowDecoder *NetflowDecoder) DecodeDatagram(exporter string, datagram []byte) ([]*FlowRecord, error) {
+	if len(datagram) < 2 {
+		return nil, newFlowError("packet is shorter than version field: %d bytes", len(datagram))
+	}
+	version := binary.BigEndian.Uint16(datagram[0:2])
+	switch version {
+	case NETFLOW_V5:
+		return decodeNetflowV5(datagram)
+	case NETFLOW_V9:
+		if uint(len(datagram)) < NETFLOW_V9_HEADER_LENGTH {
+			return nil, newFlowError("NetFlow v9 packet is shorter than header: %d bytes", len(datagram))
+		}
+		sourceId := binary.BigEndian.Uint32(datagram[16:20])
+		clock := exportClock{
+			exportTime: time.Unix(int64(binary.BigEndian.Uint32(datagram[8:12])), 0),
+			sysUptime: binary.BigEndian.Uint32(datagram[4:8]),
+			uptimePresent: true,
+		}
+		return NetflowDecoder.decodeSets(exporter, sourceId, version, &clock, datagram[NETFLOW_V9_HEADER_LENGTH:])
+	case IPFIX_VERSION:
+		if uint(len(datagram)) < IPFIX_HEADER_LENGTH {
+			return nil, newFlowError("IPFIX message is shorter than header: %d bytes", len(datagram))
+		}
+		messageLength := uint(binary.BigEndian.Uint16(datagram[2:4]))
+		if messageLength < IPFIX_HEADER_LENGTH || messageLength > uint(len(datagram)) {
+			return nil, newFlowError("IPFIX message length %d doesn't match received %d bytes",
+				messageLength, len(datagram))
+		}
+		domainId := binary.BigEndian.Uint32(datagram[12:16])
+		clock := exportClock{exportTime: time.Unix(int64(binary.BigEndian.Uint32(datagram[4:8])), 0)}
+		return NetflowDecoder.decodeSets(exporter, domainId, version, &clock,
+			datagram[IPFIX_HEADER_LENGTH:messageLength])
+	default:
+		return nil, newFlowError("unsupported NetFlow / IPFIX version: %d", version)
+	}
+}
+
+// Decoding of NetFlow v5 packet (fixed format of records).
+// Parameter datagram []byte - received UDP payload.
+// Returning []*FlowRecord - decoded flow records. See FlowRecord.
+// Returning error - truncated packet.
+func decodeNetflowV5(datagram []byte) ([]*FlowRecord, error) {
+	length := uint(len(datagram))
+	if length < NETFLOW_V5_HEADER_LENGTH {
+		return nil, newFlowError("NetFlow v5 packet is shorter than header: %d bytes", length)
+	}
+	count := uint(binary.BigEndian.Uint16(datagram[2:4]))
+	if length < NETFLOW_V5_HEADER_LENGTH + count * NETFLOW_V5_RECORD_LENGTH {
+		return nil, newFlowError("NetFlow v5 packet with %d records is truncated: %d bytes", count, length)
+	}
+	samplingInterval := uint64(binary.BigEndian.Uint16(datagram[22:24]) & NETFLOW_V5_SAMPLING_MASK)
+	if samplingInterval == 0 {
+		samplingInterval = 1
+	}
+	clock := exportClock{
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(datagram[8:12])),
+			int64(binary.BigEndian.Uint32(datagram[12:16]))),
+		sysUptime: binary.BigEndian.Uint32(datagram[4:8]),
+		uptimePresent: true,
+	}
+	records := make([]*FlowRecord, 0, count)
+	for i := uint(0); i < count; i++ {
+		record := datagram[NETFLOW_V5_HEADER_LENGTH + i * NETFLOW_V5_RECORD_LENGTH:]
+		records = append(records, &FlowRecord{
+			SrcAddress: net.IP(record[0:4]),
+			DstAddress: net.IP(record[4:8]),
+			Packets: uint64(binary.BigEndian.Uint32(record[16:20])) * samplingInterval,
+			Bytes: uint64(binary.BigEndian.Uint32(record[20:24])) * samplingInterval,
+			Start: clock.uptimeToTime(binary.BigEndian.Uint32(record[24:28])),
+			End: clock.uptimeToTime(binary.BigEndian.Uint32(record[28:32])),
+			SrcPort: binary.BigEndian.Uint16(record[32:34]),
+			DstPort: binary.BigEndian.Uint16(record[34:36]),
+			Protocol: record[38],
+			IpVersion: 4,
+			FlowDirection: FLOW_DIRECTION_UNKNOWN,
+		})
+	}
+	return records, nil
+}
+
+// Decoding of template and data sets (flow sets) of NetFlow v9 packet or IPFIX message.
+// Parameter exporter string - address of the exporting device.
+// Parameter domain uint32 - source ID (NetFlow v9) or observation domain ID (IPFIX).
+// Parameter version uint16 - NetFlow v9 or IPFIX version.
+// Parameter clock *exportClock - clock of the exporter from packet header. See exportClock.
+// Parameter sets []byte - sets that follow after packet header.
+// Returning []*FlowRecord - decoded flow records. See FlowRecord.
+// Returning error - malformed set.
+func (NetflowDecoder *NetflowDecoder) decodeSets(exporter string, domain uint32, version uint16, clock *exportClock,
+	sets []byte) ([]*FlowRecord, error) {
+	var records []*FlowRecord
+	for len(sets) != 0 {
+		if uint(len(sets)) < FLOW_SET_HEADER_LENGTH {
+			// padding at the end of NetFlow v9 packet
+			break
+		}
+		setId := binary.BigEndian.Uint16(sets[0:2])
+		setLength := uint(binary.BigEndian.Uint16(sets[2:4]))
+		if setLength < FLOW_SET_HEADER_LENGTH || setLength > uint(len(sets)) {
+			return records, newFlowError("invalid length of set %d: %d bytes", setId, setLength)
+		}
+		body := sets[FLOW_SET_HEADER_LENGTH:setLength]
+		switch {
+		case (version == NETFLOW_V9 && setId == NETFLOW_V9_TEMPLATE_SET) ||
+			(version == IPFIX_VERSION && setId == IPFIX_TEMPLATE_SET):
+			err := NetflowDecoder.decodeTemplateSet(exporter, domain, version, body)
+			if err != nil {
+				return records, err
+			}
+		case setId >= FLOW_MINIMUM_DATA_SET:
+			key := templateKey{exporter: exporter, domain: domain, templateId: setId, version: version}
+			fields, present := NetflowDecoder.templates[key]
+			if present {
+				records = append(records, decodeDataSet(fields, clock, body)...)
+			}
+		}
+		// options templates and their data are not used
+		sets = sets[setLength:]
+	}
+	return records, nil
+}
+
+// Decoding and remembering of templates from template set.
+// Parameter exporter string - address of the exporting device.
+// Parameter domain uint32 - source ID (NetFlow v9) or observation domain ID (IPFIX).
+// Parameter version uint16 - NetFlow v9 or IPFIX version.
+// Parameter body []byte - template set without set header.
+// Returning error - truncated template.
+func (NetflowDecoder *NetflowDecoder) decodeTemplateSet(exporter string, domain uint32, version uint16,
+	body []byte) error {
+	for uint(len(body)) >= 4 {
+		templateId := binary.BigEndian.Uint16(body[0:2])
+		fieldCount := uint(binary.BigEndian.Uint16(body[2:4]))
+		body = body[4:]
+		fields := make([]templateField, 0, fieldCount)
+		for i := uint(0); i < fieldCount; i++ {
+			if len(body) < 4 {
+				return newFlowError("template %d is truncated", templateId)
+			}
+			field := templateField{
+				fieldType: binary.BigEndian.Uint16(body[0:2]),
+				length: binary.BigEndian.Uint16(body[2:4]),
+			}
+			body = body[4:]
+			if version == IPFIX_VERSION && field.fieldType & IPFIX_ENTERPRISE_BIT != 0 {
+				if len(body) < 4 {
+					return newFlowError("enterprise number of template %d is truncated", templateId)
+				}
+				field.enterprise = true
+				field.fieldType &^= IPFIX_ENTERPRISE_BIT
+				body = body[4:]
+			}
+			fields = append(fields, field)
+		}
+		key := templateKey{exporter: exporter, domain: domain, templateId: templateId, version: version}
+		if fieldCount == 0 {
+			// template withdrawal
+			delete(NetflowDecoder.templates, key)
+		} else {
+			NetflowDecoder.templates[key] = fields
+		}
+	}
+	return nil
+}
+
+// Decoding of data records from data set by using of template.
+// Parameter fields []templateField - template fields.
+// Parameter clock *exportClock - clock of the exporter from packet header. See exportClock.
+// Parameter body []byte - data set without set header.
+// Returning []*FlowRecord - decoded flow records (the last incomplete record or padding is skipped).
+// See FlowRecord.
+func decodeDataSet(fields []templateField, clock *exportClock, body []byte) []*FlowRecord {
+	var records []*FlowRecord
+	for len(body) != 0 {
+		record := FlowRecord{FlowDirection: FLOW_DIRECTION_UNKNOWN}
+		samplingInterval := uint64(1)
+		offset := uint(0)
+		complete := true
+		for _, field := range fields {
+			fieldLength := uint(field.length)
+			if field.length == IPFIX_VARIABLE_LENGTH {
+				if offset + 1 > uint(len(body)) {
+					complete = false
+					break
+				}
+				fieldLength = uint(body[offset])
+				offset++
+				if fieldLength == 255 {
+					if offset + 2 > uint(len(body)) {
+						complete = false
+						break
+					}
+					fieldLength = uint(binary.BigEndian.Uint16(body[offset : offset + 2]))
+					offset += 2
+				}
+			}
+			if offset + fieldLength > uint(len(body)) {
+				complete = false
+				break
+			}
+			value := body[offset : offset + fieldLength]
+			offset += fieldLength
+			if !field.enterprise {
+				applyTemplateField(&record, &samplingInterval, clock, field.fieldType, value)
+			}
+		}
+		if !complete || offset == 0 {
+			break
+		}
+		record.Bytes *= samplingInterval
+		record.Packets *= samplingInterval
+		records = append(records, &record)
+		body = body[offset:]
+	}
+	return records
+}
+
+// Writing of the field value into flow record.
+// Parameter record *FlowRecord - decoded flow record. See FlowRecord.
+// Parameter samplingInterval *uint64 - sampling interval of the record (1 - no sampling).
+// Parameter clock *exportClock - clock of the exporter from packet header. See exportClock.
+// Parameter fieldType uint16 - information element identifier.
+// Parameter value []byte - field value.
+func applyTemplateField(record *FlowRecord, samplingInterval *uint64, clock *exportClock, fieldType uint16,
+	value []byte) {
+	switch fieldType {
+	case FIELD_BYTES:
+		record.Bytes = readUnsigned(value)
+	case FIELD_PACKETS:
+		record.Packets = readUnsigned(value)
+	case FIELD_PROTOCOL:
+		record.Protocol = uint8(readUnsigned(value))
+	case FIELD_SRC_PORT:
+		record.SrcPort = uint16(readUnsigned(value))
+	case FIELD_DST_PORT:
+		record.DstPort = uint16(readUnsigned(value))
+	case FIELD_IPV4_SRC_ADDR, FIELD_IPV6_SRC_ADDR:
+		record.SrcAddress = copyAddress(value)
+		record.IpVersion = ipVersionOfAddress(value, record.IpVersion)
+	case FIELD_IPV4_DST_ADDR, FIELD_IPV6_DST_ADDR:
+		record.DstAddress = copyAddress(value)
+		record.IpVersion = ipVersionOfAddress(value, record.IpVersion)
+	case FIELD_SAMPLING_INTERVAL:
+		interval := readUnsigned(value)
+		if interval != 0 {
+			*samplingInterval = interval
+		}
+	case FIELD_SRC_MAC:
+		if len(value) == 6 {
+			record.SrcMac = net.HardwareAddr(append([]byte(nil), value...))
+		}
+	case FIELD_IP_VERSION:
+		record.IpVersion = uint8(readUnsigned(value))
+	case FIELD_DIRECTION:
+		record.FlowDirection = int(readUnsigned(value))
+	case FIELD_FIRST_SWITCHED:
+		// IPFIX exporters relate switched times to their initialisation time, which is not decoded
+		if clock.uptimePresent {
+			record.Start = clock.uptimeToTime(uint32(readUnsigned(value)))
+		}
+	case FIELD_LAST_SWITCHED:
+		if clock.uptimePresent {
+			record.End = clock.uptimeToTime(uint32(readUnsigned(value)))
+		}
+	case FIELD_FLOW_START_SECONDS:
+		record.Start = time.Unix(int64(readUnsigned(value)), 0)
+	case FIELD_FLOW_END_SECONDS:
+		record.End = time.Unix(int64(readUnsigned(value)), 0)
+	case FIELD_FLOW_START_MILLISECONDS:
+		record.Start = time.Unix(0, 0).Add(time.Duration(readUnsigned(value)) * time.Millisecond)
+	case FIELD_FLOW_END_MILLISECONDS:
+		record.End = time.Unix(0, 0).Add(time.Duration(readUnsigned(value)) * time.Millisecond)
+	}
+}
+
+// Reading of unsigned big-endian integer with reduced-size encoding (1 - 8 bytes).
+// Parameter value []byte - field value.
+// Returning uint64 - read integer.
+func readUnsigned(value []byte) uint64 {
+	result := uint64(0)
+	for _, b := range value {
+		result = result << 8 | uint64(b)
+	}
+	return result
+}
+
+// Copying of IPv4 or IPv6 address from field value.
+// Parameter value []byte - field value.
+// Returning net.IP - copied address or nil if the field doesn't have length of IPv4 or IPv6 address.
+func copyAddress(value []byte) net.IP {
+	if len(value) != net.IPv4len && len(value) != net.IPv6len {
+		return nil
+	}
+	return net.IP(append([]byte(nil), value...))
+}
+
+// Deriving of IP version from length of address field.
+// Parameter value []byte - field value.
+// Parameter actualVersion uint8 - already known IP version (it is kept if the address is not valid).
+// Returning uint8 - IP version.
+func ipVersionOfAddress(value []byte, actualVersion uint8) uint8 {
+	if len(value) == net.IPv4len {
+		return 4
+	} else if len(value) == net.IPv6len {
+		return 6
+	}
+	return actualVersion
+}
+
+// Creating of error that describes malformed flow export packet.
+// Parameter format string - message format.
+// Parameter arguments ...interface{} - message arguments.
+// Returning error - evaluated composite error.
+func newFlowError(format string, arguments ...interface{}) error {
+	compositeError := configuration.NewCompositeError()
+	compositeError.AddError(1, fmt.Sprintf(format, arguments...))
+	return compositeError.Evaluate()
+}
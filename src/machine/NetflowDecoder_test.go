@@ -0,0 +1,254 @@
+package machine
+
+import (
+	"testing"
+	"encoding/binary"
+	"net"
+	"time"
+	"model"
+)
+
+// Building of NetFlow v5 packet with one TCP record.
+func buildNetflowV5Packet(samplingInterval uint16) []byte {
+	packet := make([]byte, NETFLOW_V5_HEADER_LENGTH + NETFLOW_V5_RECORD_LENGTH)
+	binary.BigEndian.PutUint16(packet[0:2], NETFLOW_V5)
+	binary.BigEndian.PutUint16(packet[2:4], 1)
+	binary.BigEndian.PutUint16(packet[22:24], 0x4000 | samplingInterval)
+	record := packet[NETFLOW_V5_HEADER_LENGTH:]
+	copy(record[0:4], []byte{10, 0, 0, 1})
+	copy(record[4:8], []byte{10, 0, 0, 2})
+	binary.BigEndian.PutUint32(record[16:20], 3)
+	binary.BigEndian.PutUint32(record[20:24], 1500)
+	binary.BigEndian.PutUint16(record[32:34], 51000)
+	binary.BigEndian.PutUint16(record[34:36], 443)
+	record[38] = PROTOCOL_TCP
+	return packet
+}
+
+// Building of flow set (NetFlow v9) or set (IPFIX) with header.
+func buildFlowSet(setId uint16, body []byte) []byte {
+	set := make([]byte, FLOW_SET_HEADER_LENGTH)
+	binary.BigEndian.PutUint16(set[0:2], setId)
+	binary.BigEndian.PutUint16(set[2:4], uint16(FLOW_SET_HEADER_LENGTH) + uint16(len(body)))
+	return append(set, body...)
+}
+
+// Building of big-endian field values.
+func uint16Bytes(value uint16) []byte {
+	array := make([]byte, 2)
+	binary.BigEndian.PutUint16(array, value)
+	return array
+}
+
+func uint32Bytes(value uint32) []byte {
+	array := make([]byte, 4)
+	binary.BigEndian.PutUint32(array, value)
+	return array
+}
+
+// Testing of NetFlow v5 decoding - counters are scaled by the sampling interval.
+func TestDecodeNetflowV5(t *testing.T) {
+	records, err := NewNetflowDecoder().DecodeDatagram("192.0.2.1", buildNetflowV5Packet(10))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Decoding of NetFlow v5 packet failed: %v, records: %d", err, len(records))
+	}
+	record := records[0]
+	if record.Bytes != 15000 || record.Packets != 30 || record.Protocol != PROTOCOL_TCP ||
+		record.SrcPort != 51000 || record.DstPort != 443 || record.IpVersion != 4 ||
+		!record.SrcAddress.Equal(net.IPv4(10, 0, 0, 1)) {
+		t.Errorf("Wrongly decoded NetFlow v5 record: %+v", *record)
+	}
+	_, err = NewNetflowDecoder().DecodeDatagram("192.0.2.1", buildNetflowV5Packet(10)[:50])
+	if err == nil {
+		t.Errorf("Truncated NetFlow v5 packet should be rejected")
+	}
+}
+
+// Testing of NetFlow v9 decoding - data set is decoded only after its template arrives from the same exporter.
+func TestDecodeNetflowV9Template(t *testing.T) {
+	header := make([]byte, NETFLOW_V9_HEADER_LENGTH)
+	binary.BigEndian.PutUint16(header[0:2], NETFLOW_V9)
+	binary.BigEndian.PutUint32(header[16:20], 7)
+	var template []byte
+	template = append(template, uint16Bytes(300)...)
+	template = append(template, uint16Bytes(5)...)
+	for _, field := range [][2]uint16{{FIELD_IPV6_SRC_ADDR, 16}, {FIELD_PROTOCOL, 1}, {FIELD_DST_PORT, 2},
+		{FIELD_BYTES, 4}, {FIELD_DIRECTION, 1}} {
+		template = append(template, uint16Bytes(field[0])...)
+		template = append(template, uint16Bytes(field[1])...)
+	}
+	var data []byte
+	data = append(data, net.ParseIP("2001:db8::1")...)
+	data = append(data, PROTOCOL_UDP)
+	data = append(data, uint16Bytes(53)...)
+	data = append(data, uint32Bytes(800)...)
+	data = append(data, 1, 0, 0)
+	dataPacket := append(append([]byte(nil), header...), buildFlowSet(300, data)...)
+	netflowDecoder := NewNetflowDecoder()
+	records, _ := netflowDecoder.DecodeDatagram("192.0.2.1", dataPacket)
+	if len(records) != 0 {
+		t.Fatalf("Data set without template should be skipped, decoded records: %d", len(records))
+	}
+	templatePacket := append(append([]byte(nil), header...), buildFlowSet(NETFLOW_V9_TEMPLATE_SET, template)...)
+	_, err := netflowDecoder.DecodeDatagram("192.0.2.1", templatePacket)
+	if err != nil {
+		t.Fatalf("Decoding of NetFlow v9 template failed: %v", err)
+	}
+	records, _ = netflowDecoder.DecodeDatagram("192.0.2.2", dataPacket)
+	if len(records) != 0 {
+		t.Fatalf("Template of another exporter should not be used, decoded records: %d", len(records))
+	}
+	records, err = netflowDecoder.DecodeDatagram("192.0.2.1", dataPacket)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Decoding of NetFlow v9 data set failed: %v, records: %d", err, len(records))
+	}
+	record := records[0]
+	if record.Bytes != 800 || record.Protocol != PROTOCOL_UDP || record.DstPort != 53 || record.IpVersion != 6 ||
+		record.FlowDirection != 1 {
+		t.Errorf("Wrongly decoded NetFlow v9 record: %+v", *record)
+	}
+}
+
+// Testing of IPFIX decoding - enterprise and variable-length fields are skipped, sampling interval is applied.
+func TestDecodeIpfixVariableLength(t *testing.T) {
+	var template []byte
+	template = append(template, uint16Bytes(400)...)
+	template = append(template, uint16Bytes(5)...)
+	template = append(template, uint16Bytes(FIELD_IPV4_SRC_ADDR)...)
+	template = append(template, uint16Bytes(4)...)
+	template = append(template, uint16Bytes(IPFIX_ENTERPRISE_BIT | 1)...)
+	template = append(template, uint16Bytes(IPFIX_VARIABLE_LENGTH)...)
+	template = append(template, uint32Bytes(9)...)
+	template = append(template, uint16Bytes(FIELD_PROTOCOL)...)
+	template = append(template, uint16Bytes(1)...)
+	template = append(template, uint16Bytes(FIELD_BYTES)...)
+	template = append(template, uint16Bytes(8)...)
+	template = append(template, uint16Bytes(FIELD_SAMPLING_INTERVAL)...)
+	template = append(template, uint16Bytes(2)...)
+	var data []byte
+	for i := 0; i < 2; i++ {
+		data = append(data, 192, 168, 1, byte(i))
+		data = append(data, 3, 'a', 'b', 'c')
+		data = append(data, PROTOCOL_TCP)
+		data = append(data, 0, 0, 0, 0, 0, 0, 0, 100)
+		data = append(data, uint16Bytes(4)...)
+	}
+	message := make([]byte, IPFIX_HEADER_LENGTH)
+	message = append(message, buildFlowSet(IPFIX_TEMPLATE_SET, template)...)
+	message = append(message, buildFlowSet(400, data)...)
+	binary.BigEndian.PutUint16(message[0:2], IPFIX_VERSION)
+	binary.BigEndian.PutUint16(message[2:4], uint16(len(message)))
+	records, err := NewNetflowDecoder().DecodeDatagram("192.0.2.1", message)
+	if err != nil || len(records) != 2 {
+		t.Fatalf("Decoding of IPFIX message failed: %v, records: %d", err, len(records))
+	}
+	for i, record := range records {
+		if record.Bytes != 400 || record.Protocol != PROTOCOL_TCP || record.IpVersion != 4 ||
+			!record.SrcAddress.Equal(net.IPv4(192, 168, 1, byte(i))) {
+			t.Errorf("Wrongly decoded IPFIX record %d: %+v", i, *record)
+		}
+	}
+}
+
+// Testing of flow start and end times - NetFlow v5 switched times are relative to exporter's uptime, IPFIX times are
+// absolute.
+func TestDecodeFlowTimes(t *testing.T) {
+	exportTime := time.Unix(1600000000, 0)
+	packet := buildNetflowV5Packet(1)
+	binary.BigEndian.PutUint32(packet[4:8], 100000)
+	binary.BigEndian.PutUint32(packet[8:12], uint32(exportTime.Unix()))
+	record := packet[NETFLOW_V5_HEADER_LENGTH:]
+	binary.BigEndian.PutUint32(record[24:28], 40000)
+	binary.BigEndian.PutUint32(record[28:32], 90000)
+	records, err := NewNetflowDecoder().DecodeDatagram("192.0.2.1", packet)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Decoding of NetFlow v5 packet failed: %v, records: %d", err, len(records))
+	}
+	if !records[0].Start.Equal(exportTime.Add(-60 * time.Second)) ||
+		!records[0].End.Equal(exportTime.Add(-10 * time.Second)) {
+		t.Errorf("Wrong times of NetFlow v5 record: %v - %v", records[0].Start, records[0].End)
+	}
+
+	var template []byte
+	template = append(template, uint16Bytes(500)...)
+	template = append(template, uint16Bytes(3)...)
+	for _, field := range [][2]uint16{{FIELD_BYTES, 4}, {FIELD_FLOW_START_MILLISECONDS, 8},
+		{FIELD_FLOW_END_MILLISECONDS, 8}} {
+		template = append(template, uint16Bytes(field[0])...)
+		template = append(template, uint16Bytes(field[1])...)
+	}
+	var data []byte
+	data = append(data, uint32Bytes(100)...)
+	for _, flowTime := range []time.Time{exportTime.Add(-5500 * time.Millisecond), exportTime} {
+		milliseconds := make([]byte, 8)
+		binary.BigEndian.PutUint64(milliseconds, uint64(flowTime.UnixNano() / int64(time.Millisecond)))
+		data = append(data, milliseconds...)
+	}
+	message := make([]byte, IPFIX_HEADER_LENGTH)
+	message = append(message, buildFlowSet(IPFIX_TEMPLATE_SET, template)...)
+	message = append(message, buildFlowSet(500, data)...)
+	binary.BigEndian.PutUint16(message[0:2], IPFIX_VERSION)
+	binary.BigEndian.PutUint16(message[2:4], uint16(len(message)))
+	binary.BigEndian.PutUint32(message[4:8], uint32(exportTime.Unix()))
+	records, err = NewNetflowDecoder().DecodeDatagram("192.0.2.1", message)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Decoding of IPFIX message failed: %v, records: %d", err, len(records))
+	}
+	if !records[0].Start.Equal(exportTime.Add(-5500 * time.Millisecond)) || !records[0].End.Equal(exportTime) {
+		t.Errorf("Wrong times of IPFIX record: %v - %v", records[0].Start, records[0].End)
+	}
+}
+
+// Testing of spreading of flow records over seconds between the first and the last packet.
+func TestSpreadFlowRecord(t *testing.T) {
+	receiveTime := time.Unix(1600000000, 0)
+	rawDataType := model.RawDataType{NetworkProtocol: uint(ETHER_TYPE_IPV4)}
+	tests := []struct {
+		name		string
+		record		FlowRecord
+		entries		int
+		first		time.Time
+		last		time.Time
+	}{
+		{"ten seconds", FlowRecord{Bytes: 1000, Packets: 10, Start: receiveTime.Add(-9 * time.Second),
+			End: receiveTime}, 10, receiveTime.Add(-9 * time.Second), receiveTime},
+		{"without times", FlowRecord{Bytes: 1000, Packets: 10}, 1, receiveTime, receiveTime},
+		{"one hour", FlowRecord{Bytes: 3600000, Packets: 3600, Start: receiveTime.Add(-time.Hour),
+			End: receiveTime.Add(-time.Second)}, int(FLOW_SPREAD_PARTS_MAX), receiveTime.Add(-time.Hour),
+			receiveTime.Add(-12 * time.Second)},
+		{"exporter clock ahead", FlowRecord{Bytes: 1000, Packets: 10, Start: receiveTime.Add(time.Minute),
+			End: receiveTime.Add(time.Minute + 4 * time.Second)}, 5, receiveTime.Add(-4 * time.Second),
+			receiveTime},
+	}
+	for _, test := range tests {
+		dataAggregator := NewDataAggregator()
+		spreadFlowRecord(dataAggregator, rawDataType, &test.record, receiveTime)
+		entries := *dataAggregator.BuildSlice()
+		if len(entries) != test.entries {
+			t.Errorf("%s: expected entries: %d; given entries: %d", test.name, test.entries, len(entries))
+			continue
+		}
+		bytes := uint64(0)
+		packets := uint64(0)
+		first := entries[0].Time
+		last := entries[0].Time
+		for _, entry := range entries {
+			bytes += entry.Bytes
+			packets += entry.Packets
+			if entry.Time.Before(first) {
+				first = entry.Time
+			}
+			if entry.Time.After(last) {
+				last = entry.Time
+			}
+		}
+		if bytes != test.record.Bytes || packets != test.record.Packets {
+			t.Errorf("%s: expected %d bytes and %d packets; given %d bytes and %d packets", test.name,
+				test.record.Bytes, test.record.Packets, bytes, packets)
+		}
+		if !first.Equal(test.first) || !last.Equal(test.last) {
+			t.Errorf("%s: expected entries from %v to %v; given entries from %v to %v", test.name, test.first,
+				test.last, first, last)
+		}
+	}
+}
@@ -29,46 +29,53 @@ const CREATE_TS_END = ")"
 // model.LoadAnalyserConfiguration.
 // Attribute deviceManager *DeviceManager - device manager that is notified when a new real-time load is computed.
 // See DeviceManager.
-// Attribute statisticalData *model.StatisticalData - source of captured statistical data. See model.StatisticalData.
+// Attribute statisticalData model.Storage - source of captured statistical data. See model.Storage.
 // Attribute smoothingCreator *SmoothingCreator - tools that are used for performing of smoothing over defined range.
 // See SmoothingCreator.
 // Attribute rServer *configuration.RServer - connection to R statistical server. See configuration.RServer.
-// Attribute linkBandwidth uint64 - observed link bandwidth (maximum load) [bytes/s].
+// Attribute captureSources []model.CaptureSource - analysed links with their bandwidths (maximum load) [bytes/s].
+// See model.CaptureSource.
+// Attribute directions []uint - analysed traffic directions. See model.NetworkConfiguration.ListDirections.
 type PredictionAnalyser struct {
 	configuration		*model.PredictionAnalyserConfiguration
 	deviceManager		*DeviceManager
-	statisticalData 	*model.StatisticalData
+	statisticalData 	model.Storage
 	smoothingCreator	*SmoothingCreator
 	rServer				*configuration.RServer
-	linkBandwidth		uint64
+	captureSources		[]model.CaptureSource
+	directions			[]uint
 }
 
 // Creating of the instance of PredictionAnalyser structure.
 // Parameter configuration *model.PredictionAnalyserConfiguration - computation interval, depth, horizon.
 // Parameter deviceManager *DeviceManager - device manager that is notified when a new real-time load is computed.
 // See DeviceManager.
-// Parameter statisticalData *model.StatisticalData - source of captured statistical data. See model.StatisticalData.
+// Parameter statisticalData model.Storage - source of captured statistical data. See model.Storage.
 // Parameter smoothingCreator *SmoothingCreator - tools that are used for performing of smoothing over defined range.
 // See SmoothingCreator.
 // Parameter rServer *configuration.RServer - connection to R statistical server. See configuration.RServer.
-// Parameter linkBandwidth uint64 - observed link bandwidth (maximum load) [bytes/s].
+// Parameter captureSources []model.CaptureSource - analysed links with their bandwidths (maximum load) [bytes/s].
+// See model.CaptureSource.
+// Parameter directions []uint - analysed traffic directions. See model.NetworkConfiguration.ListDirections.
 // Returning *LoadAnalyser - reference to created object.
 func NewPredictionAnalyser(configuration *model.PredictionAnalyserConfiguration, deviceManager *DeviceManager,
-		statisticalData *model.StatisticalData, smoothingCreator *SmoothingCreator,
-		rServer *configuration.RServer, linkBandwidth uint64) *PredictionAnalyser {
+		statisticalData model.Storage, smoothingCreator *SmoothingCreator,
+		rServer *configuration.RServer, captureSources []model.CaptureSource,
+		directions []uint) *PredictionAnalyser {
 	predictionLoader := PredictionAnalyser{
 		statisticalData: statisticalData,
 		smoothingCreator: smoothingCreator,
 		deviceManager: deviceManager,
 		configuration: configuration,
 		rServer: rServer,
-		linkBandwidth: linkBandwidth,
+		captureSources: captureSources,
+		directions: directions,
 	}
 	return &predictionLoader
 }
 
 // Starting of periodical computation of ARIMA over all data types with enabled prediction that are stored in database
-// (both RX and TX direction).
+// (all links; RX, TX, and optionally internal direction).
 func (PredictionAnalyser *PredictionAnalyser) StartMachine() {
 	configuration.Info.Println("Starting of the predictive load analyser.")
 	depth := PredictionAnalyser.configuration.ComputeDepth
@@ -109,47 +116,39 @@ func (PredictionAnalyser *PredictionAnalyser) computePrediction(limit *time.Time
 func (PredictionAnalyser *PredictionAnalyser) workingMethod(dataType *model.DataType, limit *time.Time,
 	horizonPoints uint, waitGroup *sync.WaitGroup) {
 	if dataType.Forecasting {
-		// list data
-		rxData, err01 := PredictionAnalyser.statisticalData.ListLastDataEntries(dataType.Name, *limit, 0)
-		txData, err02 := PredictionAnalyser.statisticalData.ListLastDataEntries(dataType.Name, *limit, 1)
-		if err01 == nil && err02 == nil {
-			// smooth data
-			smoothedRxData := PredictionAnalyser.smoothingCreator.SmoothData(rxData)
-			smoothedTxData := PredictionAnalyser.smoothingCreator.SmoothData(txData)
-			parallelData := [](*[]uint64){
-				transformFinalDataToUintArray(smoothedRxData),
-				transformFinalDataToUintArray(smoothedTxData),
-			}
-			// compute predictions
-			predictions := parallelArimaComputations(PredictionAnalyser.rServer, &parallelData, horizonPoints)
-			// standardise vectors
-			rxStandardized := standardizeVector(PredictionAnalyser.linkBandwidth, (*predictions)[0])
-			txStandardized := standardizeVector(PredictionAnalyser.linkBandwidth, (*predictions)[1])
-			// compute averages of predictions
-			rxAverage := averagePrediction(rxStandardized)
-			txAverage := averagePrediction(txStandardized)
-			// building of output structures
-			loadIdRx := DisplayTemplate{
-				dataTypeId:   dataType.ID,
-				dataTypeName: dataType.Name,
-				direction:    0,
-				prediction:   true,
-			}
-			loadIdTx := DisplayTemplate{
-				dataTypeId:   dataType.ID,
-				dataTypeName: dataType.Name,
-				direction:    1,
-				prediction:   true,
+		// list and smooth data of all links and directions
+		var parallelData [](*[]uint64)
+		var loadIds []DisplayTemplate
+		var linkBandwidths []uint64
+		for _, captureSource := range PredictionAnalyser.captureSources {
+			for _, direction := range PredictionAnalyser.directions {
+				data, err := PredictionAnalyser.statisticalData.ListLastDataEntries(dataType.Name, *limit,
+					direction, captureSource.Name)
+				if err != nil {
+					configuration.Error.Panicf("An error occurred during fetching of last "+
+						"statistical entries (%s %s): %v", captureSource.Name, directionToString(direction), err)
+				}
+				smoothedData := PredictionAnalyser.smoothingCreator.SmoothData(data)
+				parallelData = append(parallelData, transformFinalDataToUintArray(smoothedData))
+				// building of output structure
+				loadIds = append(loadIds, DisplayTemplate{
+					dataTypeId:    dataType.ID,
+					dataTypeName:  dataType.Name,
+					interfaceName: captureSource.Name,
+					direction:     direction,
+					prediction:    true,
+				})
+				linkBandwidths = append(linkBandwidths, captureSource.LinkBandwidth)
 			}
+		}
+		// compute predictions
+		predictions := parallelArimaComputations(PredictionAnalyser.rServer, &parallelData, horizonPoints)
+		for i := range loadIds {
+			// standardise vector and compute average of prediction
+			standardized := standardizeVector(linkBandwidths[i], (*predictions)[i])
+			average := averagePrediction(standardized)
 			// notify device manager
-			PredictionAnalyser.deviceManager.UpdateDisplayByPrediction(&loadIdRx, rxAverage)
-			PredictionAnalyser.deviceManager.UpdateDisplayByPrediction(&loadIdTx, txAverage)
-		} else if err01 != nil {
-			configuration.Error.Panicf("An error occurred during fetching of last "+
-				"statistical entries (RX): %v", err01)
-		} else {
-			configuration.Error.Panicf("An error occurred during fetching of last "+
-				"statistical entries (TX): %v", err02)
+			PredictionAnalyser.deviceManager.UpdateDisplayByPrediction(&loadIds[i], average)
 		}
 	}
 	waitGroup.Done()
@@ -0,0 +1,68 @@
+package machine
+
+import (
+	"model"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Unit test - predictions of data types without captured history are zero and don't need R session.
+// Parameter t *testing.T - testing engine.
+func TestComputePredictionWithoutHistory(t *testing.T) {
+	storage := model.NewMemoryStorage(nil)
+	forecasted, _ := storage.WriteNewDataType(&model.DataType{Name: "HTTPS", Forecasting: true,
+		NetworkProtocol: 2048, TransportProtocol: 6, Port: 443})
+	storage.WriteNewDataType(&model.DataType{Name: "DNS", NetworkProtocol: 2048, TransportProtocol: 17, Port: 53})
+	captureSources := []model.CaptureSource{{Name: "eth1", LinkBandwidth: 1000000}}
+	deviceManager := NewDeviceManager(&model.PHYConfiguration{}, 1000, 0.1, captureSources)
+	// another display is shown, so the LCD and LED strip are not updated
+	deviceManager.actualDisplay = &DisplayTemplate{dataTypeName: "other"}
+	predictionAnalyser := NewPredictionAnalyser(&model.PredictionAnalyserConfiguration{SmoothingRange: 1000},
+		deviceManager, storage, NewSmoothingCreator(1000, 1), nil, captureSources,
+		[]uint{model.DIRECTION_RX, model.DIRECTION_TX})
+	limit := time.Now()
+	predictionAnalyser.computePrediction(&limit, 5)
+
+	if len(*deviceManager.allDisplays) != 2 {
+		t.Fatalf("Expected two predictions of HTTPS only; given displays: %+v", *deviceManager.allDisplays)
+	}
+	for _, direction := range []uint{model.DIRECTION_RX, model.DIRECTION_TX} {
+		display := DisplayTemplate{dataTypeId: forecasted.ID, dataTypeName: "HTTPS", interfaceName: "eth1",
+			direction: direction, prediction: true}
+		if prediction, present := (*deviceManager.allDisplays)[display]; !present || prediction != 0 {
+			t.Errorf("Expected zero prediction of %s; given: %v (present: %v)", directionToString(direction),
+				prediction, present)
+		}
+	}
+}
+
+// Unit test - building of R vectors and processing of forecasted values.
+// Parameter t *testing.T - testing engine.
+func TestPredictionVectors(t *testing.T) {
+	if vector := uintSliceToRVector(&[]uint64{1, 20, 300}); vector != "c(1,20,300)" {
+		t.Errorf("Expected R vector c(1,20,300); given vector: %s", vector)
+	}
+	var forecastedMean interface{} = []float64{1.5, 2000.9, 40.2}
+	predicted := reflectInterfaceToUintSlice(&forecastedMean)
+	if !reflect.DeepEqual(*predicted, []uint64{1, 2000, 40}) {
+		t.Errorf("Expected truncated predictions [1 2000 40]; given predictions: %v", *predicted)
+	}
+	standardized := standardizeVector(1000, predicted)
+	if !reflect.DeepEqual(*standardized, []uint64{1, 1000, 40}) {
+		t.Errorf("Expected predictions limited by bandwidth [1 1000 40]; given predictions: %v", *standardized)
+	}
+	if average := averagePrediction(standardized); average != 347 {
+		t.Errorf("Expected average prediction 347; given average: %v", average)
+	}
+
+	t.Log("Empty vectors are predicted as zeros without R session ...")
+	results := make([](*[]uint64), 1)
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(1)
+	evaluateArima(nil, &waitGroup, &sync.Mutex{}, 0, &[]uint64{}, 3, &results)
+	if !reflect.DeepEqual(*results[0], []uint64{0, 0, 0}) {
+		t.Errorf("Expected zero predictions [0 0 0]; given predictions: %v", *results[0])
+	}
+}
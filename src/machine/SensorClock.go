@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"sync"
+	"time"
+)
+
+// Maximum deviation between converted sensor time and capture time; the clock is anchored again if it is exceeded
+// (sensor restart, clock wrap-around, or lost datagrams).
+const SENSOR_CLOCK_TOLERANCE = time.Second
+
+// Conversion of sensor timestamps (TZSP timestamp tag - free-running clock in microseconds) to absolute time. The
+// first timestamp is anchored to its capture time and the following timestamps keep the gaps measured by sensor.
+// Attribute mutex *sync.Mutex - synchronisation of parse workers. See sync.Mutex.
+// Attribute anchored bool - the clock has been anchored.
+// Attribute anchorValue uint32 - sensor timestamp of the anchor.
+// Attribute anchorTime time.Time - capture time of the anchor. See time.Time.
+type sensorClock struct {
+	mutex		*sync.Mutex
+	anchored	bool
+	anchorValue	uint32
+	anchorTime	time.Time
+}
+
+// Creating of the sensor clock that is not anchored.
+// Returning *sensorClock - sensorClock object.
+func newSensorClock() *sensorClock {
+	return &sensorClock{
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Converting of sensor timestamp to absolute time.
+// Parameter value uint32 - sensor timestamp in microseconds.
+// Parameter captureTime time.Time - capture time of the frame that carries the timestamp. See time.Time.
+// Returning time.Time - arrival time of the frame at sensor.
+func (sensorClock *sensorClock) convert(value uint32, captureTime time.Time) time.Time {
+	sensorClock.mutex.Lock()
+	defer sensorClock.mutex.Unlock()
+	if sensorClock.anchored {
+		// signed difference - frames of parallel buckets may be converted out of order
+		elapsed := time.Duration(int32(value - sensorClock.anchorValue)) * time.Microsecond
+		converted := sensorClock.anchorTime.Add(elapsed)
+		deviation := converted.Sub(captureTime)
+		if deviation <= SENSOR_CLOCK_TOLERANCE && deviation >= -SENSOR_CLOCK_TOLERANCE {
+			return converted
+		}
+	}
+	sensorClock.anchored = true
+	sensorClock.anchorValue = value
+	sensorClock.anchorTime = captureTime
+	return captureTime
+}
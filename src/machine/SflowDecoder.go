@@ -0,0 +1,207 @@
+package machine
+
+import (
+	"model"
+	"encoding/binary"
+)
+
+// Supported version of sFlow datagrams.
+const SFLOW_V5 = uint32(5)
+// Types of agent address.
+const SFLOW_ADDRESS_IPV4 = uint32(1)
+const SFLOW_ADDRESS_IPV6 = uint32(2)
+// Sample types (enterprise 0).
+const SFLOW_FLOW_SAMPLE = uint32(1)
+const SFLOW_EXPANDED_FLOW_SAMPLE = uint32(3)
+// Flow record types (enterprise 0).
+const SFLOW_RAW_PACKET_HEADER = uint32(1)
+// Header protocol of raw packet header record.
+const SFLOW_HEADER_ETHERNET = uint32(1)
+// Lengths of fixed parts of sFlow structures.
+const SFLOW_FLOW_SAMPLE_LENGTH = uint(32)
+const SFLOW_EXPANDED_FLOW_SAMPLE_LENGTH = uint(44)
+const SFLOW_RAW_PACKET_HEADER_LENGTH = uint(16)
+
+// Frame sampled by sFlow agent.
+// Attribute RawDataType model.RawDataType - raw data type decoded from sampled header. See model.RawDataType.
+// Attribute Bytes uint64 - original frame length multiplied by sampling rate.
+// Attribute Packets uint64 - number of frames represented by the sample (sampling rate).
+type SampledFrame struct {
+	RawDataType		model.RawDataType
+	Bytes			uint64
+	Packets			uint64
+}
+
+// Attribute frameDecoder *FrameDecoder - decoding of raw data types from sampled headers. See FrameDecoder.
+type SflowDecoder struct {
+	frameDecoder	*FrameDecoder
+}
+
+// Creating instance of the SflowDecoder.
+// Parameter directionClassifier *DirectionClassifier - classification of flow direction. See DirectionClassifier.
+// Returning *SflowDecoder - SflowDecoder object.
+func NewSflowDecoder(directionClassifier *DirectionClassifier) *SflowDecoder {
+	sflowDecoder := SflowDecoder{
+		frameDecoder: NewFrameDecoder(directionClassifier),
+	}
+	return &sflowDecoder
+}
+
+// Decoding of sampled frames from sFlow v5 datagram. Counter samples and flow records other than raw Ethernet
+// packet headers are skipped.
+// Parameter datagram []byte - received UDP payload.
+// Returning []*SampledFrame - decoded sampled frames. See SampledFrame.
+// Returning error - malformed datagram or unsupported version.
+func (SflowDecoder *SflowDecoder) DecodeDatagram(datagram []byte) ([]*SampledFrame, error) {
+	reader := xdrReader{data: datagram}
+	version := reader.readUint32()
+	if reader.failed {
+		return nil, newFlowError("sFlow datagram is shorter than version field: %d bytes", len(datagram))
+	}
+	if version != SFLOW_V5 {
+		return nil, newFlowError("unsupported sFlow version: %d", version)
+	}
+	switch reader.readUint32() {
+	case SFLOW_ADDRESS_IPV4:
+		reader.skip(4)
+	case SFLOW_ADDRESS_IPV6:
+		reader.skip(16)
+	default:
+		return nil, newFlowError("unknown type of sFlow agent address")
+	}
+	// sub agent ID, sequence number, and uptime
+	reader.skip(12)
+	sampleCount := reader.readUint32()
+	if reader.failed {
+		return nil, newFlowError("sFlow datagram is shorter than header: %d bytes", len(datagram))
+	}
+	var frames []*SampledFrame
+	for i := uint32(0); i < sampleCount; i++ {
+		sampleFormat := reader.readUint32()
+		sample := reader.readOpaque()
+		if reader.failed {
+			return frames, newFlowError("sFlow sample %d of %d is truncated", i + 1, sampleCount)
+		}
+		var sampleFrames []*SampledFrame
+		var err error
+		switch sampleFormat {
+		case SFLOW_FLOW_SAMPLE:
+			sampleFrames, err = SflowDecoder.decodeFlowSample(sample, SFLOW_FLOW_SAMPLE_LENGTH)
+		case SFLOW_EXPANDED_FLOW_SAMPLE:
+			sampleFrames, err = SflowDecoder.decodeFlowSample(sample, SFLOW_EXPANDED_FLOW_SAMPLE_LENGTH)
+		}
+		frames = append(frames, sampleFrames...)
+		if err != nil {
+			return frames, err
+		}
+	}
+	return frames, nil
+}
+
+// Decoding of flow sample or expanded flow sample.
+// Parameter sample []byte - sample data without format and length fields.
+// Parameter fixedLength uint - length of sample fields preceding the records (it differs for expanded sample).
+// Returning []*SampledFrame - decoded sampled frames. See SampledFrame.
+// Returning error - truncated sample.
+func (SflowDecoder *SflowDecoder) decodeFlowSample(sample []byte, fixedLength uint) ([]*SampledFrame, error) {
+	if uint(len(sample)) < fixedLength {
+		return nil, newFlowError("sFlow flow sample is shorter than its header: %d bytes", len(sample))
+	}
+	// sequence number and source ID (two fields in expanded sample) precede the sampling rate
+	rateIndex := uint(8)
+	if fixedLength == SFLOW_EXPANDED_FLOW_SAMPLE_LENGTH {
+		rateIndex = 12
+	}
+	samplingRate := uint(binary.BigEndian.Uint32(sample[rateIndex : rateIndex + 4]))
+	if samplingRate == 0 {
+		samplingRate = 1
+	}
+	recordCount := binary.BigEndian.Uint32(sample[fixedLength - 4 : fixedLength])
+	reader := xdrReader{data: sample, index: fixedLength}
+	var frames []*SampledFrame
+	for i := uint32(0); i < recordCount; i++ {
+		recordFormat := reader.readUint32()
+		record := reader.readOpaque()
+		if reader.failed {
+			return frames, newFlowError("sFlow flow record %d of %d is truncated", i + 1, recordCount)
+		}
+		if recordFormat != SFLOW_RAW_PACKET_HEADER {
+			continue
+		}
+		frame, decoded := SflowDecoder.decodeRawPacketHeader(record, samplingRate)
+		if decoded {
+			frames = append(frames, frame)
+		}
+	}
+	return frames, nil
+}
+
+// Decoding of raw packet header record by using of the same decoder as captured frames.
+// Parameter record []byte - record data without format and length fields.
+// Parameter samplingRate uint - sampling rate of the flow sample.
+// Returning *SampledFrame - decoded sampled frame. See SampledFrame.
+// Returning bool - the record contains valid Ethernet header.
+func (SflowDecoder *SflowDecoder) decodeRawPacketHeader(record []byte, samplingRate uint) (*SampledFrame, bool) {
+	reader := xdrReader{data: record}
+	headerProtocol := reader.readUint32()
+	frameLength := uint(reader.readUint32())
+	stripped := uint(reader.readUint32())
+	header := reader.readOpaque()
+	if reader.failed || headerProtocol != SFLOW_HEADER_ETHERNET {
+		return nil, false
+	}
+	rawDataType, decoded := SflowDecoder.frameDecoder.DecodeFrame(header)
+	if !decoded {
+		return nil, false
+	}
+	// stripped bytes (usually FCS) are not counted by frames parser either
+	if stripped < frameLength {
+		frameLength -= stripped
+	}
+	return &SampledFrame{RawDataType: rawDataType, Bytes: uint64(frameLength) * uint64(samplingRate),
+		Packets: uint64(samplingRate)}, true
+}
+
+// Sequential reader of XDR-encoded fields (big-endian, opaque data padded to 4 bytes).
+// Attribute data []byte - read data.
+// Attribute index uint - index of the next field.
+// Attribute failed bool - some field exceeded the data (subsequent reads return zero values).
+type xdrReader struct {
+	data			[]byte
+	index			uint
+	failed			bool
+}
+
+// Reading of 32-bit unsigned integer.
+// Returning uint32 - read value (0 if the data is truncated).
+func (xdrReader *xdrReader) readUint32() uint32 {
+	if xdrReader.failed || xdrReader.index + 4 > uint(len(xdrReader.data)) {
+		xdrReader.failed = true
+		return 0
+	}
+	value := binary.BigEndian.Uint32(xdrReader.data[xdrReader.index : xdrReader.index + 4])
+	xdrReader.index += 4
+	return value
+}
+
+// Reading of variable-length opaque data prefixed by its length.
+// Returning []byte - read data without padding (nil if the data is truncated).
+func (xdrReader *xdrReader) readOpaque() []byte {
+	length := uint(xdrReader.readUint32())
+	start := xdrReader.index
+	xdrReader.skip((length + 3) &^ 3)
+	if xdrReader.failed {
+		return nil
+	}
+	return xdrReader.data[start : start + length]
+}
+
+// Skipping of fixed number of bytes.
+// Parameter length uint - number of skipped bytes.
+func (xdrReader *xdrReader) skip(length uint) {
+	if xdrReader.failed || xdrReader.index + length > uint(len(xdrReader.data)) {
+		xdrReader.failed = true
+		return
+	}
+	xdrReader.index += length
+}
@@ -0,0 +1,82 @@
+package machine
+
+import (
+	"testing"
+	"model"
+)
+
+// Encoding of XDR opaque data (length prefix and padding to 4 bytes).
+func buildXdrOpaque(data []byte) []byte {
+	opaque := uint32Bytes(uint32(len(data)))
+	opaque = append(opaque, data...)
+	for len(opaque) % 4 != 0 {
+		opaque = append(opaque, 0)
+	}
+	return opaque
+}
+
+// Building of sFlow v5 datagram with one flow sample that carries extended switch and raw packet header records.
+func buildSflowDatagram(sampleFormat uint32, samplingRate uint32, header []byte, frameLength uint32) []byte {
+	var record []byte
+	record = append(record, uint32Bytes(SFLOW_HEADER_ETHERNET)...)
+	record = append(record, uint32Bytes(frameLength)...)
+	record = append(record, uint32Bytes(4)...)
+	record = append(record, buildXdrOpaque(header)...)
+	var sample []byte
+	sample = append(sample, uint32Bytes(1)...)
+	if sampleFormat == SFLOW_EXPANDED_FLOW_SAMPLE {
+		sample = append(sample, uint32Bytes(0)...)
+		sample = append(sample, uint32Bytes(3)...)
+	} else {
+		sample = append(sample, uint32Bytes(3)...)
+	}
+	sample = append(sample, uint32Bytes(samplingRate)...)
+	sample = append(sample, make([]byte, 8)...)
+	if sampleFormat == SFLOW_EXPANDED_FLOW_SAMPLE {
+		sample = append(sample, make([]byte, 16)...)
+	} else {
+		sample = append(sample, make([]byte, 8)...)
+	}
+	sample = append(sample, uint32Bytes(2)...)
+	// extended switch record is skipped
+	sample = append(sample, uint32Bytes(1001)...)
+	sample = append(sample, buildXdrOpaque(make([]byte, 16))...)
+	sample = append(sample, uint32Bytes(SFLOW_RAW_PACKET_HEADER)...)
+	sample = append(sample, buildXdrOpaque(record)...)
+	var datagram []byte
+	datagram = append(datagram, uint32Bytes(SFLOW_V5)...)
+	datagram = append(datagram, uint32Bytes(SFLOW_ADDRESS_IPV4)...)
+	datagram = append(datagram, 192, 0, 2, 1)
+	datagram = append(datagram, make([]byte, 12)...)
+	datagram = append(datagram, uint32Bytes(1)...)
+	datagram = append(datagram, uint32Bytes(sampleFormat)...)
+	datagram = append(datagram, buildXdrOpaque(sample)...)
+	return datagram
+}
+
+// Testing of sFlow decoding - sampled headers are decoded like captured frames and scaled by sampling rate.
+func TestDecodeSflowRawPacketHeader(t *testing.T) {
+	header := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	expected := model.RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 443, DstPort: 50000,
+		Direction: 1}
+	directionClassifier, _ := NewDirectionClassifier(&decoderRouterMac, nil)
+	sflowDecoder := NewSflowDecoder(directionClassifier)
+	for _, sampleFormat := range []uint32{SFLOW_FLOW_SAMPLE, SFLOW_EXPANDED_FLOW_SAMPLE} {
+		frames, err := sflowDecoder.DecodeDatagram(buildSflowDatagram(sampleFormat, 512, header, 1518))
+		if err != nil || len(frames) != 1 {
+			t.Fatalf("Decoding of sFlow sample %d failed: %v, frames: %d", sampleFormat, err, len(frames))
+		}
+		frames[0].RawDataType.SrcAddress = model.IPAddress{}
+		frames[0].RawDataType.DstAddress = model.IPAddress{}
+		if frames[0].RawDataType != expected || frames[0].Bytes != 1514 * 512 ||
+			frames[0].Packets != 512 {
+			t.Errorf("Wrongly decoded sFlow sample %d: %+v", sampleFormat, *frames[0])
+		}
+	}
+	datagram := buildSflowDatagram(SFLOW_FLOW_SAMPLE, 512, header, 1518)
+	_, err := sflowDecoder.DecodeDatagram(datagram[:len(datagram) - 8])
+	if err == nil {
+		t.Errorf("Truncated sFlow datagram should be rejected")
+	}
+}
@@ -28,11 +28,15 @@ func NewSmoothingCreator(smoothingRange uint, smoothingThreads uint) *SmoothingC
 	return &predictionCreator
 }
 
-// Initial smoothing of data slice - creating of periodic intervals with specified cell size (time window).
+// Initial smoothing of data slice - creating of periodic intervals with specified cell size (time window). Entries of
+// coarser resolution than the cell size (minute, hour, or day counters) are spread over all cells of their bucket.
 // Parameter dataSlice *[](*model.Data) - original data slice with frames bytes and timestamps. See model.Data.
 // Returning *[](*model.FinalData) - smoothed data vector. See model.FinalData.
 func (SmoothingCreator *SmoothingCreator) SmoothData(dataSlice *[](*model.Data)) (*[](*model.FinalData)) {
 	if len(*dataSlice) != 0 {
+		if isCoarseData(SmoothingCreator.smoothingRange, dataSlice) {
+			return spreadData(SmoothingCreator.smoothingRange, dataSlice)
+		}
 		mutex := &sync.Mutex{}
 		smoothedData := initSmoothingSlice(SmoothingCreator.smoothingRange, dataSlice)
 		assignSmoothingJobs(SmoothingCreator.smoothingRange, dataSlice, int(SmoothingCreator.smoothingThreads),
@@ -44,6 +48,65 @@ func (SmoothingCreator *SmoothingCreator) SmoothData(dataSlice *[](*model.Data))
 	}
 }
 
+// Checking whether the data slice contains entries whose resolution is coarser than the smoothing cell.
+// Parameter smoothingRange uint - configuration setting - smoothing cell size.
+// Parameter dataSlice *[](*model.Data) - original data slice with frames bytes and timestamps. See model.Data.
+// Returning bool - some entry covers more than one smoothing cell.
+func isCoarseData(smoothingRange uint, dataSlice *[](*model.Data)) bool {
+	cellSize := time.Duration(smoothingRange) * time.Millisecond
+	for _, data := range *dataSlice {
+		if data.Resolution > cellSize {
+			return true
+		}
+	}
+	return false
+}
+
+// Smoothing of data slice with coarse entries - bytes and frames of each entry are divided evenly among smoothing
+// cells of its time bucket (cells are filled directly, entries are not split).
+// Parameter smoothingRange uint - configuration setting - smoothing cell size.
+// Parameter dataSlice *[](*model.Data) - original data slice ordered by time. See model.Data.
+// Returning *[](*model.FinalData) - smoothed data vector. See model.FinalData.
+func spreadData(smoothingRange uint, dataSlice *[](*model.Data)) *[](*model.FinalData) {
+	cellSize := time.Duration(smoothingRange) * time.Millisecond
+	dataSliceBody := *dataSlice
+	sliceStart := dataSliceBody[0].Time
+	sliceEnd := sliceStart
+	for _, data := range dataSliceBody {
+		dataEnd := data.Time.Add(cellSize)
+		if data.Resolution > cellSize {
+			dataEnd = data.Time.Add(data.Resolution)
+		}
+		if dataEnd.After(sliceEnd) {
+			sliceEnd = dataEnd
+		}
+	}
+	parts := uint64(math.Ceil(float64(sliceEnd.Sub(sliceStart)) / float64(cellSize)))
+	smoothedData := make([](*model.FinalData), parts)
+	for i := range smoothedData {
+		smoothedData[i] = &model.FinalData{Timestamp: sliceStart.Add(time.Duration(i + 1) * cellSize)}
+	}
+	for _, data := range dataSliceBody {
+		firstCell := uint64(data.Time.Sub(sliceStart) / cellSize)
+		cells := uint64(data.Resolution / cellSize)
+		if cells == 0 {
+			cells = 1
+		}
+		for i := uint64(0); i < cells && firstCell + i < parts; i++ {
+			cell := smoothedData[firstCell + i]
+			cell.DataElement += data.Bytes / cells
+			if i < data.Bytes % cells {
+				cell.DataElement++
+			}
+			cell.Packets += data.Packets / cells
+			if i < data.Packets % cells {
+				cell.Packets++
+			}
+		}
+	}
+	return &smoothedData
+}
+
 // Initialisation of the smoothing slice.
 // Parameter smoothingRange uint - configuration setting - smoothing cell size.
 // Parameter dataSlice *[](*model.Data) - original data slice with frames bytes and timestamps. See model.Data.
@@ -112,28 +175,35 @@ func smoothingThread(smoothingRange uint, dataSlice *[](*model.Data), startIndex
 	smoothedDataRef := *smoothedData
 	runningTime := sliceStart.Add(time.Duration(smoothingRange) * time.Millisecond)
 	if startIndex <= endIndex {
-		var dataBuffer []uint
+		var dataBuffer []uint64
+		var packetsBuffer []uint64
 		for i := startIndex; i <= endIndex || uint(runningTime.Sub(sliceStart).Nanoseconds()/1000000) <= sliceLength; {
 			if dataSliceBody[i].Time.Before(runningTime) {
 				dataBuffer = append(dataBuffer, dataSliceBody[i].Bytes)
+				packetsBuffer = append(packetsBuffer, dataSliceBody[i].Packets)
 				i++
 			} else {
 				sumX := sum(&dataBuffer)
+				sumP := sum(&packetsBuffer)
 				smoothingMutex.Lock()
 				smoothedDataRef[smoothingIndex] = &model.FinalData{
 					DataElement: sumX,
+					Packets:     sumP,
 					Timestamp:   runningTime}
 				smoothingMutex.Unlock()
 				runningTime = runningTime.Add(time.Duration(smoothingRange) * time.Millisecond)
 				smoothingIndex ++
 				dataBuffer = nil
+				packetsBuffer = nil
 			}
 		}
 		if len(dataBuffer) != 0 {
 			sumX := sum(&dataBuffer)
+			sumP := sum(&packetsBuffer)
 			smoothingMutex.Lock()
 			smoothedDataRef[smoothingIndex] = &model.FinalData{
 				DataElement: sumX,
+				Packets:     sumP,
 				Timestamp:   runningTime}
 			smoothingMutex.Unlock()
 		}
@@ -142,20 +212,21 @@ func smoothingThread(smoothingRange uint, dataSlice *[](*model.Data), startIndex
 		for i:=uint(0); i<parts; i++ {
 			smoothedDataRef[smoothingIndex+i] = &model.FinalData{
 				DataElement: uint64(0),
+				Packets:     uint64(0),
 				Timestamp:   runningTime}
 		}
 	}
 }
 
-// Computing sum of the uint slice.
-// Parameter dataBuffer *([]uint) - slice with bytes count.
-// Returning float64 - sum.
-func sum(dataBuffer *([]uint)) uint64 {
+// Computing sum of the uint64 slice.
+// Parameter dataBuffer *([]uint64) - slice with bytes or frames count.
+// Returning uint64 - sum.
+func sum(dataBuffer *([]uint64)) uint64 {
 	dataRef := *dataBuffer
 	if len(dataRef) != 0 {
 		var sum uint64 = 0
 		for i := range dataRef {
-			sum += uint64(dataRef[i])
+			sum += dataRef[i]
 		}
 		return sum
 	} else {
@@ -49,7 +49,7 @@ func TestPredictionCreatorSmoothData1(t *testing.T) {
 	runningBytes := 10
 	for i:=0; i<dataEntries; i++ {
 		dataSlice = append(dataSlice, &model.Data{
-			Bytes: uint(runningBytes),
+			Bytes: uint64(runningBytes),
 			Time: runningTime,
 		})
 		runningTime = runningTime.Add(time.Duration(1000) * time.Millisecond)
@@ -89,7 +89,7 @@ func TestPredictionCreatorSmoothData2(t *testing.T) {
 	runningBytes := 10
 	for i:=0; i<dataEntries; i++ {
 		dataSlice = append(dataSlice, &model.Data{
-			Bytes: uint(runningBytes),
+			Bytes: uint64(runningBytes),
 			Time: runningTime,
 		})
 		runningTime = runningTime.Add(time.Duration(100) * time.Millisecond)
@@ -124,7 +124,7 @@ func TestPredictionCreatorSmoothData3(t *testing.T) {
 	runningBytes := 10
 	for i:=0; i<dataEntries; i++ {
 		dataSlice = append(dataSlice, &model.Data{
-			Bytes: uint(runningBytes),
+			Bytes: uint64(runningBytes),
 			Time: runningTime,
 		})
 		runningTime = runningTime.Add(time.Duration(400) * time.Millisecond)
@@ -159,7 +159,7 @@ func TestPredictionCreatorSmoothData4(t *testing.T) {
 	runningBytes := 10
 	for i:=0; i<dataEntries; i++ {
 		dataSlice = append(dataSlice, &model.Data{
-			Bytes: uint(runningBytes),
+			Bytes: uint64(runningBytes),
 			Time: runningTime,
 		})
 		runningTime = runningTime.Add(time.Duration(5000) * time.Millisecond)
@@ -188,4 +188,71 @@ func TestPredictionCreatorSmoothData4(t *testing.T) {
 				validData[i], (*smoothedData)[i].DataElement)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// Unit test - smoothing of frames count next to bytes.
+// Parameter t *testing.T - testing engine.
+func TestPredictionCreatorSmoothPackets(t *testing.T) {
+	t.Log("Initialisation of prediction configuration and data slice ...")
+	smoothingCreator.smoothingRange = 5000
+	var dataSlice [](*model.Data)
+	dataEntries := 18
+	runningTime := time.Now()
+	for i:=0; i<dataEntries; i++ {
+		dataSlice = append(dataSlice, &model.Data{
+			Bytes: uint64(1500),
+			Packets: uint64(i % 3 + 1),
+			Time: runningTime,
+		})
+		runningTime = runningTime.Add(time.Duration(1000) * time.Millisecond)
+	}
+
+	t.Log("Execution of data smoothing ...")
+	smoothedData := smoothingCreator.SmoothData(&dataSlice)
+
+	t.Log("Verification of smoothed data slice ...")
+	validBytes := []uint64{7500, 7500, 7500, 4500}
+	validPackets := []uint64{9, 10, 11, 6}
+	if len(*smoothedData) != len(validPackets) {
+		t.Fatalf("The length of smoothed data is invalid - expected length: %d, actual length: %d",
+			len(validPackets), len(*smoothedData))
+	}
+	for i:=0; i<len(validPackets); i++ {
+		if validBytes[i] != (*smoothedData)[i].DataElement || validPackets[i] != (*smoothedData)[i].Packets {
+			t.Errorf("Expected bytes and frames of smoothed vector: %d / %d, actual values: %d / %d",
+				validBytes[i], validPackets[i], (*smoothedData)[i].DataElement, (*smoothedData)[i].Packets)
+		}
+	}
+}
+
+// Unit test - entries of coarse resolution (counters of 5-second buckets) are spread over smoothing cells.
+// Parameter t *testing.T - testing engine.
+func TestPredictionCreatorSmoothCoarseResolution(t *testing.T) {
+	t.Log("Initialisation of prediction configuration and data slice ...")
+	smoothingCreator.smoothingRange = 1000
+	runningTime := time.Now()
+	resolution := time.Duration(5000) * time.Millisecond
+	dataSlice := [](*model.Data){
+		{Time: runningTime, Resolution: resolution, Bytes: 10, Packets: 5, Direction: model.DIRECTION_RX},
+		{Time: runningTime, Resolution: resolution, Bytes: 5, Direction: model.DIRECTION_TX},
+		{Time: runningTime.Add(resolution), Resolution: resolution, Bytes: 15, Packets: 5},
+		{Time: runningTime.Add(2 * resolution), Resolution: resolution, Bytes: 20, Packets: 7},
+	}
+
+	t.Log("Execution of data smoothing ...")
+	smoothedData := smoothingCreator.SmoothData(&dataSlice)
+
+	t.Log("Verification of smoothed data slice ...")
+	validBytes := []uint64{3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 4, 4, 4, 4, 4}
+	validPackets := []uint64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 1, 1, 1}
+	if len(*smoothedData) != len(validBytes) {
+		t.Fatalf("The length of smoothed data is invalid - expected length: %d, actual length: %d",
+			len(validBytes), len(*smoothedData))
+	}
+	for i:=0; i<len(validBytes); i++ {
+		if validBytes[i] != (*smoothedData)[i].DataElement || validPackets[i] != (*smoothedData)[i].Packets {
+			t.Errorf("Expected bytes and frames of smoothed vector: %d / %d, actual values: %d / %d",
+				validBytes[i], validPackets[i], (*smoothedData)[i].DataElement, (*smoothedData)[i].Packets)
+		}
+	}
+}
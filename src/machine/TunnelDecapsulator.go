@@ -0,0 +1,166 @@
+package machine
+
+import (
+	"encoding/binary"
+)
+
+// Frames are mirrored as ERSPAN (type I, II, or III) or transparent Ethernet bridging over GRE.
+const ENCAPSULATION_ERSPAN = "erspan"
+// Frames are mirrored inside VXLAN datagrams.
+const ENCAPSULATION_VXLAN = "vxlan"
+// Capture filter of GRE packets (both IPv4 and IPv6).
+const FILTER_GRE = "ip proto 47 or ip6 proto 47"
+// Capture filter of VXLAN datagrams.
+const FILTER_VXLAN = "udp port 4789"
+// Listening UDP port of VXLAN datagrams.
+const PORT_VXLAN = uint16(4789)
+const PROTOCOL_GRE = uint8(47)
+const GRE_PROTOCOL_ERSPAN_II = uint16(0x88be)
+const GRE_PROTOCOL_ERSPAN_III = uint16(0x22eb)
+const GRE_PROTOCOL_TRANSPARENT_ETHERNET = uint16(0x6558)
+const GRE_FLAG_CHECKSUM = uint16(0x8000)
+const GRE_FLAG_KEY = uint16(0x2000)
+const GRE_FLAG_SEQUENCE = uint16(0x1000)
+const GRE_VERSION_MASK = uint16(0x0007)
+const ERSPAN_II_HEADER_LENGTH = uint(8)
+const ERSPAN_III_HEADER_LENGTH = uint(12)
+const ERSPAN_III_SUBHEADER_LENGTH = uint(8)
+const VXLAN_HEADER_LENGTH = uint(8)
+const VXLAN_FLAG_VNI = byte(0x08)
+
+// Decapsulator of frames that are mirrored over GRE (ERSPAN or transparent Ethernet bridging).
+type ErspanDecapsulator struct {}
+
+// Capture filter of GRE packets.
+// Returning string - BPF expression.
+func (ErspanDecapsulator *ErspanDecapsulator) CaptureFilter() string {
+	return FILTER_GRE
+}
+
+// Unwrapping of the frame from GRE packet and ERSPAN header.
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - inner Ethernet 2 frame or nil if the frame is not an ERSPAN / TEB packet.
+func (ErspanDecapsulator *ErspanDecapsulator) Unwrap(frame *[]byte) *[]byte {
+	framex := *frame
+	protocol, startIndex, found := locateOuterPayload(framex)
+	if !found || protocol != PROTOCOL_GRE {
+		return nil
+	}
+	length := uint(len(framex))
+	if length < startIndex + 4 {
+		return nil
+	}
+	flags := binary.BigEndian.Uint16(framex[startIndex : startIndex + 2])
+	greProtocol := binary.BigEndian.Uint16(framex[startIndex + 2 : startIndex + 4])
+	if flags & GRE_VERSION_MASK != 0 {
+		return nil
+	}
+	startIndex += 4
+	if flags & GRE_FLAG_CHECKSUM != 0 {
+		startIndex += 4
+	}
+	if flags & GRE_FLAG_KEY != 0 {
+		startIndex += 4
+	}
+	if flags & GRE_FLAG_SEQUENCE != 0 {
+		startIndex += 4
+	}
+	switch greProtocol {
+	case GRE_PROTOCOL_ERSPAN_II:
+		// type I doesn't use sequence numbers and it doesn't have ERSPAN header
+		if flags & GRE_FLAG_SEQUENCE != 0 {
+			startIndex += ERSPAN_II_HEADER_LENGTH
+		}
+	case GRE_PROTOCOL_ERSPAN_III:
+		if length < startIndex + ERSPAN_III_HEADER_LENGTH {
+			return nil
+		}
+		// optional platform specific sub-header is signalised by the last bit of ERSPAN III header
+		optionalSubheader := framex[startIndex + ERSPAN_III_HEADER_LENGTH - 1] & 0x01
+		startIndex += ERSPAN_III_HEADER_LENGTH
+		if optionalSubheader != 0 {
+			startIndex += ERSPAN_III_SUBHEADER_LENGTH
+		}
+	case GRE_PROTOCOL_TRANSPARENT_ETHERNET:
+	default:
+		return nil
+	}
+	return cutInnerFrame(framex, startIndex)
+}
+
+// Decapsulator of frames that are mirrored inside VXLAN datagrams.
+type VxlanDecapsulator struct {}
+
+// Capture filter of VXLAN datagrams.
+// Returning string - BPF expression.
+func (VxlanDecapsulator *VxlanDecapsulator) CaptureFilter() string {
+	return FILTER_VXLAN
+}
+
+// Unwrapping of the frame from UDP datagram and VXLAN header.
+// Parameter frame *[]byte - captured frame.
+// Returning *[]byte - inner Ethernet 2 frame or nil if the frame is not a valid VXLAN datagram.
+func (VxlanDecapsulator *VxlanDecapsulator) Unwrap(frame *[]byte) *[]byte {
+	framex := *frame
+	protocol, startIndex, found := locateOuterPayload(framex)
+	if !found || protocol != PROTOCOL_UDP {
+		return nil
+	}
+	length := uint(len(framex))
+	if length < startIndex + 8 + VXLAN_HEADER_LENGTH {
+		return nil
+	}
+	destinationPort := binary.BigEndian.Uint16(framex[startIndex + 2 : startIndex + 4])
+	if destinationPort != PORT_VXLAN {
+		return nil
+	}
+	startIndex += 8
+	if framex[startIndex] & VXLAN_FLAG_VNI == 0 {
+		return nil
+	}
+	startIndex += VXLAN_HEADER_LENGTH
+	return cutInnerFrame(framex, startIndex)
+}
+
+// Locating of the payload (transport header) of outer IPv4 or IPv6 packet carried by Ethernet 2 frame; VLAN tags
+// and IPv6 extension headers are skipped the same way as by FrameDecoder.
+// Parameter framex []byte - captured frame.
+// Returning protocol uint8 - protocol field of IPv4 packet or the upper-layer protocol of IPv6 packet.
+// Returning startIndex uint - index of the first byte of IP payload.
+// Returning found bool - true if the frame carries IPv4 or IPv6 packet with complete header (IPv6 packet must be
+// the first fragment with complete chain of extension headers).
+func locateOuterPayload(framex []byte) (protocol uint8, startIndex uint, found bool) {
+	length := uint(len(framex))
+	if length < ETHERNET_HEADER_LENGTH {
+		return 0, 0, false
+	}
+	ethertype, startIndex, _ := skipVlanTags(framex, binary.BigEndian.Uint16(framex[12:14]))
+	if ethertype == ETHER_TYPE_IPV4 && length >= startIndex + 20 {
+		ihl := uint(framex[startIndex] & 0x0f) * 4
+		protocol = framex[startIndex + 9]
+		if ihl < 20 || length < startIndex + ihl {
+			return 0, 0, false
+		}
+		return protocol, startIndex + ihl, true
+	} else if ethertype == ETHER_TYPE_IPV6 && length >= startIndex + IPV6_HEADER_LENGTH {
+		protocol, startIndex, complete := walkIpv6ExtensionHeaders(framex, startIndex + IPV6_HEADER_LENGTH,
+			framex[startIndex + 6])
+		if !complete || length < startIndex {
+			return 0, 0, false
+		}
+		return protocol, startIndex, true
+	}
+	return 0, 0, false
+}
+
+// Cutting of the inner Ethernet 2 frame from decapsulated frame.
+// Parameter framex []byte - captured frame.
+// Parameter startIndex uint - index of the first byte of inner frame.
+// Returning *[]byte - inner frame or nil if it is shorter than Ethernet 2 header.
+func cutInnerFrame(framex []byte, startIndex uint) *[]byte {
+	if uint(len(framex)) < startIndex + ETHERNET_HEADER_LENGTH {
+		return nil
+	}
+	innerFrame := framex[startIndex:]
+	return &innerFrame
+}
@@ -0,0 +1,140 @@
+package machine
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Building of GRE header with optional fields that are signalised by flags.
+// Parameter flags uint16 - flags and version field.
+// Parameter protocol uint16 - protocol type of the payload.
+// Returning []byte - GRE header.
+func buildGreHeader(flags uint16, protocol uint16) []byte {
+	header := []byte{byte(flags >> 8), byte(flags), byte(protocol >> 8), byte(protocol)}
+	for _, flag := range []uint16{GRE_FLAG_CHECKSUM, GRE_FLAG_KEY, GRE_FLAG_SEQUENCE} {
+		if flags & flag != 0 {
+			header = append(header, 0xee, 0xee, 0xee, 0xee)
+		}
+	}
+	return header
+}
+
+// Building of VXLAN datagram (UDP and VXLAN headers).
+// Parameter port uint16 - destination UDP port.
+// Parameter flags byte - flags of VXLAN header.
+// Returning []byte - UDP and VXLAN headers.
+func buildVxlanHeader(port uint16, flags byte) []byte {
+	return []byte{0xc3, 0x50, byte(port >> 8), byte(port), 0x00, 0x00, 0x00, 0x00,
+		flags, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64, 0x00}
+}
+
+// Unit test - unwrapping of ERSPAN I, II, III and transparent Ethernet bridging frames from GRE packets.
+// Parameter t *testing.T - testing engine.
+func TestErspanUnwrap(t *testing.T) {
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	ipv4 := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_GRE))
+	ipv6 := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6), buildIpv6Header(PROTOCOL_GRE))
+	tagged := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_QINQ), []byte{0x00, 0x64, 0x81, 0x00},
+		[]byte{0x00, 0x0a, 0x08, 0x00}, buildIpv4Header(PROTOCOL_GRE))
+	fragment := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_FRAGMENT))
+	erspanII := []byte{0x10, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	erspanIII := []byte{0x20, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	erspanIIISubheader := []byte{0x20, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	allFlags := GRE_FLAG_CHECKSUM | GRE_FLAG_KEY | GRE_FLAG_SEQUENCE
+	tests := []struct {
+		name		string
+		packet		[]byte
+		valid		bool
+	}{
+		{"ERSPAN I", buildFrame(ipv4, buildGreHeader(0, GRE_PROTOCOL_ERSPAN_II), frame), true},
+		{"ERSPAN I with key", buildFrame(ipv4, buildGreHeader(GRE_FLAG_KEY, GRE_PROTOCOL_ERSPAN_II), frame), true},
+		{"ERSPAN II", buildFrame(ipv4, buildGreHeader(GRE_FLAG_SEQUENCE, GRE_PROTOCOL_ERSPAN_II), erspanII, frame),
+			true},
+		{"ERSPAN II with all flags", buildFrame(ipv4, buildGreHeader(allFlags, GRE_PROTOCOL_ERSPAN_II), erspanII,
+			frame), true},
+		{"ERSPAN II over IPv6", buildFrame(ipv6, buildGreHeader(GRE_FLAG_SEQUENCE, GRE_PROTOCOL_ERSPAN_II),
+			erspanII, frame), true},
+		{"ERSPAN II on VLAN trunk", buildFrame(tagged, buildGreHeader(GRE_FLAG_SEQUENCE, GRE_PROTOCOL_ERSPAN_II),
+			erspanII, frame), true},
+		{"ERSPAN III", buildFrame(ipv4, buildGreHeader(GRE_FLAG_SEQUENCE, GRE_PROTOCOL_ERSPAN_III), erspanIII,
+			frame), true},
+		{"ERSPAN III with all flags", buildFrame(ipv4, buildGreHeader(allFlags, GRE_PROTOCOL_ERSPAN_III),
+			erspanIII, frame), true},
+		{"ERSPAN III with sub-header", buildFrame(ipv4, buildGreHeader(GRE_FLAG_SEQUENCE,
+			GRE_PROTOCOL_ERSPAN_III), erspanIIISubheader, frame), true},
+		{"ERSPAN III short header", buildFrame(ipv4, buildGreHeader(GRE_FLAG_SEQUENCE, GRE_PROTOCOL_ERSPAN_III),
+			erspanIII[:8]), false},
+		{"TEB", buildFrame(ipv4, buildGreHeader(0, GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame), true},
+		{"TEB with checksum", buildFrame(ipv4, buildGreHeader(GRE_FLAG_CHECKSUM,
+			GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame), true},
+		{"TEB with all flags", buildFrame(ipv6, buildGreHeader(allFlags, GRE_PROTOCOL_TRANSPARENT_ETHERNET),
+			frame), true},
+		{"TEB over IPv6 first fragment", buildFrame(fragment, []byte{PROTOCOL_GRE, 0, 0x00, 0x01, 0, 0, 0, 1},
+			buildGreHeader(0, GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame), true},
+		{"TEB over IPv6 next fragment", buildFrame(fragment, []byte{PROTOCOL_GRE, 0, 0x05, 0xa9, 0, 0, 0, 1},
+			buildGreHeader(0, GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame), false},
+		{"GRE version 1", buildFrame(ipv4, buildGreHeader(0x0001, GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame),
+			false},
+		{"unknown GRE protocol", buildFrame(ipv4, buildGreHeader(0, ETHER_TYPE_IPV4), frame), false},
+		{"short GRE header", buildFrame(ipv4, []byte{0x00, 0x00}), false},
+		{"short inner frame", buildFrame(ipv4, buildGreHeader(0, GRE_PROTOCOL_TRANSPARENT_ETHERNET),
+			frame[:10]), false},
+		{"not GRE", buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_UDP),
+			buildGreHeader(0, GRE_PROTOCOL_TRANSPARENT_ETHERNET), frame), false},
+	}
+	decapsulator := &ErspanDecapsulator{}
+	for _, test := range tests {
+		unwrapped := decapsulator.Unwrap(&test.packet)
+		if test.valid && (unwrapped == nil || !bytes.Equal(*unwrapped, frame)) {
+			t.Errorf("%s: expected unwrapped frame", test.name)
+		} else if !test.valid && unwrapped != nil {
+			t.Errorf("%s: packet shouldn't be unwrapped", test.name)
+		}
+	}
+}
+
+// Unit test - unwrapping of frames from VXLAN datagrams.
+// Parameter t *testing.T - testing engine.
+func TestVxlanUnwrap(t *testing.T) {
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	ipv4 := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_UDP))
+	ipv6 := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6), buildIpv6Header(PROTOCOL_UDP))
+	tagged := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_VLAN), []byte{0x00, 0x0a, 0x08, 0x00},
+		buildIpv4Header(PROTOCOL_UDP))
+	extensionHeaders := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+		buildIpv6Header(IPV6_HOP_BY_HOP), []byte{IPV6_DESTINATION_OPTIONS, 0, 0, 0, 0, 0, 0, 0},
+		[]byte{PROTOCOL_UDP, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	tests := []struct {
+		name		string
+		datagram	[]byte
+		valid		bool
+	}{
+		{"VXLAN", buildFrame(ipv4, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame), true},
+		{"VXLAN over IPv6", buildFrame(ipv6, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame), true},
+		{"VXLAN on VLAN trunk", buildFrame(tagged, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame), true},
+		{"VXLAN over IPv6 with extension headers", buildFrame(extensionHeaders,
+			buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame), true},
+		{"truncated extension header", buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+			buildIpv6Header(IPV6_HOP_BY_HOP), []byte{PROTOCOL_UDP, 0, 0, 0}), false},
+		{"other port", buildFrame(ipv4, buildVxlanHeader(8472, VXLAN_FLAG_VNI), frame), false},
+		{"missing VNI flag", buildFrame(ipv4, buildVxlanHeader(PORT_VXLAN, 0x00), frame), false},
+		{"short UDP header", buildFrame(ipv4, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI)[:6]), false},
+		{"short VXLAN header", buildFrame(ipv4, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI)[:12]), false},
+		{"short inner frame", buildFrame(ipv4, buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame[:10]), false},
+		{"not UDP", buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+			buildVxlanHeader(PORT_VXLAN, VXLAN_FLAG_VNI), frame), false},
+	}
+	decapsulator := &VxlanDecapsulator{}
+	for _, test := range tests {
+		unwrapped := decapsulator.Unwrap(&test.datagram)
+		if test.valid && (unwrapped == nil || !bytes.Equal(*unwrapped, frame)) {
+			t.Errorf("%s: expected unwrapped frame", test.name)
+		} else if !test.valid && unwrapped != nil {
+			t.Errorf("%s: datagram shouldn't be unwrapped", test.name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package machine
+
+import (
+	"fmt"
+	"encoding/binary"
+	"configuration"
+)
+
+// Supported version of TZSP.
+const TZSP_VERSION = uint8(1)
+// Length of TZSP header (version, type, and encapsulated protocol) that precedes tags.
+const TZSP_HEADER_LENGTH = uint(4)
+// Types of TZSP datagrams that carry frames.
+const TZSP_TYPE_RECEIVED = uint8(0)
+const TZSP_TYPE_TRANSMITTED = uint8(1)
+// Encapsulated protocol - Ethernet.
+const TZSP_PROTOCOL_ETHERNET = uint16(1)
+// Tags without length and value.
+const TAG_TYPE_PADDING = byte(0x00)
+const TAG_TYPE_END = byte(0x01)
+// Signed RSSI of received frame (1 or 2 bytes).
+const TAG_TYPE_RAW_RSSI = byte(0x0a)
+// Timestamp of received frame - sensor clock in microseconds (4 bytes).
+const TAG_TYPE_TIMESTAMP = byte(0x0d)
+// Length of the original frame before it has been truncated by sensor (2 bytes).
+const TAG_TYPE_ORIGINAL_LENGTH = byte(0x29)
+// Identifier of the sensor (serial number or MAC address, variable length).
+const TAG_TYPE_SENSOR_ID = byte(0x3c)
+
+// Decoded TZSP header with tags that are relevant for accounting.
+// Attribute Version uint8 - TZSP version.
+// Attribute Type uint8 - datagram type (TZSP_TYPE_RECEIVED or TZSP_TYPE_TRANSMITTED).
+// Attribute Protocol uint16 - encapsulated protocol (TZSP_PROTOCOL_ETHERNET).
+// Attribute Timestamp uint32 - value of the timestamp tag (sensor clock in microseconds).
+// Attribute TimestampPresent bool - the datagram carries the timestamp tag.
+// Attribute OriginalLength uint - length of the original frame (0 - the tag is not present).
+// Attribute SensorId []byte - identifier of the sensor (nil - the tag is not present).
+// Attribute Rssi int - RSSI of received frame.
+// Attribute RssiPresent bool - the datagram carries the RSSI tag.
+type TzspHeader struct {
+	Version				uint8
+	Type				uint8
+	Protocol			uint16
+	Timestamp			uint32
+	TimestampPresent	bool
+	OriginalLength		uint
+	SensorId			[]byte
+	Rssi				int
+	RssiPresent			bool
+}
+
+// Decoding of TZSP datagram (UDP payload) into header and encapsulated frame. Unknown tags are skipped.
+// Parameter datagram []byte - TZSP datagram.
+// Returning *TzspHeader - decoded header. See TzspHeader.
+// Returning []byte - encapsulated frame (it may be truncated - see TzspHeader.OriginalLength).
+// Returning error - malformed datagram, unsupported version, or datagram that doesn't carry Ethernet frame.
+func DecodeTzsp(datagram []byte) (*TzspHeader, []byte, error) {
+	length := uint(len(datagram))
+	if length < TZSP_HEADER_LENGTH {
+		return nil, nil, newTzspError("TZSP datagram is shorter than header: %d bytes", length)
+	}
+	header := TzspHeader{
+		Version: datagram[0],
+		Type: datagram[1],
+		Protocol: binary.BigEndian.Uint16(datagram[2:4]),
+	}
+	if header.Version != TZSP_VERSION {
+		return nil, nil, newTzspError("unsupported TZSP version: %d", header.Version)
+	}
+	index := TZSP_HEADER_LENGTH
+	for {
+		if index >= length {
+			return nil, nil, newTzspError("TZSP tags are not terminated by end tag")
+		}
+		tagType := datagram[index]
+		if tagType == TAG_TYPE_PADDING {
+			index++
+			continue
+		} else if tagType == TAG_TYPE_END {
+			index++
+			break
+		}
+		if index + 2 > length {
+			return nil, nil, newTzspError("TZSP tag 0x%02x at %d is shorter than its length field", tagType, index)
+		}
+		tagLength := uint(datagram[index + 1])
+		valueIndex := index + 2
+		if valueIndex + tagLength > length {
+			return nil, nil, newTzspError("TZSP tag 0x%02x at %d exceeds the datagram: %d bytes", tagType, index,
+				tagLength)
+		}
+		err := header.readTag(tagType, datagram[valueIndex:valueIndex + tagLength])
+		if err != nil {
+			return nil, nil, err
+		}
+		index = valueIndex + tagLength
+	}
+	if header.Type != TZSP_TYPE_RECEIVED && header.Type != TZSP_TYPE_TRANSMITTED {
+		return nil, nil, newTzspError("TZSP datagram of type %d doesn't carry a frame", header.Type)
+	}
+	if header.Protocol != TZSP_PROTOCOL_ETHERNET {
+		return nil, nil, newTzspError("unsupported encapsulated protocol: %d", header.Protocol)
+	}
+	return &header, datagram[index:], nil
+}
+
+// Reading of the value of one TZSP tag into the header.
+// Parameter tagType byte - type of the tag.
+// Parameter value []byte - value of the tag.
+// Returning error - the value has unexpected length.
+func (TzspHeader *TzspHeader) readTag(tagType byte, value []byte) error {
+	switch tagType {
+	case TAG_TYPE_TIMESTAMP:
+		if len(value) != 4 {
+			return newTzspError("TZSP timestamp tag has invalid length: %d bytes", len(value))
+		}
+		TzspHeader.Timestamp = binary.BigEndian.Uint32(value)
+		TzspHeader.TimestampPresent = true
+	case TAG_TYPE_ORIGINAL_LENGTH:
+		if len(value) != 2 {
+			return newTzspError("TZSP original length tag has invalid length: %d bytes", len(value))
+		}
+		TzspHeader.OriginalLength = uint(binary.BigEndian.Uint16(value))
+	case TAG_TYPE_RAW_RSSI:
+		if len(value) == 1 {
+			TzspHeader.Rssi = int(int8(value[0]))
+		} else if len(value) == 2 {
+			TzspHeader.Rssi = int(int16(binary.BigEndian.Uint16(value)))
+		} else {
+			return newTzspError("TZSP RSSI tag has invalid length: %d bytes", len(value))
+		}
+		TzspHeader.RssiPresent = true
+	case TAG_TYPE_SENSOR_ID:
+		TzspHeader.SensorId = append([]byte{}, value...)
+	}
+	return nil
+}
+
+// Creating of error of malformed TZSP datagram.
+// Parameter format string - format of the error message.
+// Parameter arguments ...interface{} - arguments of the format.
+// Returning error - composite error with the message. See configuration.CompositeError.
+func newTzspError(format string, arguments ...interface{}) error {
+	compositeError := configuration.NewCompositeError()
+	compositeError.AddError(1, fmt.Sprintf(format, arguments...))
+	return compositeError.Evaluate()
+}
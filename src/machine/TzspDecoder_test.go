@@ -0,0 +1,110 @@
+package machine
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Unit test - decoding of TZSP headers, tags, and payloads.
+// Parameter t *testing.T - testing engine.
+func TestDecodeTzsp(t *testing.T) {
+	payload := []byte{0xaa, 0xbb, 0xcc}
+	tests := []struct {
+		name		string
+		datagram	[]byte
+		valid		bool
+		expected	TzspHeader
+	}{
+		{"no tags", buildFrame([]byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_END}, payload), true,
+			TzspHeader{Version: 1, Protocol: TZSP_PROTOCOL_ETHERNET}},
+		{"padding", buildFrame([]byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_PADDING, TAG_TYPE_PADDING, TAG_TYPE_END},
+			payload), true, TzspHeader{Version: 1, Protocol: TZSP_PROTOCOL_ETHERNET}},
+		{"all tags", buildFrame([]byte{0x01, 0x01, 0x00, 0x01,
+			TAG_TYPE_TIMESTAMP, 0x04, 0x00, 0x01, 0x00, 0x00,
+			TAG_TYPE_PADDING,
+			TAG_TYPE_ORIGINAL_LENGTH, 0x02, 0x05, 0xdc,
+			TAG_TYPE_RAW_RSSI, 0x01, 0xc4,
+			TAG_TYPE_SENSOR_ID, 0x03, 0x01, 0x02, 0x03,
+			0x12, 0x01, 0x06,
+			TAG_TYPE_END}, payload), true,
+			TzspHeader{Version: 1, Type: TZSP_TYPE_TRANSMITTED, Protocol: TZSP_PROTOCOL_ETHERNET, Timestamp: 65536,
+				TimestampPresent: true, OriginalLength: 1500, SensorId: []byte{0x01, 0x02, 0x03}, Rssi: -60,
+				RssiPresent: true}},
+		{"two bytes RSSI", buildFrame([]byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_RAW_RSSI, 0x02, 0xff, 0x9c,
+			TAG_TYPE_END}, payload), true,
+			TzspHeader{Version: 1, Protocol: TZSP_PROTOCOL_ETHERNET, Rssi: -100, RssiPresent: true}},
+		{"short header", []byte{0x01, 0x00, 0x00}, false, TzspHeader{}},
+		{"unsupported version", buildFrame([]byte{0x02, 0x00, 0x00, 0x01, TAG_TYPE_END}, payload), false,
+			TzspHeader{}},
+		{"keepalive", []byte{0x01, 0x04, 0x00, 0x00, TAG_TYPE_END}, false, TzspHeader{}},
+		{"802.11 frame", buildFrame([]byte{0x01, 0x00, 0x00, 0x12, TAG_TYPE_END}, payload), false, TzspHeader{}},
+		{"missing end tag", []byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_PADDING}, false, TzspHeader{}},
+		{"missing tag length", []byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_TIMESTAMP}, false, TzspHeader{}},
+		{"tag exceeds datagram", []byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_SENSOR_ID, 0x10, 0x01, TAG_TYPE_END}, false,
+			TzspHeader{}},
+		{"invalid timestamp length", buildFrame([]byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_TIMESTAMP, 0x02, 0x00,
+			0x01, TAG_TYPE_END}, payload), false, TzspHeader{}},
+	}
+	for _, test := range tests {
+		header, frame, err := DecodeTzsp(test.datagram)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: expected error; given header: %+v", test.name, *header)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !bytes.Equal(frame, payload) {
+			t.Errorf("%s: expected payload: %v; given payload: %v", test.name, payload, frame)
+		}
+		if header.Version != test.expected.Version || header.Type != test.expected.Type ||
+			header.Protocol != test.expected.Protocol || header.Timestamp != test.expected.Timestamp ||
+			header.TimestampPresent != test.expected.TimestampPresent ||
+			header.OriginalLength != test.expected.OriginalLength ||
+			!bytes.Equal(header.SensorId, test.expected.SensorId) || header.Rssi != test.expected.Rssi ||
+			header.RssiPresent != test.expected.RssiPresent {
+			t.Errorf("%s: expected header: %+v; given header: %+v", test.name, test.expected, *header)
+		}
+	}
+}
+
+// Unit test - TZSP header is searched for only within bounds of IP and UDP headers (including IPv4 options).
+// Parameter t *testing.T - testing engine.
+func TestUnwrapTzspBounds(t *testing.T) {
+	frame := buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4), buildIpv4Header(PROTOCOL_TCP),
+		buildTcpHeader(443, 50000))
+	udpHeader := []byte{0x90, 0x90, 0x90, 0x90, 0x00, 0x10, 0x00, 0x00}
+	tzspHeader := []byte{0x01, 0x00, 0x00, 0x01, TAG_TYPE_END}
+	ipv4Header := func(ihl byte, length int) []byte {
+		header := append(buildIpv4Header(PROTOCOL_UDP), make([]byte, length - 20)...)
+		header[0] = 0x40 | ihl
+		return header
+	}
+	ethernetHeader := buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV4)
+	tests := []struct {
+		name		string
+		datagram	[]byte
+		valid		bool
+	}{
+		{"IPv4 options", buildFrame(ethernetHeader, ipv4Header(8, 32), udpHeader, tzspHeader, frame), true},
+		{"IPv4 options short frame", buildFrame(ethernetHeader, ipv4Header(15, 24), udpHeader[:4]), false},
+		{"IPv4 options short UDP header", buildFrame(ethernetHeader, ipv4Header(8, 32), udpHeader[:6]), false},
+		{"IPv4 header length below minimum", buildFrame(ethernetHeader, ipv4Header(4, 20), udpHeader), false},
+		{"IPv6", buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6), buildIpv6Header(PROTOCOL_UDP),
+			udpHeader, tzspHeader, frame), true},
+		{"IPv6 short UDP header", buildFrame(buildEthernetHeader(decoderRouterMac, ETHER_TYPE_IPV6),
+			buildIpv6Header(PROTOCOL_UDP), udpHeader[:2]), false},
+	}
+	decapsulator, _ := NewTzspDecapsulator(nil)
+	for _, test := range tests {
+		unwrapped, _, _ := decapsulator.UnwrapWithHeader(&test.datagram)
+		if test.valid && (unwrapped == nil || !bytes.Equal(*unwrapped, frame)) {
+			t.Errorf("%s: expected unwrapped frame", test.name)
+		} else if !test.valid && unwrapped != nil {
+			t.Errorf("%s: malformed datagram shouldn't be unwrapped", test.name)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"encoding/xml"
 	"configuration"
+	"time"
 )
 
 type ConfigurationManager struct {}
@@ -38,6 +39,51 @@ type ConfigData struct {
 // Attribute RouterMacAddress - Referencing mac address of router port (from this address, the flow direction is
 // determined).
 // Attribute LinkBandwidth uint64 - Capacity of observed network connection (both TX and RX) [bytes/s].
+// Attribute ReplayFile string - Path to pcap or pcapng file that is replayed instead of live capturing on the network
+// adapter (empty string - live capturing is used).
+// Attribute ReplaySpeed float64 - Replay speed multiplier relative to recorded timestamps (1 - recorded speed,
+// 2 - twice as fast, 0 - as fast as possible); replayed frames keep recorded gaps between their timestamps at every
+// speed.
+// Attribute Encapsulation string - Encapsulation of captured frames: tzsp (datagrams from remote sniffer - default),
+// ethernet (raw frames from switch mirror port), linux-sll (Linux cooked capture), erspan (ERSPAN type I / II / III
+// or transparent Ethernet bridging over GRE), or vxlan (VXLAN datagrams on UDP port 4789).
+// Attribute DataSource string - Source of traffic statistics: capture (frames captured on the network adapter or
+// replayed from file - default), netflow (NetFlow v5 / v9 and IPFIX records received from flow exporters), or sflow
+// (sFlow v5 raw packet header samples received from sFlow agents); flow records are accounted to single capture
+// source.
+// Attribute CollectorPort uint - UDP port on which the flow collector listens (0 - default port of the data source).
+// Attribute LocalNetworks []string - Local IPv4 / IPv6 prefixes in CIDR notation; if they are set, traffic from local
+// to non-local addresses is TX, the reverse traffic is RX, and local to local traffic is internal (router's MAC
+// address is used only for non-IP or transit traffic).
+// Attribute CaptureSources []CaptureSource - Observed links, each with its own adapter, router's MAC address, and
+// bandwidth (empty - single link described by AdapterName, RouterMacAddress, and LinkBandwidth is observed).
+// See CaptureSource.
+// Attribute StatisticsInterval uint - Interval of sampling of capture statistics (kernel and interface drops) [ms]
+// (0 - 10 seconds).
+// Attribute ParseWorkers uint - Number of goroutines that unwrap, decode, and aggregate captured frames (0 - 2
+// workers).
+// Attribute PipelineCapacity uint - Maximum number of captured frames buckets that wait for parse workers (0 - 4
+// buckets); together with DataBuffer it bounds memory used by captured frames.
+// Attribute DropPolicy string - Behaviour of capturing when the frames pipeline is full: block (the capture waits and
+// frames are dropped by kernel - default), drop-newest (the new bucket is dropped), or drop-oldest (the oldest waiting
+// bucket is dropped).
+// Attribute BucketFrames uint - Maximum number of captured frames in one frames bucket; a full bucket is passed to the
+// frames pipeline before the end of DataBuffer interval (0 - 250000 frames).
+// Attribute PipelineMemory uint - Budget of captured frames that wait for parse workers or that are parsed [MiB] (0 -
+// 256 MiB); the drop policy is applied if the budget is exhausted.
+// Attribute TzspTimestamps bool - Timestamp tags of TZSP datagrams (sensor arrival times) are used instead of capture
+// timestamps if they are present.
+// Attribute FlowIdleTimeout uint - Flows of the flow table are closed if no frame has been seen for this time [ms] (0 -
+// 15 seconds).
+// Attribute FlowActiveTimeout uint - Long-lived flows of the flow table are closed and started again after this time
+// [ms] (0 - 60 seconds).
+// Attribute FlowRetention uint - Closed flows are kept for listing of top talkers for this time [ms] (0 - 10 minutes).
+// Attribute FlowTableSize uint - Maximum number of active flows and of closed flows (0 - 100000 flows).
+// Attribute CaptureFilter string - BPF expression of captured frames of all capture sources (empty - filter of the
+// selected encapsulation is used, for example udp port 37008 for TZSP).
+// Attribute TzspPorts []uint - Listening UDP ports of TZSP datagrams (empty - port 37008).
+// Attribute InnerFilter string - BPF expression that is applied to original (decapsulated) frames; frames that don't
+// match are ignored (empty - all frames are accounted).
 type NetworkConfiguration struct {
 	AdapterName 		string
 	MaximumFrameSize 	uint
@@ -45,14 +91,57 @@ type NetworkConfiguration struct {
 	DataBuffer 			uint
 	RouterMacAddress	string
 	LinkBandwidth		uint64
+	ReplayFile			string
+	ReplaySpeed			float64
+	Encapsulation		string
+	DataSource			string
+	CollectorPort		uint
+	LocalNetworks		[]string	`xml:"LocalNetworks>LocalNetwork"`
+	CaptureSources		[]CaptureSource	`xml:"CaptureSources>CaptureSource"`
+	StatisticsInterval	uint
+	ParseWorkers		uint
+	PipelineCapacity	uint
+	DropPolicy			string
+	BucketFrames		uint
+	PipelineMemory		uint
+	TzspTimestamps		bool
+	FlowIdleTimeout		uint
+	FlowActiveTimeout	uint
+	FlowRetention		uint
+	FlowTableSize		uint
+	CaptureFilter		string
+	TzspPorts			[]uint		`xml:"TzspPorts>TzspPort"`
+	InnerFilter			string
 }
 
-// Cleaning-based settings.
+// Observed link (capture source).
+// Attribute Name string - Interface identifier that is stored with data entries (empty - AdapterName is used).
+// Attribute AdapterName string - The PCAP path to network adapter of the link.
+// Attribute RouterMacAddress string - Referencing mac address of router port on the link.
+// Attribute LinkBandwidth uint64 - Capacity of the link (both TX and RX) [bytes/s].
+// Attribute CaptureFilter string - BPF expression of captured frames of the link (empty - CaptureFilter of network
+// configuration is used).
+type CaptureSource struct {
+	Name				string
+	AdapterName			string
+	RouterMacAddress	string
+	LinkBandwidth		uint64
+	CaptureFilter		string
+}
+
+// Cleaning-based settings. Data entries are kept in 1-second counters and rolled up to 1-minute, 1-hour, and 1-day
+// counters; each resolution has its own retention (0 - counters of the resolution are never removed).
 // Attribute CleaningInterval uint - attribute specifies how often should old data entries be removed (ms).
-// Attribute CleaningDepth uint - only data entries that are older than this treshhold are removed (ms).
+// Attribute CleaningDepth uint - only 1-second counters that are older than this treshhold are removed (ms).
+// Attribute MinuteCleaningDepth uint64 - retention of 1-minute counters (ms).
+// Attribute HourCleaningDepth uint64 - retention of 1-hour counters (ms).
+// Attribute DayCleaningDepth uint64 - retention of 1-day counters (ms).
 type CleaningConfiguration struct {
 	CleaningInterval 	uint
 	CleaningDepth 		uint
+	MinuteCleaningDepth	uint64
+	HourCleaningDepth	uint64
+	DayCleaningDepth	uint64
 }
 
 // Settings of traffic load analyser.
@@ -93,6 +182,10 @@ type PredictionAnalyserConfiguration struct {
 // Attribute PathRemoveDataType string - Site: removing of the specific data type (DELETE).
 // Attribute PathWriteNewDataType string - Site: creating of the new data type (POST).
 // Attribute PathModifyDataType string - Site: modifying of existing data type (POST).
+// Attribute PathGetLoads string - Site: listing of actual loads (bytes and frames per second) of all data types (GET).
+// Attribute PathGetCaptureStatistics string - Site: listing of capture health counters of all links (GET).
+// Attribute PathGetTopTalkers string - Site: listing of top flows and top hosts by bytes, query parameters count and
+// window [s] (GET).
 type RestConfiguration struct {
 	LocalhostPort			uint
 	PathGetDataTypes		string
@@ -100,6 +193,9 @@ type RestConfiguration struct {
 	PathRemoveDataType		string
 	PathWriteNewDataType	string
 	PathModifyDataType		string
+	PathGetLoads			string
+	PathGetCaptureStatistics	string
+	PathGetTopTalkers		string
 }
 
 // Web server configuration (Angular 4 scope).
@@ -145,6 +241,52 @@ type PHYConfiguration struct {
 	LEDsBrightness		uint
 }
 
+// Listing of traffic directions that are analysed - internal direction is present only if local networks are set.
+// Returning []uint - RX, TX, and optionally internal direction. See DIRECTION_RX.
+func (NetworkConfiguration *NetworkConfiguration) ListDirections() []uint {
+	if len(NetworkConfiguration.LocalNetworks) != 0 {
+		return []uint{DIRECTION_RX, DIRECTION_TX, DIRECTION_INTERNAL}
+	}
+	return []uint{DIRECTION_RX, DIRECTION_TX}
+}
+
+// Listing of resolutions of data counters with their retentions.
+// Returning []DataResolution - 1-second, 1-minute, 1-hour, and 1-day resolution (from the finest). See DataResolution.
+func (CleaningConfiguration *CleaningConfiguration) ListResolutions() []DataResolution {
+	return []DataResolution{
+		{Duration: RESOLUTION_SECOND, Retention: time.Duration(CleaningConfiguration.CleaningDepth) * time.Millisecond},
+		{Duration: RESOLUTION_MINUTE,
+			Retention: time.Duration(CleaningConfiguration.MinuteCleaningDepth) * time.Millisecond},
+		{Duration: RESOLUTION_HOUR,
+			Retention: time.Duration(CleaningConfiguration.HourCleaningDepth) * time.Millisecond},
+		{Duration: RESOLUTION_DAY, Retention: time.Duration(CleaningConfiguration.DayCleaningDepth) * time.Millisecond},
+	}
+}
+
+// Listing of observed links - configured capture sources or the single link described by network configuration.
+// Returning []CaptureSource - capture sources with filled names and capture filters. See CaptureSource.
+func (NetworkConfiguration *NetworkConfiguration) ListCaptureSources() []CaptureSource {
+	captureSources := NetworkConfiguration.CaptureSources
+	if len(captureSources) == 0 {
+		captureSources = []CaptureSource{{
+			AdapterName: NetworkConfiguration.AdapterName,
+			RouterMacAddress: NetworkConfiguration.RouterMacAddress,
+			LinkBandwidth: NetworkConfiguration.LinkBandwidth,
+		}}
+	}
+	namedSources := make([]CaptureSource, len(captureSources))
+	for i, captureSource := range captureSources {
+		if captureSource.Name == "" {
+			captureSource.Name = captureSource.AdapterName
+		}
+		if captureSource.CaptureFilter == "" {
+			captureSource.CaptureFilter = NetworkConfiguration.CaptureFilter
+		}
+		namedSources[i] = captureSource
+	}
+	return namedSources
+}
+
 // Creating instance of configuration manager.
 // Returning *ConfigurationManager - ConfigurationManager object.
 func NewConfigurationManager() *ConfigurationManager {
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"time"
+)
+
+// Resolutions (widths of time buckets) of data counters; data entries are counted in 1-second buckets and rolled up to
+// coarser buckets as they arrive.
+const RESOLUTION_SECOND = time.Second
+const RESOLUTION_MINUTE = time.Minute
+const RESOLUTION_HOUR = time.Hour
+const RESOLUTION_DAY = 24 * time.Hour
+
+// Pre-aggregated counters of one data type, direction, and observed link within one time bucket. Data entries are
+// added to counters (UPSERT), so the number of rows grows with the number of buckets instead of the number of data
+// entries.
+// Attribute ID uint - unique identification of the counter.
+// Attribute DataTypeID uint - data type that matched counted data entries. See DataType.
+// Attribute Direction uint - RX (0), TX (1), or internal (2) direction of flow.
+// Attribute InterfaceName string - identifier of the observed link (capture source). See CaptureSource.
+// Attribute Resolution int64 - width of the time bucket [seconds]. See RESOLUTION_SECOND.
+// Attribute Bucket int64 - start of the time bucket [Unix seconds].
+// Attribute Bytes uint64 - number of captured bytes within the bucket.
+// Attribute Packets uint64 - number of captured frames within the bucket.
+type DataCounter struct {
+	ID					uint		`gorm:"primary_key;AUTO_INCREMENT"`
+	DataTypeID			uint		`gorm:"not null;unique_index:idx_unique_counter"`
+	Direction			uint		`gorm:"not null;unique_index:idx_unique_counter"`
+	InterfaceName		string		`gorm:"not null;default:'';size:64;unique_index:idx_unique_counter"`
+	Resolution			int64		`gorm:"not null;default:1;unique_index:idx_unique_counter"`
+	Bucket				int64		`gorm:"not null;unique_index:idx_unique_counter"`
+	Bytes				uint64		`gorm:"not null;default:0"`
+	Packets				uint64		`gorm:"not null;default:0"`
+}
+
+// Resolution of data counters and retention of its counters.
+// Attribute Duration time.Duration - width of the time bucket. See RESOLUTION_SECOND.
+// Attribute Retention time.Duration - counters older than retention are removed (0 - counters are never removed).
+type DataResolution struct {
+	Duration		time.Duration
+	Retention		time.Duration
+}
+
+// Identification of data counter.
+// Attribute dataTypeId uint - data type ID. See DataType.
+// Attribute direction uint - RX (0), TX (1), or internal (2) direction of flow.
+// Attribute interfaceName string - identifier of the observed link.
+// Attribute resolution int64 - width of the time bucket [seconds].
+// Attribute bucket int64 - start of the time bucket [Unix seconds].
+type dataCounterKey struct {
+	dataTypeId		uint
+	direction		uint
+	interfaceName	string
+	resolution		int64
+	bucket			int64
+}
+
+// Computation of the time bucket of data entry.
+// Parameter timestamp time.Time - time of the data entry. See time.Time.
+// Parameter resolution int64 - width of the time bucket [seconds].
+// Returning int64 - start of the time bucket [Unix seconds].
+func bucketOf(timestamp time.Time, resolution int64) int64 {
+	seconds := timestamp.Unix()
+	return seconds - ((seconds % resolution) + resolution) % resolution
+}
+
+// Conversion of resolution to the width of time bucket in seconds.
+// Parameter resolution DataResolution - resolution of data counters. See DataResolution.
+// Returning int64 - width of the time bucket [seconds].
+func resolutionSeconds(resolution DataResolution) int64 {
+	return int64(resolution.Duration / time.Second)
+}
+
+// Adding of bytes and frames to the counter of map (the counter is created if it doesn't exist).
+// Parameter counters map[dataCounterKey](*DataCounter) - aggregated counters. See DataCounter.
+// Parameter key dataCounterKey - identification of the counter. See dataCounterKey.
+// Parameter bytes uint64 - added bytes.
+// Parameter packets uint64 - added frames.
+func addToCounter(counters map[dataCounterKey](*DataCounter), key dataCounterKey, bytes uint64, packets uint64) {
+	counter, present := counters[key]
+	if !present {
+		counter = &DataCounter{DataTypeID: key.dataTypeId, Direction: key.direction,
+			InterfaceName: key.interfaceName, Resolution: key.resolution, Bucket: key.bucket}
+		counters[key] = counter
+	}
+	counter.Bytes += bytes
+	counter.Packets += packets
+}
+
+// Adding of bytes and frames to counters of all resolutions (rollups).
+// Parameter counters map[dataCounterKey](*DataCounter) - aggregated counters. See DataCounter.
+// Parameter key dataCounterKey - identification of the counter (resolution and bucket are overwritten).
+// Parameter timestamp time.Time - time of the counted data. See time.Time.
+// Parameter resolutions []DataResolution - resolutions of updated counters. See DataResolution.
+// Parameter bytes uint64 - added bytes.
+// Parameter packets uint64 - added frames.
+func addToRollups(counters map[dataCounterKey](*DataCounter), key dataCounterKey, timestamp time.Time,
+	resolutions []DataResolution, bytes uint64, packets uint64) {
+	for _, resolution := range resolutions {
+		key.resolution = resolutionSeconds(resolution)
+		key.bucket = bucketOf(timestamp, key.resolution)
+		addToCounter(counters, key, bytes, packets)
+	}
+}
+
+// Aggregation of raw data into counters of all matching data types and all resolutions. Raw data without time is
+// counted in the actual buckets.
+// Parameter classifier *DataTypeClassifier - in-memory index of data types. See DataTypeClassifier.
+// Parameter rawData *[](*RawData) - aggregated data entries. See RawData.
+// Parameter resolutions []DataResolution - resolutions of data counters. See DataResolution.
+// Returning map[dataCounterKey](*DataCounter) - counters of matching data types. See DataCounter.
+func aggregateCounters(classifier *DataTypeClassifier, rawData *[](*RawData),
+	resolutions []DataResolution) map[dataCounterKey](*DataCounter) {
+	counters := make(map[dataCounterKey](*DataCounter))
+	now := time.Now()
+	for _, data := range *rawData {
+		dataTypes := classifier.Classify(data.RawDataType)
+		if len(dataTypes) == 0 {
+			continue
+		}
+		timestamp := data.Time
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+		for _, dataType := range dataTypes {
+			key := dataCounterKey{dataTypeId: dataType.ID, direction: data.Direction,
+				interfaceName: data.InterfaceName}
+			addToRollups(counters, key, timestamp, resolutions, data.Bytes, data.Packets)
+		}
+	}
+	return counters
+}
+
+// Selection of resolution for listing of data entries - the finest resolution whose counters are kept for the whole
+// requested time span (the coarsest resolution if there is no such resolution).
+// Parameter resolutions []DataResolution - resolutions of data counters (from the finest). See DataResolution.
+// Parameter limit time.Time - start of the requested time span. See time.Time.
+// Returning DataResolution - selected resolution. See DataResolution.
+func selectResolution(resolutions []DataResolution, limit time.Time) DataResolution {
+	span := time.Since(limit)
+	for _, resolution := range resolutions {
+		if resolution.Retention == 0 || resolution.Retention >= span {
+			return resolution
+		}
+	}
+	return resolutions[len(resolutions) - 1]
+}
+
+// Computation of retention of data counters of data type with its own retention - retention of the data type replaces
+// retention of the coarsest resolution and it shortens retentions of finer resolutions.
+// Parameter resolutions []DataResolution - resolutions of data counters (from the finest). See DataResolution.
+// Parameter index int - index of the resolution of data counters.
+// Parameter dataType *DataType - data type with non-zero retention. See DataType.
+// Returning time.Duration - retention of data counters of the data type in the resolution.
+func dataTypeRetention(resolutions []DataResolution, index int, dataType *DataType) time.Duration {
+	retention := time.Duration(dataType.Retention) * time.Millisecond
+	if index != len(resolutions) - 1 && resolutions[index].Retention != 0 && resolutions[index].Retention < retention {
+		return resolutions[index].Retention
+	}
+	return retention
+}
+
+// Checking of configured resolutions; all resolutions without retention are used if no resolution is configured.
+// Parameter resolutions []DataResolution - configured resolutions (from the finest). See DataResolution.
+// Returning []DataResolution - resolutions of data counters. See DataResolution.
+func checkResolutions(resolutions []DataResolution) []DataResolution {
+	if len(resolutions) == 0 {
+		return (&CleaningConfiguration{}).ListResolutions()
+	}
+	return resolutions
+}
+
+// Converting of the counter to data entry that is processed by analysers.
+// Returning *Data - data entry at the start of the bucket. See Data.
+func (DataCounter *DataCounter) toData() *Data {
+	return &Data{Time: time.Unix(DataCounter.Bucket, 0), Bytes: DataCounter.Bytes,
+		Packets: DataCounter.Packets, Direction: DataCounter.Direction,
+		InterfaceName: DataCounter.InterfaceName, Resolution: time.Duration(DataCounter.Resolution) * time.Second}
+}
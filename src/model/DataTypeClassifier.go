@@ -0,0 +1,155 @@
+package model
+
+// Data types with the same transport protocol indexed by their ports.
+// Attribute anyPort []*DataType - data types without port (any port is matched).
+// Attribute byPort map[uint]([]*DataType) - data types with single port by the port number.
+// Attribute portRanges []*DataType - data types with port range.
+type portTable struct {
+	anyPort		[]*DataType
+	byPort		map[uint]([]*DataType)
+	portRanges	[]*DataType
+}
+
+// Data types with the same network protocol indexed by their transport protocols.
+// Attribute anyTransport []*DataType - data types without transport protocol (any transport protocol is matched).
+// Attribute byTransport map[uint]*portTable - port tables by transport protocols. See portTable.
+type transportTable struct {
+	anyTransport	[]*DataType
+	byTransport		map[uint]*portTable
+}
+
+// In-memory index of data types that is used for classification of raw data without database queries. The index
+// is immutable - it is rebuilt whenever data types are changed. See StatisticalData.
+// Attribute anyNetwork []*DataType - data types without network protocol (all raw data types are matched).
+// Attribute byNetwork map[uint]*transportTable - transport tables by network protocols (EtherType).
+// See transportTable.
+type DataTypeClassifier struct {
+	anyNetwork	[]*DataType
+	byNetwork	map[uint]*transportTable
+}
+
+// Building of the classifier from data types.
+// Parameter dataTypes [](*DataType) - all data types (their prefix bounds must be filled). See DataType.
+// Returning *DataTypeClassifier - built classifier.
+func NewDataTypeClassifier(dataTypes [](*DataType)) *DataTypeClassifier {
+	classifier := DataTypeClassifier{
+		byNetwork: make(map[uint]*transportTable),
+	}
+	for _, dataType := range dataTypes {
+		if dataType.NetworkProtocol == 0 {
+			classifier.anyNetwork = append(classifier.anyNetwork, dataType)
+			continue
+		}
+		transports, present := classifier.byNetwork[dataType.NetworkProtocol]
+		if !present {
+			transports = &transportTable{byTransport: make(map[uint]*portTable)}
+			classifier.byNetwork[dataType.NetworkProtocol] = transports
+		}
+		if dataType.TransportProtocol == 0 {
+			transports.anyTransport = append(transports.anyTransport, dataType)
+			continue
+		}
+		ports, present := transports.byTransport[dataType.TransportProtocol]
+		if !present {
+			ports = &portTable{byPort: make(map[uint]([]*DataType))}
+			transports.byTransport[dataType.TransportProtocol] = ports
+		}
+		if dataType.Port == 0 {
+			ports.anyPort = append(ports.anyPort, dataType)
+		} else if dataType.PortEnd == 0 {
+			ports.byPort[dataType.Port] = append(ports.byPort[dataType.Port], dataType)
+		} else {
+			ports.portRanges = append(ports.portRanges, dataType)
+		}
+	}
+	return &classifier
+}
+
+// Searching for data types that match raw data type (protocols, ports, VLAN, address prefixes, server name, and
+// ICMP type and code).
+// Parameter rawDataType *RawDataType - classified raw data type. See RawDataType.
+// Returning [](*DataType) - matching data types (each data type is present only once; the data types are shared
+// by all callers, so they must not be modified). See DataType.
+func (DataTypeClassifier *DataTypeClassifier) Classify(rawDataType *RawDataType) [](*DataType) {
+	var candidates [](*DataType)
+	candidates = append(candidates, DataTypeClassifier.anyNetwork...)
+	transports, present := DataTypeClassifier.byNetwork[rawDataType.NetworkProtocol]
+	if present {
+		candidates = append(candidates, transports.anyTransport...)
+		ports, present := transports.byTransport[rawDataType.TransportProtocol]
+		if present {
+			candidates = append(candidates, ports.anyPort...)
+			candidates = append(candidates, ports.byPort[rawDataType.SrcPort]...)
+			if rawDataType.DstPort != rawDataType.SrcPort {
+				candidates = append(candidates, ports.byPort[rawDataType.DstPort]...)
+			}
+			candidates = append(candidates, ports.portRanges...)
+		}
+	}
+	srcAddress := rawDataType.SrcAddress.toBound()
+	dstAddress := rawDataType.DstAddress.toBound()
+	var matches [](*DataType)
+	for _, dataType := range candidates {
+		if matchesVlan(dataType, rawDataType.VlanId) && matchesPort(dataType, rawDataType) &&
+			matchesNetwork(dataType.SrcNetwork, dataType.SrcNetworkFirst, dataType.SrcNetworkLast, srcAddress) &&
+			matchesNetwork(dataType.DstNetwork, dataType.DstNetworkFirst, dataType.DstNetworkLast, dstAddress) &&
+			matchesServerName(dataType, rawDataType) && matchesIcmp(dataType, rawDataType) {
+			matches = append(matches, dataType)
+		}
+	}
+	return matches
+}
+
+// Matching of VLAN identifier.
+// Parameter dataType *DataType - tested data type (0 - any VLAN). See DataType.
+// Parameter vlanId uint - VLAN identifier of raw data type.
+// Returning bool - true if VLAN matches.
+func matchesVlan(dataType *DataType, vlanId uint) bool {
+	return dataType.VlanId == 0 || dataType.VlanId == vlanId
+}
+
+// Matching of ports - data types without transport protocol or port match any ports; otherwise source and / or
+// destination port (according to port matching) must be the port or lie within the port range.
+// Parameter dataType *DataType - tested data type. See DataType.
+// Parameter rawDataType *RawDataType - raw data type with ports. See RawDataType.
+// Returning bool - true if ports match.
+func matchesPort(dataType *DataType, rawDataType *RawDataType) bool {
+	if dataType.NetworkProtocol == 0 || dataType.TransportProtocol == 0 || dataType.Port == 0 {
+		return true
+	}
+	return (dataType.PortMatching != PORT_MATCHING_DESTINATION && isPortInRange(dataType, rawDataType.SrcPort)) ||
+		(dataType.PortMatching != PORT_MATCHING_SOURCE && isPortInRange(dataType, rawDataType.DstPort))
+}
+
+// Matching of ICMP / ICMPv6 type and code.
+// Parameter dataType *DataType - tested data type (nil type - any ICMP type, nil code - any ICMP code). See DataType.
+// Parameter rawDataType *RawDataType - raw data type with decoded ICMP header. See RawDataType.
+// Returning bool - true if ICMP type and code match.
+func matchesIcmp(dataType *DataType, rawDataType *RawDataType) bool {
+	if dataType.IcmpType == nil {
+		return true
+	}
+	return rawDataType.IcmpPresent && rawDataType.IcmpType == *dataType.IcmpType &&
+		(dataType.IcmpCode == nil || rawDataType.IcmpCode == *dataType.IcmpCode)
+}
+
+// Checking whether the port is the port of data type or it lies within its port range.
+// Parameter dataType *DataType - tested data type. See DataType.
+// Parameter port uint - tested port.
+// Returning bool - true if the port is matched.
+func isPortInRange(dataType *DataType, port uint) bool {
+	if dataType.PortEnd == 0 {
+		return dataType.Port == port
+	}
+	return dataType.Port <= port && dataType.PortEnd >= port
+}
+
+// Matching of address against prefix bounds.
+// Parameter network string - prefix of data type (empty - any address).
+// Parameter first string - the first address of prefix. See IPAddress.toBound.
+// Parameter last string - the last address of prefix. See IPAddress.toBound.
+// Parameter address string - tested address (empty - unknown address that doesn't match any prefix).
+// Returning bool - true if the address is matched.
+func matchesNetwork(network string, first string, last string, address string) bool {
+	return network == "" || (address != "" && first <= address && last >= address)
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"testing"
+	"net"
+)
+
+// Building of data type with derived prefix bounds.
+// Parameter dataType DataType - data type without bounds. See DataType.
+// Parameter t *testing.T - testing engine.
+// Returning *DataType - data type that can be indexed by classifier.
+func buildClassifiedDataType(dataType DataType, t *testing.T) *DataType {
+	err := checkDataType(&dataType)
+	if err != nil {
+		t.Fatalf("Invalid tested data type %s: %v", dataType.Name, err)
+	}
+	return &dataType
+}
+
+// Unit test - classification of raw data types by in-memory classifier.
+// Parameter t *testing.T - testing engine.
+func TestDataTypeClassifier(t *testing.T) {
+	dataTypes := [](*DataType){
+		buildClassifiedDataType(DataType{ID: 1, Name: "ALL"}, t),
+		buildClassifiedDataType(DataType{ID: 2, Name: "IPV4", NetworkProtocol: 2048}, t),
+		buildClassifiedDataType(DataType{ID: 3, Name: "TCP", NetworkProtocol: 2048, TransportProtocol: 6}, t),
+		buildClassifiedDataType(DataType{ID: 4, Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6,
+			Port: 443}, t),
+		buildClassifiedDataType(DataType{ID: 5, Name: "HIGH", NetworkProtocol: 2048, TransportProtocol: 6,
+			Port: 50000, PortEnd: 60000, PortMatching: PORT_MATCHING_DESTINATION}, t),
+		buildClassifiedDataType(DataType{ID: 6, Name: "VLAN10", NetworkProtocol: 2048, VlanId: 10}, t),
+		buildClassifiedDataType(DataType{ID: 7, Name: "LAN", SrcNetwork: "192.168.1.0/24"}, t),
+		buildClassifiedDataType(DataType{ID: 8, Name: "HTTPS-SRC", NetworkProtocol: 2048, TransportProtocol: 6,
+			Port: 443, PortMatching: PORT_MATCHING_SOURCE}, t),
+	}
+	classifier := NewDataTypeClassifier(dataTypes)
+	lanAddress := NewIPAddress(net.ParseIP("192.168.1.10"))
+	remoteAddress := NewIPAddress(net.ParseIP("8.8.8.8"))
+
+	tests := []struct {
+		name		string
+		rawDataType	RawDataType
+		expected	[]uint
+	}{
+		{"non-IP frame", RawDataType{NetworkProtocol: 2054}, []uint{1}},
+		{"UDP datagram", RawDataType{NetworkProtocol: 2048, TransportProtocol: 17, SrcPort: 443, DstPort: 53},
+			[]uint{1, 2}},
+		{"HTTPS response", RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 443, DstPort: 55000},
+			[]uint{1, 2, 3, 4, 5, 8}},
+		{"HTTPS request", RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 55000, DstPort: 443},
+			[]uint{1, 2, 3, 4}},
+		{"same ports", RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 443, DstPort: 443},
+			[]uint{1, 2, 3, 4, 8}},
+		{"tagged frame", RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, VlanId: 10}, []uint{1, 2, 6}},
+		{"local source", RawDataType{NetworkProtocol: 34525, SrcAddress: lanAddress, DstAddress: remoteAddress},
+			[]uint{1, 7}},
+		{"remote source", RawDataType{NetworkProtocol: 34525, SrcAddress: remoteAddress, DstAddress: lanAddress},
+			[]uint{1}},
+	}
+	for _, test := range tests {
+		matches := classifier.Classify(&test.rawDataType)
+		found := make(map[uint]int)
+		for _, dataType := range matches {
+			found[dataType.ID]++
+		}
+		valid := len(matches) == len(test.expected)
+		for _, id := range test.expected {
+			valid = valid && found[id] == 1
+		}
+		if !valid {
+			var ids []uint
+			for _, dataType := range matches {
+				ids = append(ids, dataType.ID)
+			}
+			t.Errorf("%s: expected data types: %v; given data types: %v", test.name, test.expected, ids)
+		}
+	}
+}
+
+// Unit test - classifier without data types.
+// Parameter t *testing.T - testing engine.
+func TestDataTypeClassifierEmpty(t *testing.T) {
+	classifier := NewDataTypeClassifier(nil)
+	matches := classifier.Classify(&RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 80})
+	if len(matches) != 0 {
+		t.Errorf("Empty classifier should not match any data type: %v", matches)
+	}
+}
@@ -0,0 +1,292 @@
+package model
+
+import (
+	"configuration"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Storage of data types and data counters in memory (data is lost when the application is stopped). It validates data
+// types in the same way as StatisticalData, so it can replace the database in unit tests of analysers and
+// controllers.
+// Attribute mutex *sync.Mutex - synchronisation of access to data types and data counters. See sync.Mutex.
+// Attribute ultimateLock *sync.Mutex - lock of data types for analysers and REST controller. See sync.Mutex.
+// Attribute dataTypes map[uint](*DataType) - stored data types by ID. See DataType.
+// Attribute counters map[dataCounterKey](*DataCounter) - stored data counters. See DataCounter.
+// Attribute classifier *DataTypeClassifier - in-memory index of stored data types. See DataTypeClassifier.
+// Attribute lastDataTypeId uint - the last assigned ID of data type (IDs are not reused).
+// Attribute resolutions []DataResolution - resolutions of data counters and their retentions. See DataResolution.
+type MemoryStorage struct {
+	mutex			*sync.Mutex
+	ultimateLock	*sync.Mutex
+	dataTypes		map[uint](*DataType)
+	counters		map[dataCounterKey](*DataCounter)
+	classifier		*DataTypeClassifier
+	lastDataTypeId	uint
+	resolutions		[]DataResolution
+}
+
+// Creating instance of the MemoryStorage.
+// Parameter resolutions []DataResolution - resolutions of data counters from the finest (nil - all resolutions
+// without retention). See CleaningConfiguration.ListResolutions.
+// Returning *MemoryStorage - storage without data types and data counters.
+func NewMemoryStorage(resolutions []DataResolution) *MemoryStorage {
+	memoryStorage := MemoryStorage{
+		mutex: &sync.Mutex{},
+		ultimateLock: &sync.Mutex{},
+		dataTypes: make(map[uint](*DataType)),
+		counters: make(map[dataCounterKey](*DataCounter)),
+		classifier: NewDataTypeClassifier(nil),
+		resolutions: checkResolutions(resolutions),
+	}
+	return &memoryStorage
+}
+
+func (MemoryStorage *MemoryStorage) UltimateLock() {
+	MemoryStorage.ultimateLock.Lock()
+}
+
+func (MemoryStorage *MemoryStorage) UltimateUnlock() {
+	MemoryStorage.ultimateLock.Unlock()
+}
+
+// Rebuilding of the in-memory classifier from stored data types (mutex must be locked).
+func (MemoryStorage *MemoryStorage) rebuildClassifier() {
+	dataTypes := make([](*DataType), 0, len(MemoryStorage.dataTypes))
+	for _, dataType := range MemoryStorage.dataTypes {
+		dataTypes = append(dataTypes, dataType)
+	}
+	MemoryStorage.classifier = NewDataTypeClassifier(dataTypes)
+}
+
+// Writing of new data entries into data counters. Data is counted only if there is at least one stored data type that
+// matches specified raw data. See StatisticalData.WriteNewDataEntries.
+// Parameter rawData *[](*RawData) - list of data that is going to be written. See RawData.
+func (MemoryStorage *MemoryStorage) WriteNewDataEntries(rawData *[](*RawData)) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	for key, counter := range aggregateCounters(MemoryStorage.classifier, rawData, MemoryStorage.resolutions) {
+		addToCounter(MemoryStorage.counters, key, counter.Bytes, counter.Packets)
+	}
+}
+
+// Adding of new data type. See StatisticalData.WriteNewDataType.
+// Parameter dataType *DataType - information about data type that is going to be stored (without id). See DataType.
+// Returning *DataType - Data type with assigned ID.
+// Returning error - The data type is not valid or unique.
+func (MemoryStorage *MemoryStorage) WriteNewDataType(dataType *DataType) (*DataType, error) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	err := MemoryStorage.checkStoredDataType(dataType, 0)
+	if err != nil {
+		return nil, err
+	}
+	MemoryStorage.lastDataTypeId++
+	dataType.ID = MemoryStorage.lastDataTypeId
+	MemoryStorage.dataTypes[dataType.ID] = copyDataType(dataType)
+	MemoryStorage.rebuildClassifier()
+	return dataType, nil
+}
+
+// Reading of information about data type by input ID.
+// Parameter id uint - unique id of data type.
+// Returning *DataType - copy of stored data type or nil if the error is not nil. See DataType.
+// Returning error - Data type doesn't exist.
+func (MemoryStorage *MemoryStorage) GetDataType(id uint) (*DataType, error) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	dataType, present := MemoryStorage.dataTypes[id]
+	if !present {
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("The data type with specified id cannot be found, data type id: %d",
+			id))
+		return nil, compositeError.Evaluate()
+	}
+	return copyDataType(dataType), nil
+}
+
+// Altering of data type settings. See StatisticalData.ModifyDataType.
+// Parameter id uint - unique id of data type that is going to be modified.
+// Parameter dataType *DataType - modified data type (id cannot be changed). See DataType.
+// Returning error - the specified data type is not valid or unique or data type with specified id cannot be found.
+func (MemoryStorage *MemoryStorage) ModifyDataType(id uint, dataType *DataType) error {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	err := MemoryStorage.checkStoredDataType(dataType, id)
+	if err != nil {
+		return err
+	}
+	if _, present := MemoryStorage.dataTypes[id]; !present {
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("Old data type cannot be identified, data type id: %d", id))
+		return compositeError.Evaluate()
+	}
+	dataType.ID = id
+	MemoryStorage.dataTypes[id] = copyDataType(dataType)
+	MemoryStorage.rebuildClassifier()
+	return nil
+}
+
+// Removal of the data type including its data counters.
+// Parameter id uint - id of the data type that is going to be removed.
+// Returning *DataType - removed data type. See DataType.
+// Returning error - data type with given id cannot be found.
+func (MemoryStorage *MemoryStorage) RemoveDataType(id uint) (*DataType, error) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	dataType, present := MemoryStorage.dataTypes[id]
+	if !present {
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("The data type with given id doesn't exist: %d", id))
+		return nil, compositeError.Evaluate()
+	}
+	delete(MemoryStorage.dataTypes, id)
+	for key := range MemoryStorage.counters {
+		if key.dataTypeId == id {
+			delete(MemoryStorage.counters, key)
+		}
+	}
+	MemoryStorage.rebuildClassifier()
+	return dataType, nil
+}
+
+// Listing of all stored data types.
+// Returning *[](*DataType) - copies of all data types ordered by ID. See DataType.
+func (MemoryStorage *MemoryStorage) ListDataTypes() *[](*DataType) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	dataTypes := make([](*DataType), 0, len(MemoryStorage.dataTypes))
+	for _, dataType := range MemoryStorage.dataTypes {
+		dataTypes = append(dataTypes, copyDataType(dataType))
+	}
+	sort.Slice(dataTypes, func(i, j int) bool {
+		return dataTypes[i].ID < dataTypes[j].ID
+	})
+	return &dataTypes
+}
+
+// Searching for the most recent data entries of specific type. See StatisticalData.ListLastDataEntries.
+// Parameter name string - name of the data type.
+// Parameter limit time.Time - only time buckets that start after the bucket of limit are returned. See time.Time.
+// Parameter direction uint - only RX (0), TX (1), or internal (2) data entries are returned.
+// Parameter interfaceName string - only data entries of this observed link are returned. See CaptureSource.
+// Returning *[](*Data) - data entries ordered by time. See Data.
+// Returning error - Non-nil error is returned if the data type with selected name doesn't exist.
+func (MemoryStorage *MemoryStorage) ListLastDataEntries(name string, limit time.Time, direction uint,
+	interfaceName string) (*[](*Data), error) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	var dataType *DataType
+	for _, storedDataType := range MemoryStorage.dataTypes {
+		if storedDataType.Name == name {
+			dataType = storedDataType
+		}
+	}
+	if dataType == nil {
+		compositeError := configuration.NewCompositeError()
+		compositeError.AddError(1, fmt.Sprintf("The data type with given name doesn't exist: %s", name))
+		return nil, compositeError.Evaluate()
+	}
+	finalData := [](*Data){}
+	resolution := resolutionSeconds(selectResolution(MemoryStorage.resolutions, limit))
+	limitBucket := bucketOf(limit, resolution)
+	for key, counter := range MemoryStorage.counters {
+		if key.dataTypeId == dataType.ID && key.resolution == resolution && key.bucket > limitBucket &&
+			key.direction == direction && key.interfaceName == interfaceName {
+			finalData = append(finalData, counter.toData())
+		}
+	}
+	sort.Slice(finalData, func(i, j int) bool {
+		return finalData[i].Time.Before(finalData[j].Time)
+	})
+	return &finalData, nil
+}
+
+// Removing of old data counters - retentions of resolutions and data types are applied. See
+// StatisticalData.RemoveOldDataEntries.
+// Parameter now time.Time - actual time. See time.Time.
+func (MemoryStorage *MemoryStorage) RemoveOldDataEntries(now time.Time) {
+	MemoryStorage.mutex.Lock()
+	defer MemoryStorage.mutex.Unlock()
+	indices := make(map[int64]int)
+	for i, resolution := range MemoryStorage.resolutions {
+		indices[resolutionSeconds(resolution)] = i
+	}
+	for key := range MemoryStorage.counters {
+		index, present := indices[key.resolution]
+		if !present {
+			continue
+		}
+		retention := MemoryStorage.resolutions[index].Retention
+		if dataType := MemoryStorage.dataTypes[key.dataTypeId]; dataType != nil && dataType.Retention != 0 {
+			retention = dataTypeRetention(MemoryStorage.resolutions, index, dataType)
+		}
+		if retention != 0 && key.bucket <= now.Add(-retention).Unix() {
+			delete(MemoryStorage.counters, key)
+		}
+	}
+}
+
+// Validation of data type and checking of its uniqueness against stored data types - unique name, unique capture
+// fields, and port ranges that don't overlap (mutex must be locked).
+// Parameter dataType *DataType - inspected data type. See DataType.
+// Parameter id uint - ID of modified data type that is excluded from comparison (0 - a new data type).
+// Returning error - the data type is not valid or unique.
+func (MemoryStorage *MemoryStorage) checkStoredDataType(dataType *DataType, id uint) error {
+	err := checkDataType(dataType)
+	if err != nil {
+		return err
+	}
+	var candidates [](*DataType)
+	compositeError := configuration.NewCompositeError()
+	for _, storedDataType := range MemoryStorage.dataTypes {
+		if storedDataType.ID == id {
+			continue
+		}
+		if storedDataType.Name == dataType.Name {
+			compositeError.AddError(1, fmt.Sprintf("data type name: %s: the name is already used",
+				dataType.Name))
+		} else if hasSameCaptureFields(storedDataType, dataType) {
+			compositeError.AddError(1, fmt.Sprintf("data type %s: capture fields are the same as capture fields " +
+				"of data type %s", dataType.Name, storedDataType.Name))
+		}
+		if dataType.Port != 0 && isPortOverlapCandidate(storedDataType, dataType, id) {
+			candidates = append(candidates, storedDataType)
+		}
+	}
+	err = compositeError.Evaluate()
+	if err != nil {
+		return err
+	}
+	return findPortOverlaps(dataType, candidates)
+}
+
+// Comparison of capture fields of data types (fields of the unique index idx_unique_capture).
+// Parameter first *DataType - compared data type. See DataType.
+// Parameter second *DataType - compared data type. See DataType.
+// Returning bool - data types have the same capture fields.
+func hasSameCaptureFields(first *DataType, second *DataType) bool {
+	return first.NetworkProtocol == second.NetworkProtocol && first.TransportProtocol == second.TransportProtocol &&
+		first.Port == second.Port && first.PortEnd == second.PortEnd && first.PortMatching == second.PortMatching &&
+		first.VlanId == second.VlanId && first.SrcNetwork == second.SrcNetwork &&
+		first.DstNetwork == second.DstNetwork && first.ServerName == second.ServerName &&
+		first.ServerNameSource == second.ServerNameSource && first.IcmpKey == second.IcmpKey
+}
+
+// Copying of data type, so stored data types cannot be changed by callers.
+// Parameter dataType *DataType - copied data type. See DataType.
+// Returning *DataType - copy of data type.
+func copyDataType(dataType *DataType) *DataType {
+	dataTypeCopy := *dataType
+	if dataType.IcmpType != nil {
+		icmpType := *dataType.IcmpType
+		dataTypeCopy.IcmpType = &icmpType
+	}
+	if dataType.IcmpCode != nil {
+		icmpCode := *dataType.IcmpCode
+		dataTypeCopy.IcmpCode = &icmpCode
+	}
+	return &dataTypeCopy
+}
@@ -0,0 +1,218 @@
+package model
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Both implementations of the storage.
+var _ Storage = (*StatisticalData)(nil)
+var _ Storage = (*MemoryStorage)(nil)
+
+// Unit test - creating, modifying, and removing of data types including validation and uniqueness.
+// Parameter t *testing.T - testing engine.
+func TestMemoryStorageDataTypes(t *testing.T) {
+	storage := NewMemoryStorage(nil)
+	https, err := storage.WriteNewDataType(&DataType{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6,
+		Port: 443, ServerName: "Example.COM"})
+	if err != nil || https.ID != 1 || https.ServerName != "example.com" {
+		t.Fatalf("Expected validated data type with ID 1; given: %+v, %v", https, err)
+	}
+	icmpType := uint(8)
+	if _, err := storage.WriteNewDataType(&DataType{Name: "Ping", NetworkProtocol: 2048, TransportProtocol: 1,
+		IcmpType: &icmpType}); err != nil {
+		t.Errorf("Unexpected error of ICMP data type: %v", err)
+	}
+
+	tests := []struct {
+		name		string
+		dataType	DataType
+	}{
+		{"invalid port", DataType{Name: "Invalid", Port: 70000}},
+		{"duplicate name", DataType{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 17, Port: 443}},
+		{"duplicate capture fields", DataType{Name: "TLS", NetworkProtocol: 2048, TransportProtocol: 6, Port: 443,
+			ServerName: "example.com"}},
+		{"overlapping port range", DataType{Name: "Web", NetworkProtocol: 2048, TransportProtocol: 6, Port: 400,
+			PortEnd: 500, ServerName: "example.com"}},
+	}
+	for _, test := range tests {
+		if _, err := storage.WriteNewDataType(&test.dataType); err == nil {
+			t.Errorf("%s: expected error of data type: %+v", test.name, test.dataType)
+		}
+	}
+
+	t.Log("Modifying of data type ...")
+	if err := storage.ModifyDataType(https.ID, &DataType{Name: "HTTPS", NetworkProtocol: 2048,
+		TransportProtocol: 6, Port: 8443, Forecasting: true}); err != nil {
+		t.Errorf("Unexpected error of modification: %v", err)
+	}
+	if err := storage.ModifyDataType(10, &DataType{Name: "Unknown"}); err == nil {
+		t.Errorf("Expected error of modification of unknown data type")
+	}
+	modified, err := storage.GetDataType(https.ID)
+	if err != nil || modified.Port != 8443 || !modified.Forecasting || modified.ServerName != "" {
+		t.Errorf("Expected modified data type; given: %+v, %v", modified, err)
+	}
+	modified.Port = 1
+	if stored, _ := storage.GetDataType(https.ID); stored.Port != 8443 {
+		t.Errorf("Stored data type shouldn't be changed by caller: %+v", stored)
+	}
+
+	t.Log("Removing of data type ...")
+	if _, err := storage.RemoveDataType(https.ID); err != nil {
+		t.Errorf("Unexpected error of removal: %v", err)
+	}
+	if _, err := storage.GetDataType(https.ID); err == nil {
+		t.Errorf("Removed data type shouldn't be found")
+	}
+	if _, err := storage.RemoveDataType(https.ID); err == nil {
+		t.Errorf("Expected error of removal of unknown data type")
+	}
+	dataTypes := *storage.ListDataTypes()
+	if len(dataTypes) != 1 || dataTypes[0].Name != "Ping" {
+		t.Errorf("Expected only ICMP data type; given: %+v", dataTypes)
+	}
+	recreated, _ := storage.WriteNewDataType(&DataType{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6,
+		Port: 443})
+	if recreated == nil || recreated.ID != 3 {
+		t.Errorf("IDs of removed data types shouldn't be reused; given: %+v", recreated)
+	}
+}
+
+// Unit test - writing, listing, and removing of data entries (time-bucketed counters).
+// Parameter t *testing.T - testing engine.
+func TestMemoryStorageDataEntries(t *testing.T) {
+	storage := NewMemoryStorage(nil)
+	https, _ := storage.WriteNewDataType(&DataType{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6,
+		Port: 443})
+	ipv4, _ := storage.WriteNewDataType(&DataType{Name: "IPv4", NetworkProtocol: 2048})
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	buildRawData := func(port uint, direction uint, timestamp time.Time) *RawData {
+		return &RawData{Bytes: 100, Packets: 1, Time: timestamp, RawDataType: &RawDataType{NetworkProtocol: 2048,
+			TransportProtocol: 6, SrcPort: 50000, DstPort: port, Direction: direction, InterfaceName: "eth1"}}
+	}
+	rawData := [](*RawData){
+		buildRawData(443, DIRECTION_TX, start.Add(2 * time.Second)),
+		buildRawData(443, DIRECTION_TX, start.Add(time.Second)),
+		buildRawData(80, DIRECTION_TX, start.Add(time.Second)),
+		buildRawData(443, DIRECTION_RX, start.Add(time.Second)),
+		{Bytes: 60, Packets: 1, Time: start, RawDataType: &RawDataType{NetworkProtocol: 2054}},
+	}
+	storage.WriteNewDataEntries(&rawData)
+
+	tests := []struct {
+		name		string
+		dataType	string
+		limit		time.Time
+		direction	uint
+		expected	int
+	}{
+		{"HTTPS TX", "HTTPS", start, DIRECTION_TX, 2},
+		{"HTTPS RX", "HTTPS", start, DIRECTION_RX, 1},
+		{"IPv4 TX", "IPv4", start, DIRECTION_TX, 2},
+		{"limit", "HTTPS", start.Add(time.Second), DIRECTION_TX, 1},
+	}
+	for _, test := range tests {
+		data, err := storage.ListLastDataEntries(test.dataType, test.limit, test.direction, "eth1")
+		if err != nil || len(*data) != test.expected {
+			t.Errorf("%s: expected %d data entries; given: %v, %v", test.name, test.expected, data, err)
+		}
+	}
+	data, _ := storage.ListLastDataEntries("HTTPS", start, DIRECTION_TX, "eth1")
+	if len(*data) == 2 && !(*data)[0].Time.Before((*data)[1].Time) {
+		t.Errorf("Data entries should be ordered by time: %v, %v", (*data)[0].Time, (*data)[1].Time)
+	}
+	data, _ = storage.ListLastDataEntries("IPv4", start, DIRECTION_TX, "eth1")
+	if len(*data) != 2 || (*data)[0].Bytes != 200 || (*data)[0].Packets != 2 {
+		t.Errorf("Data entries of the same bucket should be summed up; given: %v", data)
+	}
+	if _, err := storage.ListLastDataEntries("Unknown", start, DIRECTION_TX, "eth1"); err == nil {
+		t.Errorf("Expected error of unknown data type")
+	}
+
+	t.Log("Removing of data types and their data counters ...")
+	storage.RemoveDataType(https.ID)
+	if len(storage.counters) != 9 {
+		t.Errorf("Data counters of remaining data type should be kept; given: %d", len(storage.counters))
+	}
+	storage.RemoveDataType(ipv4.ID)
+	if len(storage.counters) != 0 {
+		t.Errorf("Data counters of removed data types should be removed; given: %d", len(storage.counters))
+	}
+
+	t.Log("Removing of old data entries ...")
+	storage = NewMemoryStorage((&CleaningConfiguration{CleaningDepth: 1000, DayCleaningDepth: 1}).ListResolutions())
+	storage.WriteNewDataType(&DataType{Name: "IPv4", NetworkProtocol: 2048})
+	storage.WriteNewDataEntries(&rawData)
+	storage.RemoveOldDataEntries(start.Add(2 * time.Second))
+	var counters [](*DataCounter)
+	for _, counter := range storage.counters {
+		if counter.Resolution != int64(RESOLUTION_MINUTE / time.Second) &&
+			counter.Resolution != int64(RESOLUTION_HOUR / time.Second) {
+			counters = append(counters, counter)
+		}
+	}
+	if len(counters) != 1 || counters[0].Bucket != start.Unix() + 2 || counters[0].Direction != DIRECTION_TX {
+		t.Errorf("Expected only the newest 1-second data counter; given: %v", counters)
+	}
+}
+
+// Unit test - removing of data counters according to retentions of resolutions and data types.
+// Parameter t *testing.T - testing engine.
+func TestMemoryStorageRetention(t *testing.T) {
+	storage := NewMemoryStorage((&CleaningConfiguration{CleaningDepth: 60000}).ListResolutions())
+	ipv4, _ := storage.WriteNewDataType(&DataType{Name: "IPv4", NetworkProtocol: 2048})
+	mdns, _ := storage.WriteNewDataType(&DataType{Name: "mDNS", NetworkProtocol: 2048, TransportProtocol: 17,
+		Port: 5353, Retention: 600000})
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	rawData := [](*RawData){{Bytes: 100, Packets: 1, Time: start, RawDataType: &RawDataType{NetworkProtocol: 2048,
+		TransportProtocol: 17, SrcPort: 5353, DstPort: 5353}}}
+	storage.WriteNewDataEntries(&rawData)
+	storage.RemoveOldDataEntries(start.Add(time.Hour))
+
+	counts := make(map[uint]int)
+	for key := range storage.counters {
+		counts[key.dataTypeId]++
+	}
+	if counts[ipv4.ID] != 3 {
+		t.Errorf("Expected 1-minute, 1-hour, and 1-day counters of data type without retention; given: %d",
+			counts[ipv4.ID])
+	}
+	if counts[mdns.ID] != 0 {
+		t.Errorf("Counters of data type with short retention should be removed; given: %d", counts[mdns.ID])
+	}
+}
+
+// Unit test - concurrent writing of data entries and data types.
+// Parameter t *testing.T - testing engine.
+func TestMemoryStorageConcurrency(t *testing.T) {
+	storage := NewMemoryStorage(nil)
+	storage.WriteNewDataType(&DataType{Name: "IPv4", NetworkProtocol: 2048})
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		waitGroup.Add(1)
+		go func(worker int) {
+			defer waitGroup.Done()
+			for j := 0; j < 100; j++ {
+				rawData := [](*RawData){{Bytes: 100, Packets: 1, Time: time.Now(),
+					RawDataType: &RawDataType{NetworkProtocol: 2048}}}
+				storage.WriteNewDataEntries(&rawData)
+				storage.ListLastDataEntries("IPv4", time.Time{}, DIRECTION_RX, "")
+			}
+			storage.WriteNewDataType(&DataType{Name: "VLAN", NetworkProtocol: 2048, VlanId: uint(worker + 1)})
+		}(i)
+	}
+	waitGroup.Wait()
+	data, _ := storage.ListLastDataEntries("IPv4", time.Time{}, DIRECTION_RX, "")
+	packets := uint64(0)
+	for _, dataEntry := range *data {
+		packets += dataEntry.Packets
+	}
+	if packets != 800 {
+		t.Errorf("Expected 800 frames; given: %d", packets)
+	}
+	if dataTypes := *storage.ListDataTypes(); len(dataTypes) != 2 {
+		t.Errorf("Expected only one data type with name VLAN; given: %d data types", len(dataTypes))
+	}
+}
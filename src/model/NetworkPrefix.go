@@ -0,0 +1,69 @@
+package model
+
+import (
+	"net"
+	"encoding/hex"
+	"fmt"
+)
+
+// IPv4 (stored in IPv4-mapped IPv6 form) or IPv6 address that can be used as a part of map key.
+// Zero value represents unknown address (for example non-IP frames).
+type IPAddress [net.IPv6len]byte
+
+// Converting of net.IP to IPAddress.
+// Parameter address net.IP - IPv4 or IPv6 address (nil - unknown address).
+// Returning IPAddress - converted address (zero value if the address is not known or valid).
+func NewIPAddress(address net.IP) IPAddress {
+	var ipAddress IPAddress
+	address16 := address.To16()
+	if address16 != nil {
+		copy(ipAddress[:], address16)
+	}
+	return ipAddress
+}
+
+// Checking whether the address is known.
+// Returning bool - false for zero value.
+func (IPAddress IPAddress) IsKnown() bool {
+	return IPAddress != [net.IPv6len]byte{}
+}
+
+// Converting of IPAddress to net.IP.
+// Returning net.IP - converted address or nil if the address is not known.
+func (IPAddress IPAddress) ToIP() net.IP {
+	if !IPAddress.IsKnown() {
+		return nil
+	}
+	return net.IP(append([]byte(nil), IPAddress[:]...))
+}
+
+// Encoding of the address to fixed-length hexadecimal string that is compared with prefix bounds of data types
+// (lexicographical ordering of these strings is the same as numerical ordering of addresses).
+// Returning string - hexadecimal address or empty string if the address is not known (it doesn't match any prefix).
+func (IPAddress IPAddress) toBound() string {
+	if !IPAddress.IsKnown() {
+		return ""
+	}
+	return hex.EncodeToString(IPAddress[:])
+}
+
+// Parsing of network prefix in CIDR notation to its canonical form and bounds (the first and the last address
+// encoded as in IPAddress.toBound).
+// Parameter cidr string - IPv4 or IPv6 prefix (for example 192.168.1.0/24 or 2001:db8::/32).
+// Returning canonical string - prefix with host bits cleared.
+// Returning first string - hexadecimal form of the first address of prefix.
+// Returning last string - hexadecimal form of the last address of prefix.
+// Returning err error - the prefix is not valid.
+func parseNetworkBounds(cidr string) (canonical string, first string, last string, err error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid prefix %s: %v", cidr, err)
+	}
+	firstAddress := NewIPAddress(network.IP)
+	lastAddress := firstAddress
+	offset := net.IPv6len - len(network.Mask)
+	for i := range network.Mask {
+		lastAddress[offset + i] |= ^network.Mask[i]
+	}
+	return network.String(), firstAddress.toBound(), lastAddress.toBound(), nil
+}
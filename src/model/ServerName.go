@@ -0,0 +1,65 @@
+package model
+
+import (
+	"strings"
+)
+
+// Prefix of wildcard server name pattern that matches all subdomains of the domain.
+const SERVER_NAME_WILDCARD = "*."
+// Maximum length of server name (and of server name pattern).
+const SERVER_NAME_MAX_LENGTH = 255
+// Maximum length of one domain label.
+const SERVER_NAME_MAX_LABEL_LENGTH = 63
+
+// Checking of the server name pattern of data type - exact domain name or "*.domain" (empty - any flow).
+// Parameter pattern string - server name pattern in lower case.
+// Returning bool - true if the pattern is valid.
+func isServerNamePattern(pattern string) bool {
+	if pattern == "" {
+		return true
+	}
+	if len(pattern) > SERVER_NAME_MAX_LENGTH {
+		return false
+	}
+	return isDomainName(strings.TrimPrefix(pattern, SERVER_NAME_WILDCARD))
+}
+
+// Checking of the domain name syntax (letters, digits, hyphens, and underscores in dot-separated labels).
+// Parameter name string - domain name in lower case (without trailing dot).
+// Returning bool - true if the name is valid.
+func isDomainName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > SERVER_NAME_MAX_LABEL_LENGTH {
+			return false
+		}
+		for _, character := range label {
+			if !(character >= 'a' && character <= 'z') && !(character >= '0' && character <= '9') &&
+				character != '-' && character != '_' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Matching of server name of the flow against server name of data type.
+// Parameter dataType *DataType - tested data type (empty server name - any flow). See DataType.
+// Parameter rawDataType *RawDataType - raw data type with server name of the flow. See RawDataType.
+// Returning bool - true if the server name matches.
+func matchesServerName(dataType *DataType, rawDataType *RawDataType) bool {
+	if dataType.ServerName == "" {
+		return true
+	}
+	if rawDataType.ServerName == "" || (dataType.ServerNameSource != SERVER_NAME_ANY &&
+		dataType.ServerNameSource != rawDataType.ServerNameSource) {
+		return false
+	}
+	if strings.HasPrefix(dataType.ServerName, SERVER_NAME_WILDCARD) {
+		// "*.example.com" matches "www.example.com" and "a.b.example.com", but not "example.com"
+		return strings.HasSuffix(rawDataType.ServerName, dataType.ServerName[1:])
+	}
+	return rawDataType.ServerName == dataType.ServerName
+}
@@ -6,31 +6,57 @@ import (
 	"strings"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"github.com/jinzhu/gorm"
 )
 
+// Direction of traffic stored in data entries: received (RX), transmitted (TX), or local to local (internal).
+const DIRECTION_RX = uint(0)
+const DIRECTION_TX = uint(1)
+const DIRECTION_INTERNAL = uint(2)
+// Side of the flow that is matched against port (range) of data type.
+const PORT_MATCHING_EITHER = uint(0)
+const PORT_MATCHING_SOURCE = uint(1)
+const PORT_MATCHING_DESTINATION = uint(2)
+// Application-layer source of the server name of the flow (TLS ClientHello SNI, HTTP Host header, or DNS query
+// name); any source is matched by data types with SERVER_NAME_ANY.
+const SERVER_NAME_ANY = uint(0)
+const SERVER_NAME_TLS = uint(1)
+const SERVER_NAME_HTTP = uint(2)
+const SERVER_NAME_DNS = uint(3)
+// ICMP and ICMPv6 transport protocols whose data types can match ICMP type and code.
+const TRANSPORT_PROTOCOL_ICMP = uint(1)
+const TRANSPORT_PROTOCOL_ICMPV6 = uint(58)
+
 // Attribute DatabaseConnection *configuration.DatabaseConnection - database connection manager.
 // Attribute mutex *sync.Mutex - synchronisation of access to data table (bug in sqlite3).
 // See *configuration.DatabaseConnection.
+// Attribute classifier *atomic.Value - actual in-memory index of data types (*DataTypeClassifier) that is replaced
+// whenever data types are changed. See DataTypeClassifier.
+// Attribute resolutions []DataResolution - resolutions of data counters and their retentions. See DataResolution.
 type StatisticalData struct {
 	DatabaseConnection 	*configuration.DatabaseConnection
 	mutex				*sync.Mutex
 	ultimateLock		*sync.Mutex
+	classifier			*atomic.Value
+	resolutions			[]DataResolution
 }
 
-// Data represents structure of information that is stored for matching incoming frames.
-// Attribute ID uint - unique identification of data entry.
-// Attribute Time time.Time - automatically generated time of insetting data entry into the relation.
-// See time.Time.
-// Attribute Bytes uint - number of captured bytes (whole frame).
-// Attribute DataTypes *([]*DataType) - list of data types that describe this data entry (many-to-many).
-// See DataType.
-// Attribute Direction uint - RX (0) or TX (1) direction of flow.
+// Data represents traffic of one data type within one time bucket that is read from counters for analysers. See
+// DataCounter.
+// Attribute Time time.Time - start of the time bucket. See time.Time.
+// Attribute Resolution time.Duration - width of the time bucket. See RESOLUTION_SECOND.
+// Attribute Bytes uint64 - number of captured bytes (whole frames).
+// Attribute Packets uint64 - number of captured frames.
+// Attribute Direction uint - RX (0), TX (1), or internal (2) direction of flow.
+// Attribute InterfaceName string - identifier of the observed link (capture source). See CaptureSource.
 type Data struct {
-	ID 					uint			`gorm:"primary_key;AUTO_INCREMENT"`
-	Time				time.Time		`gorm:"not null;default:CURRENT_TIMESTAMP"`
-	Bytes				uint			`gorm:"not null"`
-	DataTypes			*([]*DataType)	`gorm:"many2many:data_to_types"`
-	Direction			uint			`gorm:"not null"`
+	Time				time.Time
+	Resolution			time.Duration
+	Bytes				uint64
+	Packets				uint64
+	Direction			uint
+	InterfaceName		string
 }
 
 // Description of the data type.
@@ -39,8 +65,24 @@ type Data struct {
 // Attribute Forecasting bool - Enabled or disabled forecasting feature.
 // NetworkProtocol uint - EthernetType field from Ethernet2 frame (decimal value).
 // TransportProtocol uint - Protocol field from IPv4 / IPv6 packet (decimal value).
-// Port uint - TCP / UDP destination / source port number.
-// Data *([]*Data) - List of data that is in relation with this data type (many-to-many). See Data.
+// Port uint - TCP / UDP port number or the first port of range (0 - any port).
+// PortEnd uint - the last port of range (0 - only Port is matched).
+// PortMatching uint - matching of either (0), only source (1), or only destination (2) port.
+// VlanId uint - VLAN identifier of the outer 802.1Q / 802.1ad tag (0 - any VLAN or untagged traffic).
+// SrcNetwork string - IPv4 / IPv6 source prefix in CIDR notation (empty - any source address).
+// DstNetwork string - IPv4 / IPv6 destination prefix in CIDR notation (empty - any destination address).
+// SrcNetworkFirst, SrcNetworkLast, DstNetworkFirst, DstNetworkLast string - bounds of prefixes that are derived
+// from SrcNetwork and DstNetwork (they are used for matching of addresses by DataTypeClassifier).
+// ServerName string - server name pattern of the flow; exact domain name or wildcard "*.domain" that matches
+// all subdomains (empty - any flow including flows without server name).
+// ServerNameSource uint - server name from any source (0), TLS SNI (1), HTTP Host (2), or DNS query name (3).
+// IcmpType *uint - ICMP / ICMPv6 type (nil - any type; only for transport protocols 1 and 58).
+// IcmpCode *uint - ICMP / ICMPv6 code (nil - any code; IcmpType must be specified).
+// IcmpKey string - type and code in the form "type/code" that is derived from IcmpType and IcmpCode (nullable
+// columns cannot be part of the unique index).
+// Retention uint64 - how long data counters of the data type are kept [ms]; it shortens retentions of resolutions
+// and replaces retention of the coarsest resolution (0 - only retentions of resolutions are applied). See
+// CleaningConfiguration.
 type DataType struct {
 	ID 					uint 			`gorm:"primary_key;AUTO_INCREMENT"`
 	Name 				string			`gorm:"not null;unique;index:idx_name;size:255"`
@@ -48,15 +90,31 @@ type DataType struct {
 	NetworkProtocol		uint			`gorm:"not null;unique_index:idx_unique_capture"`
 	TransportProtocol	uint			`gorm:"not null;unique_index:idx_unique_capture"`
 	Port				uint			`gorm:"not null;unique_index:idx_unique_capture"`
-	Data				*([]*Data)		`gorm:"many2many:data_to_types"`
+	PortEnd				uint			`gorm:"not null;default:0;unique_index:idx_unique_capture"`
+	PortMatching		uint			`gorm:"not null;default:0;unique_index:idx_unique_capture"`
+	VlanId				uint			`gorm:"not null;default:0;unique_index:idx_unique_capture"`
+	SrcNetwork			string			`gorm:"not null;default:'';size:64;unique_index:idx_unique_capture"`
+	DstNetwork			string			`gorm:"not null;default:'';size:64;unique_index:idx_unique_capture"`
+	SrcNetworkFirst		string			`gorm:"not null;default:'';size:32" json:"-"`
+	SrcNetworkLast		string			`gorm:"not null;default:'';size:32" json:"-"`
+	DstNetworkFirst		string			`gorm:"not null;default:'';size:32" json:"-"`
+	DstNetworkLast		string			`gorm:"not null;default:'';size:32" json:"-"`
+	ServerName			string			`gorm:"not null;default:'';size:255;unique_index:idx_unique_capture"`
+	ServerNameSource	uint			`gorm:"not null;default:0;unique_index:idx_unique_capture"`
+	IcmpType			*uint
+	IcmpCode			*uint
+	IcmpKey				string			`gorm:"not null;default:'';size:16;unique_index:idx_unique_capture" json:"-"`
+	Retention			uint64			`gorm:"not null;default:0"`
 }
 
-// This structure represents information that are used for inserting new data into Data relation.
-// Attribute Bytes uint - number of captured bytes (whole frame).
+// This structure represents information that are used for adding of new data into data counters.
+// Attribute Bytes uint64 - number of captured bytes (whole frame).
+// Attribute Packets uint64 - number of captured frames.
 // Attribute Time time.Time - time of the last frame arrival of specific type within closed time interval.
 // Attribute *RawDataType - data type information.
 type RawData struct {
-	Bytes				uint
+	Bytes				uint64
+	Packets				uint64
 	Time				time.Time
 	*RawDataType
 }
@@ -66,31 +124,57 @@ type RawData struct {
 // Attribute TransportProtocol uint - Protocol field from IPv4 / IPv6 packet (decimal value).
 // Attribute SrcPort uint - TCP / UDP source port number.
 // Attribute DstPort uint - TCP / UDP destination port number.
-// Attribute Direction uint - RX (0) or TX (1) direction of flow.
+// Attribute Direction uint - RX (0), TX (1), or internal (2) direction of flow.
+// Attribute VlanId uint - VLAN identifier of the outer 802.1Q / 802.1ad tag (0 - untagged frame).
+// Attribute SrcAddress IPAddress - source IPv4 / IPv6 address (zero value - non-IP frame). See IPAddress.
+// Attribute DstAddress IPAddress - destination IPv4 / IPv6 address (zero value - non-IP frame). See IPAddress.
+// Attribute InterfaceName string - identifier of the observed link (capture source). See CaptureSource.
+// Attribute ServerName string - server name of the flow in lower case (empty - the flow hasn't been identified).
+// Attribute ServerNameSource uint - source of the server name (SERVER_NAME_TLS, SERVER_NAME_HTTP, or
+// SERVER_NAME_DNS).
+// Attribute IcmpPresent bool - ICMP / ICMPv6 header has been decoded.
+// Attribute IcmpType uint - ICMP / ICMPv6 type.
+// Attribute IcmpCode uint - ICMP / ICMPv6 code.
 type RawDataType struct {
 	NetworkProtocol		uint
 	TransportProtocol	uint
 	SrcPort				uint
 	DstPort				uint
 	Direction			uint
+	VlanId				uint
+	SrcAddress			IPAddress
+	DstAddress			IPAddress
+	InterfaceName		string
+	ServerName			string
+	ServerNameSource	uint
+	IcmpPresent			bool
+	IcmpType			uint
+	IcmpCode			uint
 }
 
 // Smoothed or predicted data.
 // Attribute DataElement float64 - number of bytes.
+// Attribute Packets uint64 - number of frames.
 // Attribute Timestamp time.Time - data element is set on this time.
 type FinalData struct {
 	DataElement		uint64
+	Packets			uint64
 	Timestamp		time.Time
 }
 
 // Creating of StatisticalData instance.
 // Parameter databaseConnection *configuration.DatabaseConnection - database connection manager.
 // See *configuration.DatabaseConnection.
-func NewStatisticalData(databaseConnection *configuration.DatabaseConnection) *StatisticalData {
+// Parameter resolutions []DataResolution - resolutions of data counters from the finest (nil - all resolutions
+// without retention). See CleaningConfiguration.ListResolutions.
+func NewStatisticalData(databaseConnection *configuration.DatabaseConnection,
+	resolutions []DataResolution) *StatisticalData {
 	statisticalData := StatisticalData{
 		DatabaseConnection: databaseConnection,
 		mutex: &sync.Mutex{},
 		ultimateLock: &sync.Mutex{},
+		classifier: &atomic.Value{},
+		resolutions: checkResolutions(resolutions),
 	}
 	return &statisticalData
 }
@@ -109,63 +193,152 @@ func (StatisticalData *StatisticalData) TablesInit() {
 	StatisticalData.mutex.Lock()
 	defer StatisticalData.mutex.Unlock()
 	configuration.Info.Println("Initialisation of the database relations.")
-	err := StatisticalData.DatabaseConnection.DB.AutoMigrate(&DataType{}, &Data{}).Error
+	StatisticalData.dropUniqueCaptureIndex()
+	err := StatisticalData.DatabaseConnection.DB.AutoMigrate(&DataType{}, &DataCounter{}).Error
 	if err != nil {
 		configuration.Error.Panic("Golang data model cannot be migrated to SQL: ", err)
 	}
+	StatisticalData.migrateDataEntries()
+	StatisticalData.rebuildClassifier()
 	configuration.Info.Println("Relations are initialised.")
 }
 
-// Writing of new data entries into the Data relation. Data is written only if there is at least one
-// submitted data type that matches specified raw data (protocols).
+// Rebuilding of the in-memory classifier from data types that are stored in the database (for example after
+// data types have been changed directly in the database).
+func (StatisticalData *StatisticalData) RefreshClassifier() {
+	StatisticalData.mutex.Lock()
+	defer StatisticalData.mutex.Unlock()
+	StatisticalData.rebuildClassifier()
+}
+
+// Rebuilding of the in-memory classifier; the new classifier atomically replaces the old one (mutex must be locked).
+func (StatisticalData *StatisticalData) rebuildClassifier() {
+	var dataTypes [](*DataType)
+	err := StatisticalData.DatabaseConnection.DB.Find(&dataTypes).Error
+	if err != nil {
+		configuration.Error.Panic("Data types of the classifier cannot be listed: ", err)
+	}
+	StatisticalData.classifier.Store(NewDataTypeClassifier(dataTypes))
+}
+
+// Fetching of the actual classifier; it is built if it hasn't been built yet (mutex must be locked).
+// Returning *DataTypeClassifier - actual classifier. See DataTypeClassifier.
+func (StatisticalData *StatisticalData) getClassifier() *DataTypeClassifier {
+	classifier, built := StatisticalData.classifier.Load().(*DataTypeClassifier)
+	if !built {
+		StatisticalData.rebuildClassifier()
+		classifier = StatisticalData.classifier.Load().(*DataTypeClassifier)
+	}
+	return classifier
+}
+
+// Dropping of the unique index over capture fields of data types, so it is rebuilt by migration with all columns
+// that are currently part of it (the index of older database files doesn't contain newly added columns).
+func (StatisticalData *StatisticalData) dropUniqueCaptureIndex() {
+	db := StatisticalData.DatabaseConnection.DB
+	tableName := db.NewScope(&DataType{}).TableName()
+	if db.Dialect().HasIndex(tableName, "idx_unique_capture") {
+		err := db.Model(&DataType{}).RemoveIndex("idx_unique_capture").Error
+		if err != nil {
+			configuration.Error.Panic("Unique index of data types cannot be rebuilt: ", err)
+		}
+	}
+}
+
+// Migration of data entries of older database files (data table and data_to_types associations) into data counters;
+// afterwards, the old tables are dropped.
+func (StatisticalData *StatisticalData) migrateDataEntries() {
+	db := StatisticalData.DatabaseConnection.DB
+	if !db.HasTable("data") {
+		return
+	}
+	configuration.Info.Println("Migration of data entries into data counters.")
+	counters := make(map[dataCounterKey](*DataCounter))
+	if db.HasTable("data_to_types") {
+		// columns that have been added later are missing in the oldest database files
+		packetsColumn := "0"
+		if db.Dialect().HasColumn("data", "packets") {
+			packetsColumn = "data.packets"
+		}
+		interfaceNameColumn := "''"
+		if db.Dialect().HasColumn("data", "interface_name") {
+			interfaceNameColumn = "data.interface_name"
+		}
+		rows, err01 := db.Raw("SELECT data.time, data.bytes, " + packetsColumn + ", data.direction, " +
+			interfaceNameColumn + ", data_to_types.data_type_id FROM data JOIN data_to_types ON " +
+			"data_to_types.data_id = data.id").Rows()
+		if err01 != nil {
+			configuration.Error.Panic("Data entries cannot be fetched for migration: ", err01)
+		}
+		for rows.Next() {
+			var timestamp time.Time
+			var bytes, packets uint64
+			var key dataCounterKey
+			err02 := rows.Scan(&timestamp, &bytes, &packets, &key.direction, &key.interfaceName, &key.dataTypeId)
+			if err02 != nil {
+				rows.Close()
+				configuration.Error.Panic("Data entry cannot be read during migration: ", err02)
+			}
+			addToRollups(counters, key, timestamp, StatisticalData.resolutions, bytes, packets)
+		}
+		rows.Close()
+	}
+	tx := db.Begin()
+	writeCounters(tx, counters)
+	for _, table := range []string{"data_to_types", "data"} {
+		err03 := tx.DropTableIfExists(table).Error
+		if err03 != nil {
+			tx.Rollback()
+			configuration.Error.Panic("Migrated table cannot be dropped: ", table, ": ", err03)
+		}
+	}
+	tx.Commit()
+	configuration.Info.Printf("Data entries are migrated into %d data counters.", len(counters))
+}
+
+// Adding of aggregated counters to stored data counters (UPSERT - a new counter is inserted or bytes and frames are
+// added to the existing counter of the same data type, direction, link, resolution, and time bucket).
+// Parameter tx *gorm.DB - actual transaction (it is rolled back on failure). See gorm.DB.
+// Parameter counters map[dataCounterKey](*DataCounter) - aggregated counters. See DataCounter.
+func writeCounters(tx *gorm.DB, counters map[dataCounterKey](*DataCounter)) {
+	tableName := tx.NewScope(&DataCounter{}).TableName()
+	statement := "INSERT INTO " + tableName + " (data_type_id, direction, interface_name, resolution, bucket, " +
+		"bytes, packets) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (data_type_id, direction, interface_name, " +
+		"resolution, bucket) DO UPDATE SET bytes = bytes + excluded.bytes, packets = packets + excluded.packets"
+	for _, counter := range counters {
+		err := tx.Exec(statement, counter.DataTypeID, counter.Direction, counter.InterfaceName, counter.Resolution,
+			counter.Bucket, counter.Bytes, counter.Packets).Error
+		if err != nil {
+			tx.Rollback()
+			configuration.Error.Panic("Data counter cannot be written: ", err)
+		}
+	}
+}
+
+// Writing of new data entries into data counters. Data is counted only if there is at least one submitted data type
+// that matches specified raw data (protocols, ports, VLAN, and address prefixes). The data types are matched by
+// in-memory classifier (see DataTypeClassifier) including server names of identified flows and ICMP types. Data
+// entries are summed up by data type, direction, link, and time bucket of each resolution (rollups are maintained as
+// data arrives) before they are written, so one row is written per counter instead of one row per data entry.
 // Parameter rawData *[](*RawData) - list of data that is going to be written into the database.
 // See RawData
 func (StatisticalData *StatisticalData) WriteNewDataEntries(rawData *[](*RawData)) {
 	StatisticalData.mutex.Lock()
 	defer StatisticalData.mutex.Unlock()
 	if len(*rawData) != 0 {
-		tx := StatisticalData.DatabaseConnection.DB.Begin()
-		for _, data := range *rawData {
-			// Searching for data types that match input data.
-			var dataTypes [](*DataType)
-			err01 := tx.Where(
-				"network_protocol = ? OR " +
-					"(network_protocol = ? AND " +
-					"(transport_protocol = ? OR " +
-					"(transport_protocol = ? AND " +
-					"(port = ? OR port = ? OR port = ?))))",
-					0, data.NetworkProtocol, 0, data.TransportProtocol, 0, data.SrcPort, data.DstPort).
-				Find(&dataTypes).Error
-			if err01 != nil {
-				tx.Rollback()
-				configuration.Error.Panic("Query into the data_types table failed: ", err01)
-			}
-			// There is at least one matching data type. Now it is needed to write new data entry.
-			if len(dataTypes) != 0 {
-				newData := Data{Bytes: data.Bytes, Time: data.Time, Direction: data.Direction}
-				err02 := tx.Create(&newData).Error
-				if err02 != nil {
-					tx.Rollback()
-					configuration.Error.Panic("A new data entry cannot be created: ", err02)
-				}
-				err03 := tx.Model(&newData).
-					Association("DataTypes").
-					Append(&dataTypes).Error
-				if err03 != nil {
-					tx.Rollback()
-					configuration.Error.Panic("Associations between data and data types " +
-						"cannot be established: ", err02)
-				}
-			}
+		counters := aggregateCounters(StatisticalData.getClassifier(), rawData, StatisticalData.resolutions)
+		if len(counters) != 0 {
+			tx := StatisticalData.DatabaseConnection.DB.Begin()
+			writeCounters(tx, counters)
+			tx.Commit()
 		}
-		tx.Commit()
 	}
 }
 
 // Adding of new data type.
 // Parameter dataType *DataType - information about data type that is going to be saved into the database
-// (without id). Data type must be unique by name and group of three information: port, network, and
-// transport protocol. See DataType.
+// (without id). Data type must be unique by name and group of capture information: port, network, and
+// transport protocol, VLAN, source / destination prefixes, server name, and ICMP type and code. See DataType.
 // Returning *DataType - Data type with assigned ID.
 // Returning error - The data type is not unique.
 func (StatisticalData *StatisticalData) WriteNewDataType(dataType *DataType) (*DataType, error) {
@@ -173,6 +346,9 @@ func (StatisticalData *StatisticalData) WriteNewDataType(dataType *DataType) (*D
 	defer StatisticalData.mutex.Unlock()
 	tx := StatisticalData.DatabaseConnection.DB.Begin()
 	err01 := checkDataType(dataType)
+	if err01 == nil {
+		err01 = checkPortOverlap(tx, dataType, 0)
+	}
 	if err01 != nil {
 		tx.Rollback()
 		return nil, err01
@@ -191,6 +367,7 @@ func (StatisticalData *StatisticalData) WriteNewDataType(dataType *DataType) (*D
 		}
 	}
 	tx.Commit()
+	StatisticalData.rebuildClassifier()
 	return dataType, nil
 }
 
@@ -235,6 +412,9 @@ func (StatisticalData *StatisticalData) ModifyDataType(id uint, dataType *DataTy
 	defer StatisticalData.mutex.Unlock()
 	tx := StatisticalData.DatabaseConnection.DB.Begin()
 	err01 := checkDataType(dataType)
+	if err01 == nil {
+		err01 = checkPortOverlap(tx, dataType, id)
+	}
 	if err01 != nil {
 		tx.Rollback()
 		return err01
@@ -264,10 +444,11 @@ func (StatisticalData *StatisticalData) ModifyDataType(id uint, dataType *DataTy
 		}
 	}
 	tx.Commit()
+	StatisticalData.rebuildClassifier()
 	return nil
 }
 
-// Removal of the data type; afterwards removal of orphaned data.
+// Removal of the data type including its data counters.
 // Parameter id uint - id of the data type that is going to be removed.
 // Returning *DataType - removed data type. See DataType.
 // Returning error - data type with given name cannot be found.
@@ -285,41 +466,22 @@ func (StatisticalData *StatisticalData) RemoveDataType(id uint) (*DataType, erro
 			tx.Rollback()
 			return nil, compositeError.Evaluate()
 		} else {
-			// Searching for related data.
-			var data [](*Data)
-			err02 := tx.Model(&dataType).Association("Data").Find(&data).Error
-			if err01 != nil {
+			// Removing of data counters of the data type.
+			err02 := tx.Where("data_type_id = ?", id).Delete(&DataCounter{}).Error
+			if err02 != nil {
 				tx.Rollback()
-				configuration.Error.Panic("Data associated with the data type cannot be matched, data type id: ",
+				configuration.Error.Panic("Data counters of the data type cannot be removed, data type id: ",
 					id, ": ", err02)
 			}
-			// Removing of associations.
-			err03 := tx.Model(&dataType).Association("Data").Delete(&data).Error
-			if err03 != nil {
-				tx.Rollback()
-				configuration.Error.Panic("An association between data and types cannot be removed, data type id: ",
-					id, ": ", err03)
-			}
-			// Removing of orphaned associated data.
-			for _, dataEntry := range data {
-				count := tx.Model(&dataEntry).Association("DataTypes").Count()
-				if count == 0 {
-					err04 := tx.Delete(&dataEntry).Error
-					if err04 != nil {
-						tx.Rollback()
-						configuration.Error.Panic("One of the data entries cannot be removed from database, " +
-							"data type id: ", id, ": ", err04)
-					}
-				}
-			}
 			// Removing of the data type.
-			err05 := tx.Delete(&dataType).Error
-			if err05 != nil {
+			err03 := tx.Delete(&dataType).Error
+			if err03 != nil {
 				tx.Rollback()
-				configuration.Error.Panic("Cannot delete an existing data type, data type id: ", id, ": ", err05)
+				configuration.Error.Panic("Cannot delete an existing data type, data type id: ", id, ": ", err03)
 			}
 		}
 		tx.Commit()
+		StatisticalData.rebuildClassifier()
 		return &dataType, nil
 	} else {
 		compositeError := configuration.NewCompositeError()
@@ -344,14 +506,16 @@ func (StatisticalData *StatisticalData) ListDataTypes() *[](*DataType) {
 	return &dataTypes
 }
 
-// Searching for the most recent data entries of specific type.
+// Searching for the most recent data entries of specific type (one data entry per time bucket of data counters). The
+// finest resolution whose counters are kept for the whole time span since limit is used.
 // Parameter name string - name of the data type.
-// Parameter limit time.Time - only data entries newer than limit are returned. See time.Time.
-// Parameter direction uint - only RX (0) or TX (1) data entries are returned.
-// Returning *[](*Data) - data entries (references). See Data.
+// Parameter limit time.Time - only time buckets that start after the bucket of limit are returned. See time.Time.
+// Parameter direction uint - only RX (0), TX (1), or internal (2) data entries are returned.
+// Parameter interfaceName string - only data entries of this observed link are returned. See CaptureSource.
+// Returning *[](*Data) - data entries ordered by time (references). See Data.
 // Returning error - Non-nil error is returned if the data type with selected name doesn't exist.
-func (StatisticalData *StatisticalData) ListLastDataEntries(name string, limit time.Time, direction uint) (
-	*[](*Data), error) {
+func (StatisticalData *StatisticalData) ListLastDataEntries(name string, limit time.Time, direction uint,
+	interfaceName string) (*[](*Data), error) {
 	StatisticalData.mutex.Lock()
 	defer StatisticalData.mutex.Unlock()
 	tx := StatisticalData.DatabaseConnection.DB.Begin()
@@ -364,56 +528,71 @@ func (StatisticalData *StatisticalData) ListLastDataEntries(name string, limit t
 		tx.Rollback()
 		return nil, compositeError.Evaluate()
 	} else {
-		err := tx.Model(&dataType).
-			Order("time asc").
-			Where("time > ? AND direction == ?", limit, direction).
-			Association("Data").
-			Find(&finalData).Error
+		var counters [](*DataCounter)
+		resolution := resolutionSeconds(selectResolution(StatisticalData.resolutions, limit))
+		err := tx.Order("bucket asc").
+			Where("data_type_id = ? AND resolution = ? AND bucket > ? AND direction = ? AND interface_name = ?",
+				dataType.ID, resolution, bucketOf(limit, resolution), direction, interfaceName).
+			Find(&counters).Error
 		if err != nil {
 			tx.Rollback()
 			configuration.Error.Panic("Historical data cannot be fetched from the database: ", err)
 		}
+		for _, counter := range counters {
+			finalData = append(finalData, counter.toData())
+		}
 	}
 	tx.Commit()
 	return &finalData, nil
 }
 
-// Removing of old data entries and associations with data types.
-// Parameter limit time.Time - only data entries that are as old or older than limit are removed.
-func (StatisticalData *StatisticalData) RemoveOldDataEntries(limit time.Time) {
+// Removing of old data counters - retention of each resolution is applied separately and data types with their own
+// retention are cleaned up according to it (counters are kept per data type, so retention of one data type doesn't
+// affect counters of other data types that have matched the same data entries).
+// Parameter now time.Time - actual time; counters whose time buckets start as old or older than now shifted by
+// retention are removed. See DataResolution and DataType.
+func (StatisticalData *StatisticalData) RemoveOldDataEntries(now time.Time) {
 	StatisticalData.mutex.Lock()
 	defer StatisticalData.mutex.Unlock()
 	tx := StatisticalData.DatabaseConnection.DB.Begin()
-	// Searching for old data.
-	var oldData [](*Data)
-	err01 := tx.Where("time <= ?", limit).Find(&oldData).Error
+	var dataTypes [](*DataType)
+	err01 := tx.Where("retention <> ?", 0).Find(&dataTypes).Error
 	if err01 != nil {
 		tx.Rollback()
-		configuration.Error.Panic("Old data cannot be fetched from database: ", err01)
+		configuration.Error.Panic("Data types with retention cannot be listed: ", err01)
 	}
-	// Removing of old associations and data.
-	for _, data := range oldData {
-		var dataTypes [](*DataType)
-		err02 := tx.Model(&data).Association("DataTypes").Find(&dataTypes).Error
-		if err02 != nil {
-			tx.Rollback()
-			configuration.Error.Panic("Old data associations cannot be fetched from database: ", err02)
-		}
-		err03 := tx.Model(&data).Association("DataTypes").Delete(&dataTypes).Error
-		if err03 != nil {
-			tx.Rollback()
-			configuration.Error.Panic("Old data associations cannot be removed from database: ", err03)
+	dataTypeIds := []uint{0}
+	for _, dataType := range dataTypes {
+		dataTypeIds = append(dataTypeIds, dataType.ID)
+	}
+	for i, resolution := range StatisticalData.resolutions {
+		// data types without their own retention
+		if resolution.Retention != 0 {
+			limit := now.Add(-resolution.Retention)
+			err02 := tx.Where("resolution = ? AND bucket <= ? AND data_type_id NOT IN (?)",
+				resolutionSeconds(resolution), limit.Unix(), dataTypeIds).Delete(&DataCounter{}).Error
+			if err02 != nil {
+				tx.Rollback()
+				configuration.Error.Panic("Old data counters cannot be removed from database: ", err02)
+			}
 		}
-		err04 := tx.Delete(&data).Error
-		if err04 != nil {
-			tx.Rollback()
-			configuration.Error.Panic("Old data cannot be removed from database: ", err04)
+		// data types with their own retention
+		for _, dataType := range dataTypes {
+			limit := now.Add(-dataTypeRetention(StatisticalData.resolutions, i, dataType))
+			err03 := tx.Where("data_type_id = ? AND resolution = ? AND bucket <= ?", dataType.ID,
+				resolutionSeconds(resolution), limit.Unix()).Delete(&DataCounter{}).Error
+			if err03 != nil {
+				tx.Rollback()
+				configuration.Error.Panic("Old data counters cannot be removed from database, data type id: ",
+					dataType.ID, ": ", err03)
+			}
 		}
 	}
 	tx.Commit()
 }
 
-// Checking of the data type specification (fields format).
+// Checking of the data type specification (fields format). Prefixes are converted to canonical form and their
+// bounds are derived.
 // Parameter dataType *DataType - inspected data type. See DataType.
 // Returning error - indication of wrong format (one or more fields).
 func checkDataType(dataType *DataType) error {
@@ -426,6 +605,26 @@ func checkDataType(dataType *DataType) error {
 		compositeError.AddError(1, fmt.Sprintf("data type port: %d: maximum value of the port identification" +
 			" is 65535", dataType.Port))
 	}
+	if dataType.PortEnd > 65535 {
+		compositeError.AddError(1, fmt.Sprintf("data type port range end: %d: maximum value of the port " +
+			"identification is 65535", dataType.PortEnd))
+	} else if dataType.PortEnd != 0 && dataType.Port == 0 {
+		compositeError.AddError(1, fmt.Sprintf("data type port range: 0-%d: the first port of range must be " +
+			"specified", dataType.PortEnd))
+	} else if dataType.PortEnd != 0 && dataType.PortEnd < dataType.Port {
+		compositeError.AddError(1, fmt.Sprintf("data type port range: %d-%d: the last port of range must not " +
+			"be lower than the first port", dataType.Port, dataType.PortEnd))
+	} else if dataType.PortEnd == dataType.Port {
+		// single port range is stored as a single port
+		dataType.PortEnd = 0
+	}
+	if dataType.PortMatching > PORT_MATCHING_DESTINATION {
+		compositeError.AddError(1, fmt.Sprintf("data type port matching: %d: allowed values are 0 (either " +
+			"port), 1 (source port), and 2 (destination port)", dataType.PortMatching))
+	} else if dataType.PortMatching != PORT_MATCHING_EITHER && dataType.Port == 0 {
+		compositeError.AddError(1, fmt.Sprintf("data type port matching: %d: matching of source or " +
+			"destination port requires port to be specified", dataType.PortMatching))
+	}
 	if dataType.TransportProtocol > 255 {
 		compositeError.AddError(1, fmt.Sprintf("data type transport protocol: %d: maximum value of the " +
 			"transport protocol identification is 255", dataType.TransportProtocol))
@@ -434,6 +633,166 @@ func checkDataType(dataType *DataType) error {
 		compositeError.AddError(1, fmt.Sprintf("data type network protocol: %d: maximum value of the " +
 			"network protocol identification is 65535", dataType.NetworkProtocol))
 	}
+	if dataType.VlanId > 4094 {
+		compositeError.AddError(1, fmt.Sprintf("data type VLAN: %d: maximum value of the VLAN " +
+			"identification is 4094", dataType.VlanId))
+	}
+	dataType.SrcNetwork, dataType.SrcNetworkFirst, dataType.SrcNetworkLast = checkNetwork(compositeError,
+		"source", dataType.SrcNetwork)
+	dataType.DstNetwork, dataType.DstNetworkFirst, dataType.DstNetworkLast = checkNetwork(compositeError,
+		"destination", dataType.DstNetwork)
+	dataType.ServerName = strings.ToLower(dataType.ServerName)
+	if !isServerNamePattern(dataType.ServerName) {
+		compositeError.AddError(1, fmt.Sprintf("data type server name: %s: the pattern must be a domain name " +
+			"or a wildcard \"*.domain\" that is not longer than 255 characters", dataType.ServerName))
+	}
+	if dataType.ServerNameSource > SERVER_NAME_DNS {
+		compositeError.AddError(1, fmt.Sprintf("data type server name source: %d: allowed values are 0 (any " +
+			"source), 1 (TLS SNI), 2 (HTTP Host), and 3 (DNS query name)", dataType.ServerNameSource))
+	} else if dataType.ServerNameSource != SERVER_NAME_ANY && dataType.ServerName == "" {
+		compositeError.AddError(1, fmt.Sprintf("data type server name source: %d: matching of the source " +
+			"requires server name to be specified", dataType.ServerNameSource))
+	}
+	checkIcmp(compositeError, dataType)
 	finalError := compositeError.Evaluate()
 	return finalError
-}
\ No newline at end of file
+}
+
+// Validation of the data type prefix and derivation of its bounds.
+// Parameter compositeError *configuration.CompositeError - buffer of validation errors.
+// Parameter side string - source or destination prefix (used in error description).
+// Parameter network string - prefix in CIDR notation (empty - any address).
+// Returning canonical string - prefix with host bits cleared (unchanged if the prefix is empty or invalid).
+// Returning first string - hexadecimal form of the first address of prefix.
+// Returning last string - hexadecimal form of the last address of prefix.
+func checkNetwork(compositeError *configuration.CompositeError, side string, network string) (canonical string,
+	first string, last string) {
+	if len(network) == 0 {
+		return "", "", ""
+	}
+	canonical, first, last, err := parseNetworkBounds(network)
+	if err != nil {
+		compositeError.AddError(1, fmt.Sprintf("data type %s network: %s: %v", side, network, err))
+		return network, "", ""
+	}
+	return canonical, first, last
+}
+
+// Validation of ICMP type and code of the data type and derivation of its ICMP key.
+// Parameter compositeError *configuration.CompositeError - buffer of validation errors.
+// Parameter dataType *DataType - inspected data type. See DataType.
+func checkIcmp(compositeError *configuration.CompositeError, dataType *DataType) {
+	dataType.IcmpKey = ""
+	if dataType.IcmpType == nil {
+		if dataType.IcmpCode != nil {
+			compositeError.AddError(1, fmt.Sprintf("data type ICMP code: %d: matching of ICMP code requires " +
+				"ICMP type to be specified", *dataType.IcmpCode))
+		}
+		return
+	}
+	if dataType.TransportProtocol != TRANSPORT_PROTOCOL_ICMP &&
+		dataType.TransportProtocol != TRANSPORT_PROTOCOL_ICMPV6 {
+		compositeError.AddError(1, fmt.Sprintf("data type ICMP type: %d: matching of ICMP type requires " +
+			"transport protocol %d (ICMP) or %d (ICMPv6)", *dataType.IcmpType, TRANSPORT_PROTOCOL_ICMP,
+			TRANSPORT_PROTOCOL_ICMPV6))
+	}
+	if dataType.Port != 0 {
+		compositeError.AddError(1, fmt.Sprintf("data type port: %d: ICMP data types cannot match ports",
+			dataType.Port))
+	}
+	if *dataType.IcmpType > 255 {
+		compositeError.AddError(1, fmt.Sprintf("data type ICMP type: %d: maximum value of the ICMP type is 255",
+			*dataType.IcmpType))
+	}
+	dataType.IcmpKey = fmt.Sprintf("%d", *dataType.IcmpType)
+	if dataType.IcmpCode != nil {
+		if *dataType.IcmpCode > 255 {
+			compositeError.AddError(1, fmt.Sprintf("data type ICMP code: %d: maximum value of the ICMP code " +
+				"is 255", *dataType.IcmpCode))
+		}
+		dataType.IcmpKey = fmt.Sprintf("%d/%d", *dataType.IcmpType, *dataType.IcmpCode)
+	}
+}
+
+// Checking whether the port range of data type overlaps with port range of another data type that matches the same
+// protocols, VLAN, prefixes, and server name (overlapping ranges would account the same traffic twice).
+// Parameter tx *gorm.DB - actual transaction. See gorm.DB.
+// Parameter dataType *DataType - inspected (already checked) data type. See DataType.
+// Parameter id uint - ID of modified data type that is excluded from comparison (0 - a new data type).
+// Returning error - description of all overlapping data types.
+func checkPortOverlap(tx *gorm.DB, dataType *DataType, id uint) error {
+	if dataType.Port == 0 {
+		return nil
+	}
+	var candidates [](*DataType)
+	err := tx.Where("id <> ? AND port <> ? AND network_protocol = ? AND transport_protocol = ? AND " +
+		"vlan_id = ? AND src_network = ? AND dst_network = ? AND server_name = ? AND server_name_source = ?", id, 0,
+		dataType.NetworkProtocol, dataType.TransportProtocol, dataType.VlanId, dataType.SrcNetwork,
+		dataType.DstNetwork, dataType.ServerName, dataType.ServerNameSource).
+		Find(&candidates).Error
+	if err != nil {
+		tx.Rollback()
+		configuration.Error.Panic("Query into the data_types table failed: ", err)
+	}
+	return findPortOverlaps(dataType, candidates)
+}
+
+// Checking whether the data type is compared with inspected data type for overlapping port ranges (the same
+// conditions as the query of checkPortOverlap).
+// Parameter candidate *DataType - stored data type. See DataType.
+// Parameter dataType *DataType - inspected (already checked) data type. See DataType.
+// Parameter id uint - ID of modified data type that is excluded from comparison (0 - a new data type).
+// Returning bool - the candidate has a port range and matches the same traffic apart from ports.
+func isPortOverlapCandidate(candidate *DataType, dataType *DataType, id uint) bool {
+	return candidate.ID != id && candidate.Port != 0 && candidate.NetworkProtocol == dataType.NetworkProtocol &&
+		candidate.TransportProtocol == dataType.TransportProtocol && candidate.VlanId == dataType.VlanId &&
+		candidate.SrcNetwork == dataType.SrcNetwork && candidate.DstNetwork == dataType.DstNetwork &&
+		candidate.ServerName == dataType.ServerName && candidate.ServerNameSource == dataType.ServerNameSource
+}
+
+// Comparison of the port range of data type with port ranges of candidates.
+// Parameter dataType *DataType - inspected (already checked) data type. See DataType.
+// Parameter candidates [](*DataType) - data types that match the same traffic apart from ports. See DataType.
+// Returning error - description of all overlapping data types.
+func findPortOverlaps(dataType *DataType, candidates [](*DataType)) error {
+	compositeError := configuration.NewCompositeError()
+	for _, candidate := range candidates {
+		sidesOverlap := dataType.PortMatching == PORT_MATCHING_EITHER ||
+			candidate.PortMatching == PORT_MATCHING_EITHER || dataType.PortMatching == candidate.PortMatching
+		rangesOverlap := dataType.Port <= portRangeEnd(candidate) && candidate.Port <= portRangeEnd(dataType)
+		if sidesOverlap && rangesOverlap {
+			compositeError.AddError(1, fmt.Sprintf("data type port range: %s: overlaps with data type %s " +
+				"(port range %s)", describePortRange(dataType), candidate.Name, describePortRange(candidate)))
+		}
+	}
+	return compositeError.Evaluate()
+}
+
+// Computation of the last port of data type range.
+// Parameter dataType *DataType - data type with specified port. See DataType.
+// Returning uint - the last port of range (Port if the data type specifies only single port).
+func portRangeEnd(dataType *DataType) uint {
+	if dataType.PortEnd == 0 {
+		return dataType.Port
+	}
+	return dataType.PortEnd
+}
+
+// Formatting of port range and matched side for error messages.
+// Parameter dataType *DataType - data type with specified port. See DataType.
+// Returning string - for example "6881-6889, destination port".
+func describePortRange(dataType *DataType) string {
+	var side string
+	switch dataType.PortMatching {
+	case PORT_MATCHING_SOURCE:
+		side = "source port"
+	case PORT_MATCHING_DESTINATION:
+		side = "destination port"
+	default:
+		side = "either port"
+	}
+	if dataType.PortEnd == 0 {
+		return fmt.Sprintf("%d, %s", dataType.Port, side)
+	}
+	return fmt.Sprintf("%d-%d, %s", dataType.Port, dataType.PortEnd, side)
+}
@@ -3,19 +3,21 @@ package model
 import (
 	"testing"
 	"time"
+	"net"
 )
 
 // Cleaning of the database - removing and recreating of all relations.
 // Parameter t *testing.T - testing engine.
 func cleanDatabases(t *testing.T) {
-	err01 := databaseConnection.DB.DropTableIfExists(&Data{}, &DataType{}, "data_to_types").Error
+	err01 := databaseConnection.DB.DropTableIfExists(&DataCounter{}, &DataType{}, "data", "data_to_types").Error
 	if err01 != nil {
 		t.Fatalf("Test failed while cleaning database: %s", err01)
 	}
-	err02 := databaseConnection.DB.AutoMigrate(&DataType{}, &Data{}).Error
+	err02 := databaseConnection.DB.AutoMigrate(&DataType{}, &DataCounter{}).Error
 	if err02 != nil {
 		t.Fatalf("Golang data model cannot be migrated to SQL: %s", err02)
 	}
+	statMachine.RefreshClassifier()
 }
 
 // Unit test - initialisation of the database relations or tables.
@@ -25,18 +27,81 @@ func TestTablesInit(t *testing.T) {
 	statMachine.TablesInit()
 
 	t.Log("Checking of created tables ...")
-	dataCheck := databaseConnection.DB.HasTable(&Data{})
-	if !dataCheck {
-		t.Errorf("Table 'data' hasn't been created.")
+	dataCounterCheck := databaseConnection.DB.HasTable(&DataCounter{})
+	if !dataCounterCheck {
+		t.Errorf("Table 'data_counters' hasn't been created.")
 	}
 	dataTypeCheck := databaseConnection.DB.HasTable(&DataType{})
 	if !dataTypeCheck {
 		t.Errorf("Table 'data_types' hasn't been created.")
 	}
-	dataToTypesCheck := databaseConnection.DB.HasTable("data_to_types")
-	if !dataToTypesCheck {
-		t.Errorf("Table 'data_to_types' hasn't been created.")
+}
+
+// Unit test - migration of data entries and associations of older database files into data counters.
+// Parameter t *testing.T - testing engine.
+func TestMigrateDataEntries(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+	dataTypes := [](*DataType){{Name: "IPv4", NetworkProtocol: 2048}, {Name: "Any"}}
+	writeNewDataTypes(&dataTypes, t)
+
+	t.Log("Writing of data entries in the oldest format (without frames and links) ...")
+	statements := []string{
+		"CREATE TABLE \"data\" (\"id\" integer primary key autoincrement, \"time\" datetime NOT NULL DEFAULT " +
+			"CURRENT_TIMESTAMP, \"bytes\" integer NOT NULL, \"direction\" integer NOT NULL)",
+		"CREATE TABLE \"data_to_types\" (\"data_id\" integer, \"data_type_id\" integer, " +
+			"PRIMARY KEY (\"data_id\", \"data_type_id\"))",
+	}
+	for _, statement := range statements {
+		if err := databaseConnection.DB.Exec(statement).Error; err != nil {
+			t.Fatalf("Test failed while creating of old tables: %s", err)
+		}
+	}
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	oldData := []struct {
+		time		time.Time
+		bytes		uint
+		direction	uint
+		dataTypes	[](*DataType)
+	}{
+		{start, 10, DIRECTION_RX, dataTypes},
+		{start.Add(300 * time.Millisecond), 20, DIRECTION_RX, dataTypes[1:]},
+		{start.Add(time.Second), 30, DIRECTION_TX, dataTypes[1:]},
+	}
+	for i, data := range oldData {
+		err := databaseConnection.DB.Exec("INSERT INTO data (id, time, bytes, direction) VALUES (?, ?, ?, ?)",
+			i + 1, data.time, data.bytes, data.direction).Error
+		for _, dataType := range data.dataTypes {
+			if err == nil {
+				err = databaseConnection.DB.Exec("INSERT INTO data_to_types (data_id, data_type_id) VALUES (?, ?)",
+					i + 1, dataType.ID).Error
+			}
+		}
+		if err != nil {
+			t.Fatalf("Test failed while writing of old data entries: %s", err)
+		}
 	}
+
+	t.Log("Migration of data entries ...")
+	statMachine.TablesInit()
+	if databaseConnection.DB.HasTable("data") || databaseConnection.DB.HasTable("data_to_types") {
+		t.Errorf("Tables of migrated data entries should be dropped.")
+	}
+	expected := []DataCounter{
+		{DataTypeID: dataTypes[0].ID, Direction: DIRECTION_RX, Resolution: 1, Bucket: start.Unix(), Bytes: 10},
+		{DataTypeID: dataTypes[1].ID, Direction: DIRECTION_RX, Resolution: 1, Bucket: start.Unix(), Bytes: 30},
+		{DataTypeID: dataTypes[1].ID, Direction: DIRECTION_TX, Resolution: 1, Bucket: start.Unix() + 1, Bytes: 30},
+	}
+	checkDataCounters(getAllDataCounters(RESOLUTION_SECOND, t), expected, t)
+	checkDataCounters(getAllDataCounters(RESOLUTION_MINUTE, t), []DataCounter{
+		{DataTypeID: dataTypes[0].ID, Direction: DIRECTION_RX, Resolution: 60, Bucket: start.Unix(), Bytes: 10},
+		{DataTypeID: dataTypes[1].ID, Direction: DIRECTION_RX, Resolution: 60, Bucket: start.Unix(), Bytes: 30},
+		{DataTypeID: dataTypes[1].ID, Direction: DIRECTION_TX, Resolution: 60, Bucket: start.Unix(), Bytes: 30},
+	}, t)
+
+	t.Log("Repeated initialisation of the database ...")
+	statMachine.TablesInit()
+	checkDataCounters(getAllDataCounters(RESOLUTION_SECOND, t), expected, t)
 }
 
 // Unit test - modifying of already written data type.
@@ -181,29 +246,307 @@ func TestWriteNewDataEntries(t *testing.T) {
 		{Bytes: 1200, RawDataType: &RawDataType{
 			NetworkProtocol: 200, TransportProtocol: 45, SrcPort: 80, DstPort: 80, Direction: 0}},
 	}
-	statMachine.WriteNewDataEntries(&rawData)
-
-	t.Log("Searching for written data with filled IDs ...")
-	completedData := getAllData(t)
+	matchedDataTypes := writeDataEntries(rawData, t)
 
 	t.Log("Verification of written data ...")
-	tx := databaseConnection.DB.Begin()
 	trueCounts := []int{1, 2, 3, 4, 2, 1, 3}
 	for i := range trueCounts {
-		var associatedTypes []DataType
-		count := tx.Model((*completedData)[i]).
-			Association("DataTypes").
-			Find(&associatedTypes).
-			Count()
-		if count != trueCounts[i] {
-			t.Errorf("Expected count of data types: %d, given count of data types: %d", trueCounts[i], count)
+		if len(matchedDataTypes[i]) != trueCounts[i] {
+			t.Errorf("Expected count of data types: %d, given count of data types: %d", trueCounts[i],
+				len(matchedDataTypes[i]))
 		}
 	}
-	tx.Commit()
+
+	t.Log("Writing of the same raw data again (counters are summed up) ...")
+	writeDataEntries(rawData, t)
+	for _, counter := range *getAllDataCounters(RESOLUTION_SECOND, t) {
+		bucket := counter.Bucket - rawData[0].Time.Unix()
+		if counter.Bytes != 2 * uint64(rawData[bucket].Bytes) {
+			t.Errorf("Expected bytes of data counter: %d, given bytes: %d", 2 * rawData[bucket].Bytes,
+				counter.Bytes)
+		}
+	}
+}
+
+// Unit test - writing of new data entries that are matched by VLAN identifier.
+// Parameter t *testing.T - testing engine.
+func TestWriteNewDataEntriesByVlan(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of new data types with VLAN identifiers into the database ...")
+	dataTypes := make([]*DataType, 3)
+	dataTypes[0] = &DataType{Name: "VLAN10", NetworkProtocol: 2048, VlanId: 10}
+	dataTypes[1] = &DataType{Name: "VLAN20", NetworkProtocol: 2048, VlanId: 20}
+	dataTypes[2] = &DataType{Name: "IPv4", NetworkProtocol: 2048}
+	writeNewDataTypes(&dataTypes, t)
+
+	t.Log("Writing of new raw data into the database ...")
+	rawData := []*RawData{
+		{Bytes: 10, RawDataType: &RawDataType{NetworkProtocol: 2048, VlanId: 10}},
+		{Bytes: 20, RawDataType: &RawDataType{NetworkProtocol: 2048, VlanId: 20}},
+		{Bytes: 30, RawDataType: &RawDataType{NetworkProtocol: 2048, VlanId: 30}},
+		{Bytes: 40, RawDataType: &RawDataType{NetworkProtocol: 2048}},
+	}
+	matchedDataTypes := writeDataEntries(rawData, t)
+
+	t.Log("Verification of written data ...")
+	trueNames := [][]string{{"VLAN10", "IPv4"}, {"VLAN20", "IPv4"}, {"IPv4"}, {"IPv4"}}
+	checkMatchedDataTypes(matchedDataTypes, trueNames, t)
+
+	t.Log("Writing of data type with invalid VLAN identifier ...")
+	_, err := statMachine.WriteNewDataType(&DataType{Name: "VLAN5000", VlanId: 5000})
+	if err == nil {
+		t.Errorf("Expected error during writing of data type with invalid VLAN identifier, but got nil error.")
+	}
 }
 
 // Unit test - searching for all data types.
 // Parameter t *testing.T - testing engine.
+func TestWriteNewDataEntriesByNetwork(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of new data types with address prefixes into the database ...")
+	dataTypes := make([]*DataType, 4)
+	dataTypes[0] = &DataType{Name: "ToNAS", DstNetwork: "192.168.1.20/32"}
+	dataTypes[1] = &DataType{Name: "FromGuests", SrcNetwork: "10.10.5.7/24"}
+	dataTypes[2] = &DataType{Name: "FromIPv6Lan", SrcNetwork: "2001:db8:1::/48"}
+	dataTypes[3] = &DataType{Name: "IPv4", NetworkProtocol: 2048}
+	for _, dataType := range dataTypes {
+		_, err := statMachine.WriteNewDataType(dataType)
+		if err != nil {
+			t.Fatalf("Test failed while creating of new data types: %s", err)
+		}
+	}
+	if dataTypes[1].SrcNetwork != "10.10.5.0/24" {
+		t.Errorf("Expected canonical prefix 10.10.5.0/24, given prefix: %s", dataTypes[1].SrcNetwork)
+	}
+
+	t.Log("Writing of new raw data into the database ...")
+	rawData := []*RawData{
+		{Bytes: 10, RawDataType: &RawDataType{NetworkProtocol: 2048,
+			SrcAddress: NewIPAddress(net.ParseIP("10.10.5.100")),
+			DstAddress: NewIPAddress(net.ParseIP("192.168.1.20"))}},
+		{Bytes: 20, RawDataType: &RawDataType{NetworkProtocol: 2048,
+			SrcAddress: NewIPAddress(net.ParseIP("10.10.6.1")),
+			DstAddress: NewIPAddress(net.ParseIP("192.168.1.21"))}},
+		{Bytes: 30, RawDataType: &RawDataType{NetworkProtocol: 34525,
+			SrcAddress: NewIPAddress(net.ParseIP("2001:db8:1:ffff::1")),
+			DstAddress: NewIPAddress(net.ParseIP("2001:db8:2::1"))}},
+		{Bytes: 40, RawDataType: &RawDataType{NetworkProtocol: 2054}},
+	}
+	matchedDataTypes := writeDataEntries(rawData, t)
+
+	t.Log("Verification of written data ...")
+	trueNames := [][]string{{"ToNAS", "FromGuests", "IPv4"}, {"IPv4"}, {"FromIPv6Lan"}, {}}
+	checkMatchedDataTypes(matchedDataTypes, trueNames, t)
+
+	t.Log("Writing of data type with invalid prefix ...")
+	_, err := statMachine.WriteNewDataType(&DataType{Name: "Invalid", DstNetwork: "192.168.1.0/40"})
+	if err == nil {
+		t.Errorf("Expected error during writing of data type with invalid prefix, but got nil error.")
+	}
+}
+
+func TestWriteNewDataEntriesByPortRange(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of new data types with port ranges into the database ...")
+	dataTypes := []*DataType{
+		{Name: "BitTorrent", NetworkProtocol: 2048, TransportProtocol: 6, Port: 6881, PortEnd: 6889},
+		{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6, Port: 443,
+			PortMatching: PORT_MATCHING_DESTINATION},
+		{Name: "DNS", NetworkProtocol: 2048, TransportProtocol: 17, Port: 53, PortEnd: 53,
+			PortMatching: PORT_MATCHING_SOURCE},
+	}
+	for _, dataType := range dataTypes {
+		_, err := statMachine.WriteNewDataType(dataType)
+		if err != nil {
+			t.Fatalf("Test failed while creating of new data types: %s", err)
+		}
+	}
+	if dataTypes[2].PortEnd != 0 {
+		t.Errorf("Single port range should be stored as a single port, given range end: %d", dataTypes[2].PortEnd)
+	}
+
+	t.Log("Writing of new raw data into the database ...")
+	rawData := []*RawData{
+		{Bytes: 10, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000,
+			DstPort: 6885}},
+		{Bytes: 20, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000,
+			DstPort: 443}},
+		{Bytes: 30, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 443,
+			DstPort: 50000}},
+		{Bytes: 40, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 17, SrcPort: 53,
+			DstPort: 50000}},
+		{Bytes: 50, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 17, SrcPort: 50000,
+			DstPort: 53}},
+	}
+	matchedDataTypes := writeDataEntries(rawData, t)
+
+	t.Log("Verification of written data ...")
+	trueNames := [][]string{{"BitTorrent"}, {"HTTPS"}, {}, {"DNS"}, {}}
+	checkMatchedDataTypes(matchedDataTypes, trueNames, t)
+
+	t.Log("Writing of data types with invalid or overlapping port ranges ...")
+	invalidDataTypes := []*DataType{
+		{Name: "Reversed", NetworkProtocol: 2048, TransportProtocol: 6, Port: 6889, PortEnd: 6881},
+		{Name: "TooHigh", NetworkProtocol: 2048, TransportProtocol: 6, Port: 49152, PortEnd: 70000},
+		{Name: "NoStart", NetworkProtocol: 2048, TransportProtocol: 6, PortEnd: 100},
+		{Name: "WrongSide", NetworkProtocol: 2048, TransportProtocol: 6, Port: 80, PortMatching: 3},
+		{Name: "Overlap", NetworkProtocol: 2048, TransportProtocol: 6, Port: 6889, PortEnd: 6900},
+	}
+	for _, dataType := range invalidDataTypes {
+		_, err := statMachine.WriteNewDataType(dataType)
+		if err == nil {
+			t.Errorf("Expected error during writing of data type %s, but got nil error.", dataType.Name)
+		}
+	}
+	_, err := statMachine.WriteNewDataType(&DataType{Name: "HTTPSReplies", NetworkProtocol: 2048,
+		TransportProtocol: 6, Port: 443, PortMatching: PORT_MATCHING_SOURCE})
+	if err != nil {
+		t.Errorf("Source and destination port matching should not overlap: %v", err)
+	}
+	err = statMachine.ModifyDataType(dataTypes[0].ID, &DataType{Name: "BitTorrent", NetworkProtocol: 2048,
+		TransportProtocol: 6, Port: 6881, PortEnd: 6999})
+	if err != nil {
+		t.Errorf("Modified data type should not overlap with itself: %v", err)
+	}
+}
+
+// Unit test - writing of data entries that are matched by server names of flows.
+// Parameter t *testing.T - testing engine.
+func TestWriteNewDataEntriesByServerName(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of new data types with server names into the database ...")
+	dataTypes := make([]*DataType, 4)
+	dataTypes[0] = &DataType{Name: "YouTube", NetworkProtocol: 2048, TransportProtocol: 6, Port: 443,
+		ServerName: "*.YouTube.com"}
+	dataTypes[1] = &DataType{Name: "YouTube-DNS", ServerName: "www.youtube.com", ServerNameSource: SERVER_NAME_DNS}
+	dataTypes[2] = &DataType{Name: "HTTPS", NetworkProtocol: 2048, TransportProtocol: 6, Port: 443}
+	dataTypes[3] = &DataType{Name: "IPv4", NetworkProtocol: 2048}
+	for _, dataType := range dataTypes {
+		_, err := statMachine.WriteNewDataType(dataType)
+		if err != nil {
+			t.Fatalf("Test failed while creating of new data types: %s", err)
+		}
+	}
+	if dataTypes[0].ServerName != "*.youtube.com" {
+		t.Errorf("Server name pattern should be stored in lower case: %s", dataTypes[0].ServerName)
+	}
+
+	t.Log("Writing of new raw data into the database ...")
+	rawData := []*RawData{
+		{Bytes: 10, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000,
+			DstPort: 443, ServerName: "www.youtube.com", ServerNameSource: SERVER_NAME_TLS}},
+		{Bytes: 20, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000,
+			DstPort: 443, ServerName: "youtube.com", ServerNameSource: SERVER_NAME_TLS}},
+		{Bytes: 30, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 17, SrcPort: 50000,
+			DstPort: 53, ServerName: "www.youtube.com", ServerNameSource: SERVER_NAME_DNS}},
+		{Bytes: 40, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 6, SrcPort: 50000,
+			DstPort: 443}},
+	}
+	matchedDataTypes := writeDataEntries(rawData, t)
+
+	t.Log("Verification of written data ...")
+	trueNames := [][]string{{"YouTube", "HTTPS", "IPv4"}, {"HTTPS", "IPv4"}, {"YouTube-DNS", "IPv4"},
+		{"HTTPS", "IPv4"}}
+	checkMatchedDataTypes(matchedDataTypes, trueNames, t)
+
+	t.Log("Writing of data types with invalid server names ...")
+	invalidDataTypes := []DataType{
+		{Name: "Wildcard", ServerName: "www.*.com"},
+		{Name: "Label", ServerName: "www..com"},
+		{Name: "Source", ServerNameSource: SERVER_NAME_TLS},
+		{Name: "Unknown source", ServerName: "youtube.com", ServerNameSource: 4},
+	}
+	for _, dataType := range invalidDataTypes {
+		_, err := statMachine.WriteNewDataType(&dataType)
+		if err == nil {
+			t.Errorf("Expected error during writing of data type %s, but got nil error.", dataType.Name)
+		}
+	}
+}
+
+// Unit test - writing of data entries that are matched by ICMP types and codes.
+// Parameter t *testing.T - testing engine.
+func TestWriteNewDataEntriesByIcmp(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of new ICMP data types into the database ...")
+	echoRequest, echoReply, unreachable, portUnreachable := uint(8), uint(0), uint(3), uint(3)
+	neighbourSolicitation := uint(135)
+	dataTypes := []*DataType{
+		{Name: "Ping", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &echoRequest},
+		{Name: "Pong", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &echoReply},
+		{Name: "Unreachable", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &unreachable},
+		{Name: "PortUnreachable", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &unreachable,
+			IcmpCode: &portUnreachable},
+		{Name: "NS", NetworkProtocol: 34525, TransportProtocol: 58, IcmpType: &neighbourSolicitation},
+		{Name: "ICMP", NetworkProtocol: 2048, TransportProtocol: 1},
+	}
+	for _, dataType := range dataTypes {
+		_, err := statMachine.WriteNewDataType(dataType)
+		if err != nil {
+			t.Fatalf("Test failed while creating of new data types: %s", err)
+		}
+	}
+
+	t.Log("Writing of new raw data into the database ...")
+	rawData := []*RawData{
+		{Bytes: 10, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true,
+			IcmpType: 8}},
+		{Bytes: 20, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true,
+			IcmpType: 0}},
+		{Bytes: 30, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true,
+			IcmpType: 3, IcmpCode: 1}},
+		{Bytes: 40, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 1, IcmpPresent: true,
+			IcmpType: 3, IcmpCode: 3}},
+		{Bytes: 50, RawDataType: &RawDataType{NetworkProtocol: 34525, TransportProtocol: 58, IcmpPresent: true,
+			IcmpType: 135}},
+		{Bytes: 60, RawDataType: &RawDataType{NetworkProtocol: 2048, TransportProtocol: 1}},
+	}
+	matchedDataTypes := writeDataEntries(rawData, t)
+
+	t.Log("Verification of written data ...")
+	trueNames := [][]string{{"Ping", "ICMP"}, {"Pong", "ICMP"}, {"Unreachable", "ICMP"},
+		{"Unreachable", "PortUnreachable", "ICMP"}, {"NS"}, {"ICMP"}}
+	checkMatchedDataTypes(matchedDataTypes, trueNames, t)
+
+	t.Log("Writing of duplicate and invalid ICMP data types ...")
+	invalidType, invalidCode := uint(256), uint(300)
+	invalidDataTypes := []DataType{
+		{Name: "Duplicate", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &echoRequest},
+		{Name: "TCP", NetworkProtocol: 2048, TransportProtocol: 6, IcmpType: &echoRequest},
+		{Name: "Port", NetworkProtocol: 2048, TransportProtocol: 1, Port: 80, IcmpType: &echoRequest},
+		{Name: "Type", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &invalidType},
+		{Name: "Code", NetworkProtocol: 2048, TransportProtocol: 1, IcmpType: &echoRequest, IcmpCode: &invalidCode},
+		{Name: "Code only", NetworkProtocol: 2048, TransportProtocol: 1, IcmpCode: &echoReply},
+	}
+	for _, dataType := range invalidDataTypes {
+		_, err := statMachine.WriteNewDataType(&dataType)
+		if err == nil {
+			t.Errorf("Expected error during writing of data type %s, but got nil error.", dataType.Name)
+		}
+	}
+
+	t.Log("Reading of ICMP data type ...")
+	dataType, err := statMachine.GetDataType(dataTypes[3].ID)
+	if err != nil || dataType.IcmpType == nil || dataType.IcmpCode == nil || *dataType.IcmpType != 3 ||
+		*dataType.IcmpCode != 3 {
+		t.Errorf("ICMP type and code are not stored: %+v, %v", dataType, err)
+	}
+	dataType, err = statMachine.GetDataType(dataTypes[5].ID)
+	if err != nil || dataType.IcmpType != nil || dataType.IcmpCode != nil {
+		t.Errorf("ICMP type and code should not be stored: %+v, %v", dataType, err)
+	}
+}
+
 func TestListDataTypes(t *testing.T) {
 	t.Log("Cleaning of the database ...")
 	cleanDatabases(t)
@@ -285,21 +628,20 @@ func TestRemoveDataType(t *testing.T) {
 	t.Log("Cleaning of the database ...")
 	cleanDatabases(t)
 
-	t.Log("Writing of a new data type ...")
+	t.Log("Writing of new data types ...")
 	dataTypeId := uint(77)
 	dataTypeName := "XXX"
 	dataType := DataType{ID: dataTypeId, Name: dataTypeName}
 	writeDataType(&dataType, t)
+	ipv4 := DataType{Name: "IPv4", NetworkProtocol: 2048}
+	writeDataType(&ipv4, t)
 
 	t.Log("Writing of some data ...")
-	data := [](*Data){
-		&Data{Time: time.Now(), Bytes: 10},
-		&Data{Time: time.Now(), Bytes: 25},
+	rawData := [](*RawData){
+		{Bytes: 10, Packets: 1, RawDataType: &RawDataType{NetworkProtocol: 2048}},
+		{Bytes: 25, Packets: 1, RawDataType: &RawDataType{NetworkProtocol: 2054}},
 	}
-	writeData(&data, t)
-
-	t.Log("Writing of associations between data type and data ...")
-	createAssociationDataTypeData(&dataType, &data, t)
+	writeDataEntries(rawData, t)
 
 	t.Log("Removing of the data type ...")
 	_, err02 := statMachine.RemoveDataType(dataTypeId)
@@ -308,13 +650,12 @@ func TestRemoveDataType(t *testing.T) {
 	}
 
 	t.Log("Checking of the data type removal ...")
-	dataEntriesCount := len(*getAllData(t))
-	if dataEntriesCount != 0 {
-		t.Errorf("Expected data entries count: 0; got data entries: %d", dataEntriesCount)
-	}
+	expected := []DataCounter{{DataTypeID: ipv4.ID, Resolution: 1, Bucket: rawData[0].Time.Unix(), Bytes: 10,
+		Packets: 1}}
+	checkDataCounters(getAllDataCounters(RESOLUTION_SECOND, t), expected, t)
 	dataTypesCount := len(*getAllDataTypes(t))
-	if dataTypesCount != 0 {
-		t.Errorf("Expected data types count: 0; got count: %d", dataTypesCount)
+	if dataTypesCount != 1 {
+		t.Errorf("Expected data types count: 1; got count: %d", dataTypesCount)
 	}
 
 	t.Log("Removing of invalid data type ...")
@@ -336,55 +677,101 @@ func TestListLastDataEntries(t *testing.T) {
 	writeDataType(&dataType, t)
 
 	t.Log("Writing of some data #1 ...")
-	data01 := [](*Data) {
-		&Data{Bytes: 10, Time: time.Now(), Direction: 0},
-		&Data{Bytes: 10, Time: time.Now(), Direction: 0},
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	data01 := [](*RawData){
+		{Bytes: 10, Packets: 1, Time: start, RawDataType: &RawDataType{}},
+		{Bytes: 10, Packets: 1, Time: start.Add(500 * time.Millisecond), RawDataType: &RawDataType{}},
 	}
-	writeData(&data01, t)
+	statMachine.WriteNewDataEntries(&data01)
 
-	t.Log("Creating of the timestamp before sleep ...")
-	timestamp := time.Now()
-	time.Sleep(1 * time.Second)
+	t.Log("Writing of some data #2 (twice into the same buckets) ...")
 	dataBytes := 20
-
-	t.Log("Writing of some data #2 ...")
-	data02 := [](*Data) {
-		&Data{Bytes: uint(dataBytes), Time: time.Now(), Direction: 0},
-		&Data{Bytes: uint(dataBytes), Time: time.Now(), Direction: 0},
+	data02 := [](*RawData){
+		{Bytes: uint64(dataBytes), Packets: 1, Time: start.Add(time.Second), RawDataType: &RawDataType{}},
+		{Bytes: uint64(dataBytes), Packets: 1, Time: start.Add(2 * time.Second), RawDataType: &RawDataType{}},
+		{Bytes: 99, Packets: 1, Time: start.Add(time.Second), RawDataType: &RawDataType{Direction: 1}},
+		{Bytes: 99, Packets: 1, Time: start.Add(time.Second), RawDataType: &RawDataType{InterfaceName: "eth1"}},
 	}
-	writeData(&data02, t)
-
-	t.Log("Creating of the associations between the data type and data ...")
-	createAssociationDataTypeData(&dataType, &data01, t)
-	createAssociationDataTypeData(&dataType, &data02, t)
+	statMachine.WriteNewDataEntries(&data02)
+	statMachine.WriteNewDataEntries(&data02)
 
 	t.Log("Fetching of last data entries ...")
-	lastData, err01 := statMachine.ListLastDataEntries(dataTypeName, timestamp, 0)
+	timestamp := start.Add(500 * time.Millisecond)
+	lastData, err01 := statMachine.ListLastDataEntries(dataTypeName, timestamp, 0, "")
 	if err01 != nil {
 		t.Fatalf("Last data entries cannot be fetched from database: %s", err01)
 	}
-	if len(*lastData) != len(data02) {
-		t.Errorf("Expected number of data entries: %d; got number of data entries: %d",
-			len(data02), len(*lastData))
+	if len(*lastData) != 2 {
+		t.Errorf("Expected number of data entries: %d; got number of data entries: %d", 2, len(*lastData))
 	} else {
-		if (*lastData)[0].Bytes != uint(dataBytes) {
-			t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
-				dataBytes, (*lastData)[0].Bytes)
-		}
-		if (*lastData)[1].Bytes != uint(dataBytes) {
-			t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
-				dataBytes, (*lastData)[1].Bytes)
+		for i, data := range *lastData {
+			if data.Bytes != uint64(2 * dataBytes) || data.Packets != 2 {
+				t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
+					2 * dataBytes, data.Bytes)
+			}
+			if !data.Time.Equal(start.Add(time.Duration(i + 1) * time.Second)) {
+				t.Errorf("Expected time of data entry: %v; given time: %v",
+					start.Add(time.Duration(i + 1) * time.Second), data.Time)
+			}
 		}
 	}
 
 	t.Log("Reading with the invalid data type ...")
-	_, err02 := statMachine.ListLastDataEntries("fake", timestamp, 1)
+	_, err02 := statMachine.ListLastDataEntries("fake", timestamp, 1, "")
 	if err02 == nil {
 		t.Errorf("An error was expected during reading of last data entries bounded to " +
 			"invalid data type but nil error is thrown.")
 	}
 }
 
+// Unit test - listing of data entries in resolution that is selected by time span.
+// Parameter t *testing.T - testing engine.
+func TestListLastDataEntriesResolution(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+	dataType := DataType{Name: "type01"}
+	writeDataType(&dataType, t)
+	resolutions := (&CleaningConfiguration{CleaningDepth: 60000, MinuteCleaningDepth: 3600000}).ListResolutions()
+	statisticalData := NewStatisticalData(databaseConnection, resolutions)
+
+	t.Log("Writing of some data ...")
+	now := time.Now()
+	rawData := [](*RawData){
+		{Bytes: 10, Packets: 1, Time: now.Add(-90 * time.Second), RawDataType: &RawDataType{}},
+		{Bytes: 20, Packets: 1, Time: now.Add(-10 * time.Second), RawDataType: &RawDataType{}},
+	}
+	statisticalData.WriteNewDataEntries(&rawData)
+
+	tests := []struct {
+		name		string
+		limit		time.Time
+		resolution	time.Duration
+		bytes		uint64
+	}{
+		{"seconds", now.Add(-30 * time.Second), RESOLUTION_SECOND, 20},
+		{"minutes", now.Add(-5 * time.Minute), RESOLUTION_MINUTE, 30},
+		{"hours", now.Add(-24 * time.Hour), RESOLUTION_HOUR, 30},
+	}
+	for _, test := range tests {
+		data, err := statisticalData.ListLastDataEntries(dataType.Name, test.limit, DIRECTION_RX, "")
+		if err != nil || len(*data) == 0 {
+			t.Errorf("%s: expected data entries; given: %v, %v", test.name, data, err)
+			continue
+		}
+		bytes := uint64(0)
+		for _, entry := range *data {
+			bytes += entry.Bytes
+			if entry.Resolution != test.resolution {
+				t.Errorf("%s: expected resolution: %v; given resolution: %v", test.name, test.resolution,
+					entry.Resolution)
+			}
+		}
+		if bytes != test.bytes {
+			t.Errorf("%s: expected number of bytes: %d; given number of bytes: %d", test.name, test.bytes, bytes)
+		}
+	}
+}
+
 // Unit test - removing of old data entries.
 // Parameter t *testing.T - testing engine.
 func TestRemoveOldDataEntries(t *testing.T) {
@@ -395,64 +782,106 @@ func TestRemoveOldDataEntries(t *testing.T) {
 	dataTypeName := "type01"
 	dataType := DataType{Name: dataTypeName}
 	writeDataType(&dataType, t)
+	resolutions := (&CleaningConfiguration{CleaningDepth: 1000, MinuteCleaningDepth: 3600000,
+		DayCleaningDepth: 1}).ListResolutions()
+	statisticalData := NewStatisticalData(databaseConnection, resolutions)
 
-	t.Log("Writing of some data #1 ...")
-	data01 := [](*Data) {
-		&Data{Bytes: 10, Time: time.Now(), Direction: 1},
-		&Data{Bytes: 10, Time: time.Now(), Direction: 0},
-	}
-	writeData(&data01, t)
-
-	t.Log("Creating of the timestamp before sleep ...")
-	timestamp := time.Now()
-	time.Sleep(1 * time.Second)
+	t.Log("Writing of some data ...")
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
 	dataBytes := 20
-
-	t.Log("Writing of some data #2 ...")
-	data02 := [](*Data) {
-		&Data{Bytes: uint(dataBytes), Time: time.Now(), Direction: 0},
-		&Data{Bytes: uint(dataBytes), Time: time.Now(), Direction: 1},
+	rawData := [](*RawData){
+		{Bytes: 10, Time: start, RawDataType: &RawDataType{Direction: 1}},
+		{Bytes: 10, Time: start, RawDataType: &RawDataType{Direction: 0}},
+		{Bytes: uint64(dataBytes), Time: start.Add(time.Second), RawDataType: &RawDataType{Direction: 0}},
+		{Bytes: uint64(dataBytes), Time: start.Add(time.Second), RawDataType: &RawDataType{Direction: 1}},
 	}
-	writeData(&data02, t)
-
-	t.Log("Creating of the associations between the data type and data ...")
-	createAssociationDataTypeData(&dataType, &data01, t)
-	createAssociationDataTypeData(&dataType, &data02, t)
+	statisticalData.WriteNewDataEntries(&rawData)
 
 	t.Log("Removing of old data entries ...")
-	statMachine.RemoveOldDataEntries(timestamp)
+	statisticalData.RemoveOldDataEntries(start.Add(1500 * time.Millisecond))
 
-	t.Log("Checking of data entries ...")
-	allData := getAllData(t)
-	if len(*allData) != len(data02) {
-		t.Errorf("Expected number of data entries: %d; got number of data entries: %d",
-			len(data02), len(*allData))
-	} else {
-		if (*allData)[0].Bytes != uint(dataBytes) {
-			t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
-				dataBytes, (*allData)[0].Bytes)
-		}
-		if (*allData)[1].Bytes != uint(dataBytes) {
-			t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
-				dataBytes, (*allData)[1].Bytes)
-		}
+	t.Log("Checking of data counters ...")
+	expected := []DataCounter{
+		{DataTypeID: dataType.ID, Direction: 0, Resolution: 1, Bucket: start.Unix() + 1, Bytes: uint64(dataBytes)},
+		{DataTypeID: dataType.ID, Direction: 1, Resolution: 1, Bucket: start.Unix() + 1, Bytes: uint64(dataBytes)},
+	}
+	checkDataCounters(getAllDataCounters(RESOLUTION_SECOND, t), expected, t)
+	expectedRollups := []DataCounter{
+		{DataTypeID: dataType.ID, Direction: 0, Resolution: 60, Bucket: start.Unix(), Bytes: uint64(10 + dataBytes)},
+		{DataTypeID: dataType.ID, Direction: 1, Resolution: 60, Bucket: start.Unix(), Bytes: uint64(10 + dataBytes)},
 	}
+	checkDataCounters(getAllDataCounters(RESOLUTION_MINUTE, t), expectedRollups, t)
+	if count := len(*getAllDataCounters(RESOLUTION_HOUR, t)); count != 2 {
+		t.Errorf("1-hour data counters without retention should be kept; given count: %d", count)
+	}
+	if count := len(*getAllDataCounters(RESOLUTION_DAY, t)); count != 0 {
+		t.Errorf("Old 1-day data counters should be removed; given count: %d", count)
+	}
+}
 
-	t.Log("Checking of associations ...")
-	tx := databaseConnection.DB.Begin()
-	var associatedData [](*Data)
-	err := tx.Model(&dataType).Association("Data").Find(&associatedData).Error
-	if err != nil {
-		tx.Rollback()
-		t.Fatalf("Cannot find associated data with specific data type: %s", err)
+// Unit test - removing of old data entries according to retentions of data types.
+// Parameter t *testing.T - testing engine.
+func TestRemoveOldDataEntriesByDataType(t *testing.T) {
+	t.Log("Cleaning of the database ...")
+	cleanDatabases(t)
+
+	t.Log("Writing of data types with retentions ...")
+	dataTypes := [](*DataType){
+		{Name: "Any"},
+		{Name: "WAN", NetworkProtocol: 2048, Retention: uint64(90 * RESOLUTION_DAY / time.Millisecond)},
+		{Name: "mDNS", NetworkProtocol: 2048, TransportProtocol: 17, Port: 5353,
+			Retention: uint64(10 * time.Minute / time.Millisecond)},
 	}
-	for _, d := range associatedData {
-		if d.Bytes != uint(dataBytes) {
-			t.Errorf("Expected number of data bytes: %d; got number of data bytes: %d",
-				dataBytes, d.Bytes)
+	writeNewDataTypes(&dataTypes, t)
+	resolutions := (&CleaningConfiguration{CleaningDepth: 60000, MinuteCleaningDepth: 3600000}).ListResolutions()
+	statisticalData := NewStatisticalData(databaseConnection, resolutions)
+
+	t.Log("Writing of data entry that is matched by all data types ...")
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	rawData := [](*RawData){{Bytes: 100, Packets: 1, Time: start, RawDataType: &RawDataType{NetworkProtocol: 2048,
+		TransportProtocol: 17, SrcPort: 5353, DstPort: 5353}}}
+	statisticalData.WriteNewDataEntries(&rawData)
+
+	tests := []struct {
+		name		string
+		now			time.Time
+		expected	map[time.Duration][]string
+	}{
+		{"after two hours", start.Add(2 * time.Hour), map[time.Duration][]string{
+			RESOLUTION_SECOND: {}, RESOLUTION_MINUTE: {}, RESOLUTION_HOUR: {"Any", "WAN"},
+			RESOLUTION_DAY: {"Any", "WAN"}}},
+		{"after 100 days", start.Add(100 * RESOLUTION_DAY), map[time.Duration][]string{
+			RESOLUTION_SECOND: {}, RESOLUTION_MINUTE: {}, RESOLUTION_HOUR: {"Any"}, RESOLUTION_DAY: {"Any"}}},
+	}
+	names := map[uint]string{dataTypes[0].ID: "Any", dataTypes[1].ID: "WAN", dataTypes[2].ID: "mDNS"}
+	for _, test := range tests {
+		statisticalData.RemoveOldDataEntries(test.now)
+		for resolution, expectedNames := range test.expected {
+			counters := *getAllDataCounters(resolution, t)
+			given := make([]string, 0, len(counters))
+			for _, counter := range counters {
+				given = append(given, names[counter.DataTypeID])
+			}
+			if len(given) != len(expectedNames) {
+				t.Errorf("%s: expected data counters of resolution %v: %v; given: %v", test.name, resolution,
+					expectedNames, given)
+				continue
+			}
+			for i := range given {
+				if given[i] != expectedNames[i] {
+					t.Errorf("%s: expected data counters of resolution %v: %v; given: %v", test.name,
+						resolution, expectedNames, given)
+					break
+				}
+			}
 		}
 	}
-	tx.Commit()
+
+	t.Log("Reading of data type with retention ...")
+	dataType, err := statMachine.GetDataType(dataTypes[2].ID)
+	if err != nil || dataType.Retention != 600000 {
+		t.Errorf("Retention of data type is not stored: %+v, %v", dataType, err)
+	}
 }
 
 // Writing of new data types into the database.
@@ -468,21 +897,96 @@ func writeNewDataTypes(dataTypes *[](*DataType), t *testing.T) {
 		}
 	}
 	tx.Commit()
+	statMachine.RefreshClassifier()
 }
 
-// Reading of all data from the database.
+// Reading of all data counters of one resolution from the database.
+// Parameter resolution time.Duration - resolution of data counters. See RESOLUTION_SECOND.
 // Parameter t *testing.T - testing engine.
-// Returning *[](*Data) - fetched data entries.
-func getAllData(t *testing.T) *[](*Data) {
+// Returning *[](*DataCounter) - fetched data counters ordered by data type, direction, and bucket.
+func getAllDataCounters(resolution time.Duration, t *testing.T) *[](*DataCounter) {
 	tx := databaseConnection.DB.Begin()
-	var data []*Data
-	err := tx.Order("id asc").Find(&data).Error
+	var counters []*DataCounter
+	err := tx.Where("resolution = ?", int64(resolution / time.Second)).
+		Order("data_type_id asc, direction asc, interface_name asc, bucket asc").Find(&counters).Error
 	if err != nil {
 		tx.Rollback()
-		t.Fatalf("Test failed while reading of written data entries: %s", err)
+		t.Fatalf("Test failed while reading of written data counters: %s", err)
 	}
 	tx.Commit()
-	return &data
+	return &counters
+}
+
+// Comparison of data counters with expected data counters (IDs are not compared).
+// Parameter counters *[](*DataCounter) - data counters ordered by data type, direction, and bucket.
+// Parameter expected []DataCounter - expected data counters in the same order.
+// Parameter t *testing.T - testing engine.
+func checkDataCounters(counters *[](*DataCounter), expected []DataCounter, t *testing.T) {
+	if len(*counters) != len(expected) {
+		t.Errorf("Expected count of data counters: %d, given count of data counters: %d", len(expected),
+			len(*counters))
+		return
+	}
+	for i, counter := range *counters {
+		given := *counter
+		given.ID = 0
+		if given != expected[i] {
+			t.Errorf("Expected data counter: %+v, given data counter: %+v", expected[i], given)
+		}
+	}
+}
+
+// Writing of raw data entries into separate time buckets and searching for data types that have matched them.
+// Parameter rawData [](*RawData) - written data entries (their time is overwritten).
+// Parameter t *testing.T - testing engine.
+// Returning [][]string - names of data types whose counters contain data entries (by indices of data entries).
+func writeDataEntries(rawData [](*RawData), t *testing.T) [][]string {
+	start := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, data := range rawData {
+		data.Time = start.Add(time.Duration(i) * RESOLUTION_SECOND)
+	}
+	statMachine.WriteNewDataEntries(&rawData)
+	names := make(map[uint]string)
+	for _, dataType := range *getAllDataTypes(t) {
+		names[dataType.ID] = dataType.Name
+	}
+	matchedDataTypes := make([][]string, len(rawData))
+	for _, counter := range *getAllDataCounters(RESOLUTION_SECOND, t) {
+		i := counter.Bucket - start.Unix()
+		if i < 0 || i >= int64(len(rawData)) {
+			t.Errorf("Unexpected bucket of data counter: %+v", *counter)
+			continue
+		}
+		matchedDataTypes[i] = append(matchedDataTypes[i], names[counter.DataTypeID])
+	}
+	return matchedDataTypes
+}
+
+// Checking of data types that have matched written data entries.
+// Parameter matchedDataTypes [][]string - names of matching data types by indices of data entries.
+// Parameter trueNames [][]string - names of expected data types by indices of data entries.
+// Parameter t *testing.T - testing engine.
+func checkMatchedDataTypes(matchedDataTypes [][]string, trueNames [][]string, t *testing.T) {
+	if len(matchedDataTypes) != len(trueNames) {
+		t.Fatalf("Expected count of data entries: %d, given count of data entries: %d", len(trueNames),
+			len(matchedDataTypes))
+	}
+	for i := range trueNames {
+		if len(matchedDataTypes[i]) != len(trueNames[i]) {
+			t.Errorf("Expected count of data types: %d, given count of data types: %d (data entry %d)",
+				len(trueNames[i]), len(matchedDataTypes[i]), i)
+			continue
+		}
+		for _, matchedName := range matchedDataTypes[i] {
+			found := false
+			for _, name := range trueNames[i] {
+				found = found || matchedName == name
+			}
+			if !found {
+				t.Errorf("Unexpected data type %s matched with data entry %d", matchedName, i)
+			}
+		}
+	}
 }
 
 // Listing of all data types.
@@ -511,34 +1015,5 @@ func writeDataType(dataType *DataType, t *testing.T) {
 		t.Fatalf("Test failed while creating of a new data type: %s", err)
 	}
 	tx.Commit()
+	statMachine.RefreshClassifier()
 }
-
-// Writing of some data into the database relation.
-// Parameter data *[](*Data) - data that is going to be written into the database.
-// Parameter t *testing.T - testing engine.
-func writeData(data *[](*Data), t* testing.T) {
-	tx := databaseConnection.DB.Begin()
-	for _,d := range *data {
-		err := tx.Create(&d).Error
-		if err != nil {
-			tx.Rollback()
-			t.Fatalf("Test failed while writing of new data: %s", err)
-		}
-	}
-	tx.Commit()
-}
-
-// Creating of associations: one data type - a lot of data.
-// Parameter dataType *DataType - the data type that is going to be associated with data.
-// Parameter data *[](*Data) - sample data entries.
-// Parameter t *testing.T - testing engine.
-func createAssociationDataTypeData(dataType *DataType, data *[](*Data), t *testing.T) {
-	tx := databaseConnection.DB.Begin()
-	err := tx.Model(dataType).Association("Data").Append(data).Error
-	if err != nil {
-		tx.Rollback()
-		t.Errorf("An error occured during creating of associations between the data type " +
-			"and data: %s", err)
-	}
-	tx.Commit()
-}
\ No newline at end of file
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+// Storage of data types and data entries that is used by collectors, analysers, and REST controller. Data entries are
+// summed up into time-bucketed counters of several resolutions (see DataCounter). StatisticalData stores them in SQL
+// database (gorm), MemoryStorage keeps them in memory. Implementations are safe for concurrent use.
+type Storage interface {
+	// Locking of data types against modification while they are read by analysers or modified by REST controller.
+	UltimateLock()
+	// Unlocking of data types. See UltimateLock.
+	UltimateUnlock()
+	// Adding of data entries to counters of matching data types. See StatisticalData.WriteNewDataEntries.
+	WriteNewDataEntries(rawData *[](*RawData))
+	// Adding of new validated and unique data type. See StatisticalData.WriteNewDataType.
+	WriteNewDataType(dataType *DataType) (*DataType, error)
+	// Reading of data type by ID. See StatisticalData.GetDataType.
+	GetDataType(id uint) (*DataType, error)
+	// Altering of existing data type. See StatisticalData.ModifyDataType.
+	ModifyDataType(id uint, dataType *DataType) error
+	// Removal of data type and its data counters. See StatisticalData.RemoveDataType.
+	RemoveDataType(id uint) (*DataType, error)
+	// Listing of all data types. See StatisticalData.ListDataTypes.
+	ListDataTypes() *[](*DataType)
+	// Listing of data entries (time buckets) of data type that are newer than limit in the resolution that is
+	// selected by time span. See StatisticalData.ListLastDataEntries.
+	ListLastDataEntries(name string, limit time.Time, direction uint, interfaceName string) (*[](*Data), error)
+	// Removing of data entries that are older than retentions of their resolutions. See
+	// StatisticalData.RemoveOldDataEntries.
+	RemoveOldDataEntries(now time.Time)
+}
@@ -28,7 +28,7 @@ func setUp() {
 	configuration.LoggingInit(ioutil.Discard, os.Stdout, os.Stdout, os.Stderr)
 	databaseConnection = configuration.NewDatabaseConnection()
 	databaseConnection.ConnectDatabaseFromTest(2)
-	statMachine = NewStatisticalData(databaseConnection)
+	statMachine = NewStatisticalData(databaseConnection, nil)
 }
 
 // Cleaning after performing unit tests - closing of the database connection.